@@ -0,0 +1,470 @@
+// Package accountvend runs the suite in an AWS account of its own. An
+// account is leased from a pool organizational unit, or created through
+// Organizations when the pool is empty, a test role is bootstrapped in it,
+// and the suite runs with that role's credentials. Afterwards the account is
+// swept and handed back to the pool, so parallel runs never share quotas or
+// trip over each other's leaks.
+//
+// Leasing moves the account from the pool OU to the leased OU. The move
+// fails for every runner but one, which is what keeps two runs from taking
+// the same account. An interrupted run releases its account once its
+// workspaces are destroyed; only a run killed outright, one whose sweep
+// fails, or one that leaks tagged resources the sweeper cannot delete, such
+// as VPCs or IAM roles, leaves the account in the leased OU to be cleaned
+// up and moved back by hand.
+package accountvend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/gruntwork-io/terratest/modules/aws"
+
+	"github.com/company/iac-framework/testing/sweeper"
+)
+
+const (
+	// PoolOUEnvVar enables account vending: the OU of accounts free to lease
+	PoolOUEnvVar = "TEST_ACCOUNT_POOL_OU"
+
+	// LeasedOUEnvVar is the OU leased accounts are moved to while in use
+	LeasedOUEnvVar = "TEST_ACCOUNT_LEASED_OU"
+
+	// EmailTemplateEnvVar is the root email of new accounts, with %s
+	// replaced by a unique suffix, e.g. "aws+terratest-%s@example.com".
+	// Without it an empty pool fails the run instead of creating accounts.
+	EmailTemplateEnvVar = "TEST_ACCOUNT_EMAIL_TEMPLATE"
+
+	// DefaultAccessRole is the role Organizations creates in new accounts
+	DefaultAccessRole = "OrganizationAccountAccessRole"
+
+	// TestRoleName is the role the suite runs as inside a leased account
+	TestRoleName = "TerratestExecution"
+)
+
+const (
+	// sessionDuration is the life of credentials handed to the suite.
+	// Chained role sessions are capped at an hour.
+	sessionDuration = time.Hour
+
+	// refreshEvery renews the credentials well before they expire
+	refreshEvery = 45 * time.Minute
+
+	createPollInterval = 15 * time.Second
+	createTimeout      = 30 * time.Minute
+)
+
+// credentialEnvVars are replaced while the suite runs in a leased account
+var credentialEnvVars = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE"}
+
+// Config says where accounts come from
+type Config struct {
+	PoolOU        string
+	LeasedOU      string
+	EmailTemplate string
+}
+
+// ConfigFromEnv reads the configuration from the environment. ok is false
+// when account vending is not configured.
+func ConfigFromEnv() (config Config, ok bool, err error) {
+	config = Config{
+		PoolOU:        os.Getenv(PoolOUEnvVar),
+		LeasedOU:      os.Getenv(LeasedOUEnvVar),
+		EmailTemplate: os.Getenv(EmailTemplateEnvVar),
+	}
+	if config.PoolOU == "" {
+		return config, false, nil
+	}
+	if config.LeasedOU == "" {
+		return config, false, fmt.Errorf("%s is set but %s is not", PoolOUEnvVar, LeasedOUEnvVar)
+	}
+	if config.EmailTemplate != "" && strings.Count(config.EmailTemplate, "%s") != 1 {
+		return config, false, fmt.Errorf("%s must contain %%s exactly once", EmailTemplateEnvVar)
+	}
+	return config, true, nil
+}
+
+// Lease is an account taken from the pool for one run
+type Lease struct {
+	AccountID string
+	config    Config
+
+	// caller holds the credentials the run started with, used for
+	// Organizations calls and to assume the test role
+	caller *session.Session
+
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+	savedEnv map[string]*string
+}
+
+// RoleArn is the test role in the leased account
+func (l *Lease) RoleArn() string {
+	return roleArn(l.AccountID, TestRoleName)
+}
+
+func roleArn(accountId string, role string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", accountId, role)
+}
+
+// Acquire leases an account from the pool, creating one if the pool is
+// empty and an email template is configured, and makes sure the test role
+// exists in it
+func Acquire(config Config) (*Lease, error) {
+	// Organizations is served from us-east-1
+	caller, err := aws.NewAuthenticatedSession("us-east-1")
+	if err != nil {
+		return nil, err
+	}
+	// Pin the caller's credentials before the environment is switched over
+	// to the leased account
+	value, err := caller.Config.Credentials.Get()
+	if err != nil {
+		return nil, fmt.Errorf("reading caller credentials: %w", err)
+	}
+	caller = caller.Copy(&awssdk.Config{Credentials: credentials.NewStaticCredentialsFromCreds(value)})
+
+	org := organizations.New(caller)
+	accountId, err := leaseFromPool(org, config)
+	if err != nil {
+		return nil, err
+	}
+	if accountId == "" {
+		if config.EmailTemplate == "" {
+			return nil, fmt.Errorf("no account free in %s and %s is not set", config.PoolOU, EmailTemplateEnvVar)
+		}
+		if accountId, err = create(org, config); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("leased account %s", accountId)
+
+	lease := &Lease{AccountID: accountId, config: config, caller: caller}
+	if err := lease.bootstrap(); err != nil {
+		// Nothing ran in the account, so it can go straight back
+		if moveErr := move(org, accountId, config.LeasedOU, config.PoolOU); moveErr != nil {
+			log.Printf("returning account %s to the pool: %v", accountId, moveErr)
+		}
+		return nil, fmt.Errorf("bootstrapping %s in %s: %w", TestRoleName, accountId, err)
+	}
+	return lease, nil
+}
+
+// leaseFromPool moves the first active pool account it can to the leased
+// OU and returns it, or "" if none could be had
+func leaseFromPool(org *organizations.Organizations, config Config) (string, error) {
+	var accounts []*organizations.Account
+	err := org.ListAccountsForParentPages(&organizations.ListAccountsForParentInput{ParentId: awssdk.String(config.PoolOU)},
+		func(page *organizations.ListAccountsForParentOutput, lastPage bool) bool {
+			accounts = append(accounts, page.Accounts...)
+			return true
+		})
+	if err != nil {
+		return "", fmt.Errorf("listing accounts in %s: %w", config.PoolOU, err)
+	}
+
+	for _, account := range accounts {
+		if awssdk.StringValue(account.Status) != organizations.AccountStatusActive {
+			continue
+		}
+		err := move(org, awssdk.StringValue(account.Id), config.PoolOU, config.LeasedOU)
+		if lostRace(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return awssdk.StringValue(account.Id), nil
+	}
+	return "", nil
+}
+
+// lostRace reports whether a move failed because another run moved the
+// account first
+func lostRace(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	switch aerr.Code() {
+	case organizations.ErrCodeSourceParentNotFoundException,
+		organizations.ErrCodeAccountNotFoundException,
+		organizations.ErrCodeConcurrentModificationException,
+		organizations.ErrCodeDuplicateAccountException:
+		return true
+	}
+	return false
+}
+
+func move(org *organizations.Organizations, accountId string, from string, to string) error {
+	_, err := org.MoveAccount(&organizations.MoveAccountInput{
+		AccountId:           awssdk.String(accountId),
+		SourceParentId:      awssdk.String(from),
+		DestinationParentId: awssdk.String(to),
+	})
+	return err
+}
+
+// create opens a new account, waits for it and moves it from the
+// organization root to the leased OU
+func create(org *organizations.Organizations, config Config) (string, error) {
+	suffix := time.Now().UTC().Format("20060102150405")
+	out, err := org.CreateAccount(&organizations.CreateAccountInput{
+		AccountName: awssdk.String("terratest-" + suffix),
+		Email:       awssdk.String(fmt.Sprintf(config.EmailTemplate, suffix)),
+		RoleName:    awssdk.String(DefaultAccessRole),
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating account: %w", err)
+	}
+	log.Printf("pool %s is empty, creating account terratest-%s", config.PoolOU, suffix)
+
+	requestId := out.CreateAccountStatus.Id
+	deadline := time.Now().Add(createTimeout)
+	for {
+		status, err := org.DescribeCreateAccountStatus(&organizations.DescribeCreateAccountStatusInput{CreateAccountRequestId: requestId})
+		if err != nil {
+			return "", err
+		}
+		switch awssdk.StringValue(status.CreateAccountStatus.State) {
+		case organizations.CreateAccountStateSucceeded:
+			accountId := awssdk.StringValue(status.CreateAccountStatus.AccountId)
+			parents, err := org.ListParents(&organizations.ListParentsInput{ChildId: awssdk.String(accountId)})
+			if err != nil {
+				return "", err
+			}
+			if len(parents.Parents) == 0 {
+				return "", fmt.Errorf("new account %s has no parent", accountId)
+			}
+			return accountId, move(org, accountId, awssdk.StringValue(parents.Parents[0].Id), config.LeasedOU)
+		case organizations.CreateAccountStateFailed:
+			return "", fmt.Errorf("creating account failed: %s", awssdk.StringValue(status.CreateAccountStatus.FailureReason))
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("account creation still in progress after %s", createTimeout)
+		}
+		time.Sleep(createPollInterval)
+	}
+}
+
+// bootstrap creates the test role through the organization access role if
+// it does not exist yet. It trusts the caller's account and gets
+// administrator access, since the suite creates and destroys all kinds of
+// resources.
+func (l *Lease) bootstrap() error {
+	identity, err := sts.New(l.caller).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return err
+	}
+	access := l.caller.Copy(&awssdk.Config{
+		Credentials: stscreds.NewCredentials(l.caller, roleArn(l.AccountID, DefaultAccessRole)),
+	})
+	client := iam.New(access)
+
+	_, err = client.GetRole(&iam.GetRoleInput{RoleName: awssdk.String(TestRoleName)})
+	if err == nil {
+		return nil
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != iam.ErrCodeNoSuchEntityException {
+		return err
+	}
+
+	_, err = client.CreateRole(&iam.CreateRoleInput{
+		RoleName:                 awssdk.String(TestRoleName),
+		AssumeRolePolicyDocument: awssdk.String(trustPolicy(awssdk.StringValue(identity.Account))),
+		Description:              awssdk.String("Assumed by Terratest runs leased this account"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.AttachRolePolicy(&iam.AttachRolePolicyInput{
+		RoleName:  awssdk.String(TestRoleName),
+		PolicyArn: awssdk.String("arn:aws:iam::aws:policy/AdministratorAccess"),
+	})
+	return err
+}
+
+func trustPolicy(accountId string) string {
+	return fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::%s:root"},"Action":"sts:AssumeRole"}]}`, accountId)
+}
+
+// Activate points the process environment, and so every session and
+// terraform run started from now on, at the test role in the leased
+// account. The credentials are renewed in the background until Deactivate.
+func (l *Lease) Activate() error {
+	l.savedEnv = map[string]*string{}
+	for _, name := range credentialEnvVars {
+		if value, ok := os.LookupEnv(name); ok {
+			l.savedEnv[name] = &value
+		} else {
+			l.savedEnv[name] = nil
+		}
+	}
+	if err := l.exportCredentials(); err != nil {
+		return err
+	}
+
+	l.stop = make(chan struct{})
+	l.stopped.Add(1)
+	go func() {
+		defer l.stopped.Done()
+		ticker := time.NewTicker(refreshEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				if err := l.exportCredentials(); err != nil {
+					log.Printf("renewing credentials for account %s: %v", l.AccountID, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (l *Lease) exportCredentials() error {
+	out, err := sts.New(l.caller).AssumeRole(&sts.AssumeRoleInput{
+		RoleArn:         awssdk.String(l.RoleArn()),
+		RoleSessionName: awssdk.String("terratest"),
+		DurationSeconds: awssdk.Int64(int64(sessionDuration / time.Second)),
+	})
+	if err != nil {
+		return fmt.Errorf("assuming %s: %w", l.RoleArn(), err)
+	}
+	os.Unsetenv("AWS_PROFILE")
+	os.Setenv("AWS_ACCESS_KEY_ID", awssdk.StringValue(out.Credentials.AccessKeyId))
+	os.Setenv("AWS_SECRET_ACCESS_KEY", awssdk.StringValue(out.Credentials.SecretAccessKey))
+	os.Setenv("AWS_SESSION_TOKEN", awssdk.StringValue(out.Credentials.SessionToken))
+	return nil
+}
+
+// Deactivate stops renewing credentials and restores the environment the
+// run started with
+func (l *Lease) Deactivate() {
+	if l.stop == nil {
+		return
+	}
+	close(l.stop)
+	l.stopped.Wait()
+	l.stop = nil
+	for name, value := range l.savedEnv {
+		if value == nil {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, *value)
+		}
+	}
+}
+
+// Release sweeps what the run left tagged in the account, in every enabled
+// region since suites pick their own, and returns it to the pool. If the
+// sweep fails, or tagged resources it cannot delete remain, the account
+// stays leased so the next run does not inherit the leftovers.
+func (l *Lease) Release() error {
+	if err := l.Activate(); err != nil {
+		return err
+	}
+	regions, err := sweeper.EnabledRegions()
+	if err != nil {
+		l.Deactivate()
+		return err
+	}
+	found, err := sweeper.Sweep(sweeper.Options{
+		Regions: regions,
+		// Every run's tag, whatever its value: nothing else belongs in a
+		// pool account, however recent
+		TagKey:    sweeper.DefaultTagKey,
+		OlderThan: time.Nanosecond,
+		Logf:      log.Printf,
+	})
+	if err != nil {
+		l.Deactivate()
+		return fmt.Errorf("sweeping account %s, leaving it leased: %w", l.AccountID, err)
+	}
+	log.Printf("swept %d leftover resource(s) from account %s", len(found), l.AccountID)
+
+	// The sweep has no step for VPCs, security groups, IAM roles and the
+	// like, so a run that leaked one must not hand the account on
+	unswept, err := sweeper.Unswept(sweeper.Options{
+		Regions: regions,
+		TagKey:  sweeper.DefaultTagKey,
+	})
+	l.Deactivate()
+	if err != nil {
+		return fmt.Errorf("listing what the sweep left in account %s, leaving it leased: %w", l.AccountID, err)
+	}
+	if len(unswept) > 0 {
+		for _, resource := range unswept {
+			log.Printf("left in account %s: %s", l.AccountID, resource)
+		}
+		return fmt.Errorf("%d tagged resource(s) the sweeper cannot delete remain in account %s, leaving it leased", len(unswept), l.AccountID)
+	}
+
+	if err := move(organizations.New(l.caller), l.AccountID, l.config.LeasedOU, l.config.PoolOU); err != nil {
+		return fmt.Errorf("returning account %s to the pool: %w", l.AccountID, err)
+	}
+	log.Printf("returned account %s to the pool", l.AccountID)
+	return nil
+}
+
+// Run runs the suite in a leased account when vending is configured, and
+// as it is otherwise. The suite gets a release function for paths that exit
+// without returning, such as the harness's interrupt handling; it is a no-op
+// without vending and releases the account only once. Use it from TestMain
+// around the harness:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(accountvend.Run(func(release func()) int {
+//			harness.OnInterrupt(release)
+//			return harness.RunWithInterruptHandling(m)
+//		}))
+//	}
+func Run(suite func(release func()) int) int {
+	config, ok, err := ConfigFromEnv()
+	if err != nil {
+		log.Printf("account vending: %v", err)
+		return 1
+	}
+	if !ok {
+		return suite(func() {})
+	}
+
+	lease, err := Acquire(config)
+	if err != nil {
+		log.Printf("account vending: %v", err)
+		return 1
+	}
+	if err := lease.Activate(); err != nil {
+		log.Printf("account vending: %v; account %s stays leased", err, lease.AccountID)
+		return 1
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			lease.Deactivate()
+			if err := lease.Release(); err != nil {
+				log.Printf("account vending: %v", err)
+			}
+		})
+	}
+	code := suite(release)
+	release()
+	return code
+}
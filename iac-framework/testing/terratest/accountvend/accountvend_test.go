@@ -0,0 +1,77 @@
+package accountvend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(PoolOUEnvVar, "")
+	_, ok, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, ok, "Vending should be off without a pool")
+
+	t.Setenv(PoolOUEnvVar, "ou-pool")
+	t.Setenv(LeasedOUEnvVar, "")
+	_, _, err = ConfigFromEnv()
+	assert.Error(t, err, "A pool without a leased OU should be rejected")
+
+	t.Setenv(LeasedOUEnvVar, "ou-leased")
+	t.Setenv(EmailTemplateEnvVar, "aws+terratest@example.com")
+	_, _, err = ConfigFromEnv()
+	assert.Error(t, err, "An email template without %s should be rejected")
+
+	t.Setenv(EmailTemplateEnvVar, "aws+terratest-%s@example.com")
+	config, ok, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Config{PoolOU: "ou-pool", LeasedOU: "ou-leased", EmailTemplate: "aws+terratest-%s@example.com"}, config)
+}
+
+func TestRunWithoutVending(t *testing.T) {
+	t.Setenv(PoolOUEnvVar, "")
+	assert.Equal(t, 3, Run(func(release func()) int {
+		// Nothing was leased, so there is nothing to release
+		release()
+		return 3
+	}))
+}
+
+func TestLostRace(t *testing.T) {
+	assert.True(t, lostRace(awserr.New(organizations.ErrCodeSourceParentNotFoundException, "moved", nil)))
+	assert.True(t, lostRace(fmt.Errorf("wrapped: %w", awserr.New(organizations.ErrCodeConcurrentModificationException, "busy", nil))))
+	assert.False(t, lostRace(awserr.New(organizations.ErrCodeAccessDeniedException, "denied", nil)))
+	assert.False(t, lostRace(nil))
+}
+
+func TestTrustPolicy(t *testing.T) {
+	var policy map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(trustPolicy("123456789012")), &policy))
+	assert.Contains(t, trustPolicy("123456789012"), `"arn:aws:iam::123456789012:root"`)
+}
+
+func TestDeactivateRestoresEnvironment(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "ci")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	os.Unsetenv("AWS_ACCESS_KEY_ID")
+
+	lease := &Lease{stop: make(chan struct{}), savedEnv: map[string]*string{}}
+	profile := "ci"
+	lease.savedEnv["AWS_PROFILE"] = &profile
+	lease.savedEnv["AWS_ACCESS_KEY_ID"] = nil
+	os.Unsetenv("AWS_PROFILE")
+	os.Setenv("AWS_ACCESS_KEY_ID", "ASIAVENDED")
+
+	lease.Deactivate()
+
+	assert.Equal(t, "ci", os.Getenv("AWS_PROFILE"))
+	_, set := os.LookupEnv("AWS_ACCESS_KEY_ID")
+	assert.False(t, set, "Variables unset before the lease should be unset again")
+}
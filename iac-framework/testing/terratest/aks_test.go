@@ -0,0 +1,110 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/tfshow"
+	"github.com/company/iac-framework/testing/workload"
+)
+
+// TestAKSCluster applies the AKS stack, checks the cluster and node pool
+// settings it was asked for, then deploys a sample workload through the
+// kubeconfig the stack outputs
+func TestAKSCluster(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "AKS runs on Azure")
+	if os.Getenv("ARM_SUBSCRIPTION_ID") == "" {
+		t.Skip("ARM_SUBSCRIPTION_ID is not set")
+	}
+
+	uniqueId := strings.ToLower(random.UniqueId())
+	kubernetesVersion := "1.28"
+	subnetCidr := "10.20.1.0/24"
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ClusterDir(t, "azure"),
+		Vars: map[string]interface{}{
+			"project_name":        fmt.Sprintf("tt-%s", uniqueId),
+			"environment":         "test",
+			"azure_location":      "East US",
+			"kubernetes_version":  kubernetesVersion,
+			"vnet_cidr":           "10.20.0.0/16",
+			"subnet_cidr":         subnetCidr,
+			"node_count":          1,
+			"min_node_count":      1,
+			"max_node_count":      2,
+			"user_node_count":     1,
+			"user_min_node_count": 1,
+			"user_max_node_count": 2,
+			"tags": map[string]string{
+				"Environment": "test",
+				"TestType":    "aks",
+			},
+		},
+	}
+
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	// What the stack asked Azure for
+	resources := tfshow.State(t, terraformOptions)
+	clusters := tfshow.OfType(resources, "azurerm_kubernetes_cluster")
+	require.Len(t, clusters, 1)
+	cluster := clusters[0]
+	assert.Equal(t, kubernetesVersion, cluster.String("kubernetes_version"))
+
+	network := cluster.Block("network_profile")
+	assert.Equal(t, "azure", network["network_plugin"], "Cluster should use Azure CNI")
+	assert.Equal(t, "azure", network["network_policy"], "Cluster should use Azure network policy")
+
+	defaultPool := cluster.Block("default_node_pool")
+	assert.EqualValues(t, 1, defaultPool["min_count"])
+	assert.EqualValues(t, 2, defaultPool["max_count"])
+
+	userPools := tfshow.OfType(resources, "azurerm_kubernetes_cluster_node_pool")
+	require.Len(t, userPools, 1)
+	assert.EqualValues(t, 1, userPools[0].Values["min_count"])
+	assert.EqualValues(t, 2, userPools[0].Values["max_count"])
+
+	// What the cluster actually runs
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	kubeconfig := terraform.Output(t, terraformOptions, "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0o600))
+	kubectl := k8s.NewKubectlOptions("", kubeconfigPath, "default")
+
+	k8s.WaitUntilAllNodesReady(t, kubectl, 30, 10*time.Second)
+	nodes := k8s.GetNodes(t, kubectl)
+	pools := workload.NodesByLabel(nodes, "agentpool")
+	for _, pool := range []string{"default", "user"} {
+		assert.GreaterOrEqual(t, pools[pool], 1, "Node pool %s should have at least its minimum nodes", pool)
+		assert.LessOrEqual(t, pools[pool], 2, "Node pool %s should not exceed its maximum nodes", pool)
+	}
+	for _, node := range nodes {
+		assert.True(t, strings.HasPrefix(node.Status.NodeInfo.KubeletVersion, "v"+kubernetesVersion+"."),
+			"Node %s runs kubelet %s, expected %s", node.Name, node.Status.NodeInfo.KubeletVersion, kubernetesVersion)
+	}
+
+	systemKubectl := k8s.NewKubectlOptions("", kubeconfigPath, "kube-system")
+	_, err := k8s.GetDaemonSetE(t, systemKubectl, "azure-npm")
+	assert.NoError(t, err, "Azure network policy manager should be running")
+
+	scoped := workload.Deploy(t, kubectl)
+	workload.AssertServes(t, scoped)
+
+	// Azure CNI gives pods addresses from the node subnet
+	workload.AssertPodIPsIn(t, workload.Pods(t, scoped), subnetCidr)
+}
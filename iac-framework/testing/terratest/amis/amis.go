@@ -0,0 +1,36 @@
+// Package amis resolves current AMI IDs through the SSM public parameters
+// AWS publishes in every region, so tests never pin an image ID that only
+// exists in one region or has since been deprecated.
+package amis
+
+import (
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// Image is the SSM public parameter that tracks the latest build of an image
+type Image string
+
+// Images the suites launch
+const (
+	AmazonLinux2    Image = "/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2"
+	AmazonLinux2023 Image = "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64"
+	Ubuntu2204      Image = "/aws/service/canonical/ubuntu/server/22.04/stable/current/amd64/hvm/ebs-gp2/ami-id"
+	Ubuntu2404      Image = "/aws/service/canonical/ubuntu/server/24.04/stable/current/amd64/hvm/ebs-gp3/ami-id"
+	Windows2022     Image = "/aws/service/ami-windows-latest/Windows_Server-2022-English-Full-Base"
+)
+
+// LatestId returns the current AMI ID for the image in the given region
+func LatestId(t testing.TestingT, region string, image Image) string {
+	id, err := LatestIdE(t, region, image)
+	require.NoError(t, err)
+	return id
+}
+
+// LatestIdE is LatestId returning an error instead of failing the test
+func LatestIdE(t testing.TestingT, region string, image Image) (string, error) {
+	return aws.GetParameterWithClientE(t, backend.NewSsmClient(t, region), string(image))
+}
@@ -0,0 +1,46 @@
+package amis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageParameters(t *testing.T) {
+	cases := []struct {
+		name      string
+		image     Image
+		publisher string
+		release   string
+		arch      string
+	}{
+		{"Amazon Linux 2", AmazonLinux2, "/aws/service/ami-amazon-linux-latest/", "amzn2-ami-hvm", "x86_64"},
+		{"Amazon Linux 2023", AmazonLinux2023, "/aws/service/ami-amazon-linux-latest/", "al2023-ami-kernel-default", "x86_64"},
+		{"Ubuntu 22.04", Ubuntu2204, "/aws/service/canonical/ubuntu/server/", "/22.04/stable/current/", "amd64"},
+		{"Ubuntu 24.04", Ubuntu2404, "/aws/service/canonical/ubuntu/server/", "/24.04/stable/current/", "amd64"},
+		{"Windows Server 2022", Windows2022, "/aws/service/ami-windows-latest/", "Windows_Server-2022-English-Full-Base", ""},
+	}
+
+	seen := map[Image]string{}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			parameter := string(tc.image)
+			assert.True(t, strings.HasPrefix(parameter, tc.publisher), "%s should be published under %s", parameter, tc.publisher)
+			assert.Contains(t, parameter, tc.release)
+
+			// The suites launch t3 instances, which only take x86 images
+			assert.Contains(t, parameter, tc.arch)
+			assert.NotContains(t, parameter, "arm64")
+
+			if strings.Contains(tc.publisher, "canonical") {
+				assert.True(t, strings.HasSuffix(parameter, "/ami-id"), "Canonical's parameters end in ami-id")
+			}
+		})
+
+		if other, ok := seen[tc.image]; ok {
+			t.Errorf("%s and %s share the parameter %s", tc.name, other, tc.image)
+		}
+		seen[tc.image] = tc.name
+	}
+}
@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	cases := map[string]string{
+		"":           AWS,
+		"aws":        AWS,
+		"localstack": LocalStack,
+		"LocalStack": LocalStack,
+	}
+	for value, expected := range cases {
+		t.Setenv(EnvVar, value)
+		assert.Equal(t, expected, Name(), "TEST_BACKEND=%q", value)
+	}
+}
+
+func TestLocalStackSession(t *testing.T) {
+	t.Setenv(EnvVar, LocalStack)
+	t.Setenv(EndpointEnvVar, "http://localstack:4566")
+
+	sess, err := NewSessionE("us-west-2")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localstack:4566", *sess.Config.Endpoint)
+	assert.True(t, *sess.Config.S3ForcePathStyle)
+
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "test", creds.AccessKeyID)
+}
+
+func TestSkipOnLocalStack(t *testing.T) {
+	t.Setenv(EnvVar, LocalStack)
+	skipped := t.Run("skipped", func(t *testing.T) {
+		SkipOnLocalStack(t, "instances do not boot")
+		t.Error("should not get here")
+	})
+	assert.True(t, skipped)
+}
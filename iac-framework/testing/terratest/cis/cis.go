@@ -0,0 +1,271 @@
+// Package cis checks deployed infrastructure against a subset of the CIS
+// AWS Foundations Benchmark (v1.4 control numbering). Module tests opt in
+// with one call to Assert and get a pass/fail line per control in the log.
+package cis
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3control"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// Control is a benchmark recommendation
+type Control struct {
+	ID    string
+	Title string
+}
+
+// Controls checked by this package
+var (
+	S3AccountPublicAccess = Control{ID: "2.1.5", Title: "S3 public access is blocked at the account level"}
+	EBSDefaultEncryption  = Control{ID: "2.2.1", Title: "EBS volume encryption is enabled by default"}
+	VPCFlowLogging        = Control{ID: "3.9", Title: "VPC flow logging is enabled"}
+	NoPublicSSH           = Control{ID: "5.2", Title: "No security group allows ingress from 0.0.0.0/0 or ::/0 to port 22"}
+)
+
+// Result is the outcome of one control
+type Result struct {
+	Control Control
+	Passed  bool
+	Detail  string
+}
+
+func (r Result) String() string {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	line := fmt.Sprintf("%s %-5s %s", status, r.Control.ID, r.Control.Title)
+	if r.Detail != "" {
+		line += ": " + r.Detail
+	}
+	return line
+}
+
+// Summary is the results of one run of checks
+type Summary []Result
+
+// Failed returns the results that did not pass
+func (s Summary) Failed() Summary {
+	var failed Summary
+	for _, result := range s {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+func (s Summary) String() string {
+	lines := make([]string, 0, len(s)+1)
+	lines = append(lines, fmt.Sprintf("CIS AWS Foundations: %d of %d controls passed", len(s)-len(s.Failed()), len(s)))
+	for _, result := range s {
+		lines = append(lines, "  "+result.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check evaluates one control in a region
+type Check struct {
+	Control Control
+	run     func(clients clients) (bool, string, error)
+}
+
+type clients struct {
+	region    string
+	ec2       *ec2.EC2
+	s3control *s3control.S3Control
+	sts       *sts.STS
+}
+
+// VPC returns the checks that apply to a VPC and what runs inside it
+func VPC(vpcId string) []Check {
+	return []Check{NoPublicSSHIn(vpcId), FlowLogsEnabled(vpcId)}
+}
+
+// Account returns the account and region wide checks
+func Account() []Check {
+	return []Check{EBSEncryptionByDefault(), S3PublicAccessBlocked()}
+}
+
+// NoPublicSSHIn checks the security groups of a VPC for SSH open to the world
+func NoPublicSSHIn(vpcId string) Check {
+	return Check{Control: NoPublicSSH, run: func(c clients) (bool, string, error) {
+		var groups []*ec2.SecurityGroup
+		err := c.ec2.DescribeSecurityGroupsPages(&ec2.DescribeSecurityGroupsInput{
+			Filters: []*ec2.Filter{{Name: awssdk.String("vpc-id"), Values: awssdk.StringSlice([]string{vpcId})}},
+		}, func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			groups = append(groups, page.SecurityGroups...)
+			return true
+		})
+		if err != nil {
+			return false, "", err
+		}
+		open := publicSSHGroups(groups)
+		if len(open) > 0 {
+			return false, "open in " + strings.Join(open, ", "), nil
+		}
+		return true, "", nil
+	}}
+}
+
+// FlowLogsEnabled checks a VPC has at least one active flow log
+func FlowLogsEnabled(vpcId string) Check {
+	return Check{Control: VPCFlowLogging, run: func(c clients) (bool, string, error) {
+		out, err := c.ec2.DescribeFlowLogs(&ec2.DescribeFlowLogsInput{
+			Filter: []*ec2.Filter{{Name: awssdk.String("resource-id"), Values: awssdk.StringSlice([]string{vpcId})}},
+		})
+		if err != nil {
+			return false, "", err
+		}
+		if !hasActiveFlowLog(out.FlowLogs) {
+			return false, "no active flow log on " + vpcId, nil
+		}
+		return true, "", nil
+	}}
+}
+
+// EBSEncryptionByDefault checks the region encrypts new volumes by default
+func EBSEncryptionByDefault() Check {
+	return Check{Control: EBSDefaultEncryption, run: func(c clients) (bool, string, error) {
+		out, err := c.ec2.GetEbsEncryptionByDefault(&ec2.GetEbsEncryptionByDefaultInput{})
+		if err != nil {
+			return false, "", err
+		}
+		if !awssdk.BoolValue(out.EbsEncryptionByDefault) {
+			return false, "disabled in " + c.region, nil
+		}
+		return true, "", nil
+	}}
+}
+
+// S3PublicAccessBlocked checks all four account level public access blocks
+func S3PublicAccessBlocked() Check {
+	return Check{Control: S3AccountPublicAccess, run: func(c clients) (bool, string, error) {
+		identity, err := c.sts.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+		if err != nil {
+			return false, "", err
+		}
+		out, err := c.s3control.GetPublicAccessBlock(&s3control.GetPublicAccessBlockInput{
+			AccountId: identity.Account,
+		})
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == "NoSuchPublicAccessBlockConfiguration" {
+			return false, "no account public access block configured", nil
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if missing := unblocked(out.PublicAccessBlockConfiguration); len(missing) > 0 {
+			return false, strings.Join(missing, ", ") + " not enabled", nil
+		}
+		return true, "", nil
+	}}
+}
+
+// Run evaluates the checks in region and logs the summary. A check that
+// could not be evaluated counts as failed.
+func Run(t *testing.T, region string, checks ...Check) Summary {
+	sess, err := backend.NewSessionE(region)
+	if err != nil {
+		t.Fatalf("creating AWS session: %v", err)
+	}
+	c := clients{region: region, ec2: ec2.New(sess), s3control: s3control.New(sess), sts: sts.New(sess)}
+
+	summary := make(Summary, 0, len(checks))
+	for _, check := range checks {
+		passed, detail, err := check.run(c)
+		if err != nil {
+			passed, detail = false, fmt.Sprintf("could not be evaluated: %v", err)
+		}
+		summary = append(summary, Result{Control: check.Control, Passed: passed, Detail: detail})
+	}
+	t.Log(summary.String())
+	return summary
+}
+
+// Assert runs the checks and fails the test for each control that fails
+func Assert(t *testing.T, region string, checks ...Check) Summary {
+	summary := Run(t, region, checks...)
+	for _, result := range summary.Failed() {
+		assert.Fail(t, "CIS control failed", result.String())
+	}
+	return summary
+}
+
+// publicSSHGroups returns the ids of groups letting the world reach port 22
+func publicSSHGroups(groups []*ec2.SecurityGroup) []string {
+	var open []string
+	for _, group := range groups {
+		for _, permission := range group.IpPermissions {
+			if coversSSH(permission) && worldReachable(permission) {
+				open = append(open, awssdk.StringValue(group.GroupId))
+				break
+			}
+		}
+	}
+	return open
+}
+
+func coversSSH(permission *ec2.IpPermission) bool {
+	switch awssdk.StringValue(permission.IpProtocol) {
+	case "-1":
+		return true
+	case "tcp", "6":
+		return awssdk.Int64Value(permission.FromPort) <= 22 && awssdk.Int64Value(permission.ToPort) >= 22
+	}
+	return false
+}
+
+func worldReachable(permission *ec2.IpPermission) bool {
+	for _, ipRange := range permission.IpRanges {
+		if awssdk.StringValue(ipRange.CidrIp) == "0.0.0.0/0" {
+			return true
+		}
+	}
+	for _, ipRange := range permission.Ipv6Ranges {
+		if awssdk.StringValue(ipRange.CidrIpv6) == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasActiveFlowLog(flowLogs []*ec2.FlowLog) bool {
+	for _, flowLog := range flowLogs {
+		if awssdk.StringValue(flowLog.FlowLogStatus) == "ACTIVE" {
+			return true
+		}
+	}
+	return false
+}
+
+func unblocked(config *s3control.PublicAccessBlockConfiguration) []string {
+	if config == nil {
+		return []string{"BlockPublicAcls", "IgnorePublicAcls", "BlockPublicPolicy", "RestrictPublicBuckets"}
+	}
+	var missing []string
+	for name, enabled := range map[string]*bool{
+		"BlockPublicAcls":       config.BlockPublicAcls,
+		"IgnorePublicAcls":      config.IgnorePublicAcls,
+		"BlockPublicPolicy":     config.BlockPublicPolicy,
+		"RestrictPublicBuckets": config.RestrictPublicBuckets,
+	} {
+		if !awssdk.BoolValue(enabled) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
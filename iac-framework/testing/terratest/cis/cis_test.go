@@ -0,0 +1,71 @@
+package cis
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3control"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicSSHGroups(t *testing.T) {
+	permission := func(protocol string, from, to int64, cidr string) *ec2.IpPermission {
+		return &ec2.IpPermission{
+			IpProtocol: awssdk.String(protocol),
+			FromPort:   awssdk.Int64(from),
+			ToPort:     awssdk.Int64(to),
+			IpRanges:   []*ec2.IpRange{{CidrIp: awssdk.String(cidr)}},
+		}
+	}
+	group := func(id string, permissions ...*ec2.IpPermission) *ec2.SecurityGroup {
+		return &ec2.SecurityGroup{GroupId: awssdk.String(id), IpPermissions: permissions}
+	}
+
+	groups := []*ec2.SecurityGroup{
+		group("sg-ssh-world", permission("tcp", 22, 22, "0.0.0.0/0")),
+		group("sg-ssh-office", permission("tcp", 22, 22, "203.0.113.0/24")),
+		group("sg-range-world", permission("tcp", 0, 1024, "0.0.0.0/0")),
+		group("sg-all-world", permission("-1", 0, 0, "0.0.0.0/0")),
+		group("sg-https-world", permission("tcp", 443, 443, "0.0.0.0/0")),
+		group("sg-ssh-ipv6", &ec2.IpPermission{
+			IpProtocol: awssdk.String("tcp"),
+			FromPort:   awssdk.Int64(22),
+			ToPort:     awssdk.Int64(22),
+			Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: awssdk.String("::/0")}},
+		}),
+	}
+
+	assert.Equal(t, []string{"sg-ssh-world", "sg-range-world", "sg-all-world", "sg-ssh-ipv6"}, publicSSHGroups(groups))
+}
+
+func TestHasActiveFlowLog(t *testing.T) {
+	flowLog := func(status string) *ec2.FlowLog {
+		return &ec2.FlowLog{FlowLogStatus: awssdk.String(status)}
+	}
+
+	assert.False(t, hasActiveFlowLog(nil))
+	assert.True(t, hasActiveFlowLog([]*ec2.FlowLog{flowLog("ACTIVE")}))
+}
+
+func TestUnblocked(t *testing.T) {
+	assert.Len(t, unblocked(nil), 4)
+	assert.Equal(t, []string{"BlockPublicPolicy", "RestrictPublicBuckets"}, unblocked(&s3control.PublicAccessBlockConfiguration{
+		BlockPublicAcls:   awssdk.Bool(true),
+		IgnorePublicAcls:  awssdk.Bool(true),
+		BlockPublicPolicy: awssdk.Bool(false),
+	}))
+}
+
+func TestSummary(t *testing.T) {
+	summary := Summary{
+		{Control: NoPublicSSH, Passed: true},
+		{Control: VPCFlowLogging, Detail: "no active flow log on vpc-1"},
+	}
+
+	assert.Equal(t, Summary{summary[1]}, summary.Failed())
+	assert.Equal(t, "CIS AWS Foundations: 1 of 2 controls passed\n"+
+		"  PASS 5.2   No security group allows ingress from 0.0.0.0/0 or ::/0 to port 22\n"+
+		"  FAIL 3.9   VPC flow logging is enabled: no active flow log on vpc-1",
+		summary.String())
+}
@@ -0,0 +1,100 @@
+// Command envdiff plans one module with each environment's tfvars and
+// reports the resource attributes that end up different between them.
+//
+// Environments are given as name=path pairs, in the order the report should
+// list them:
+//
+//	envdiff -module aws/vpc -env dev=fixtures/envdiff/vpc/dev.tfvars -env prod=fixtures/envdiff/vpc/prod.tfvars
+//
+// Attributes the tfvars are meant to change can be left out with -ignore,
+// which takes path.Match patterns such as "cidr_block" or "tags.*". With
+// -fail-on-diff it exits non-zero when any difference remains, so it can
+// gate a pipeline.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/company/iac-framework/testing/envdiff"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/tfshow"
+)
+
+// listFlag collects a flag given more than once
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	module := flag.String("module", "", "module to plan, relative to the module tree, e.g. aws/vpc")
+	asJSON := flag.Bool("json", false, "write the differences as JSON")
+	failOnDiff := flag.Bool("fail-on-diff", false, "exit non-zero when any difference is found")
+	var envFlags, ignore listFlag
+	flag.Var(&envFlags, "env", "environment as name=tfvars path; repeat for each environment")
+	flag.Var(&ignore, "ignore", "attribute pattern expected to differ; repeat as needed")
+	flag.Parse()
+
+	if *module == "" {
+		log.Fatal("no module given: pass -module")
+	}
+	envs, err := parseEnvironments(envFlags)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	moduleDir := filepath.Join(harness.ModulesRoot, *module)
+	plans := map[string][]tfshow.Resource{}
+	names := make([]string, len(envs))
+	for i, env := range envs {
+		log.Printf("planning %s with %s", *module, env.VarFile)
+		resources, err := envdiff.Plan(moduleDir, env)
+		if err != nil {
+			log.Fatal(err)
+		}
+		plans[env.Name] = resources
+		names[i] = env.Name
+	}
+
+	diffs := envdiff.Diff(plans, append(append([]string{}, envdiff.DefaultIgnore...), ignore...))
+	write := envdiff.WriteText
+	if *asJSON {
+		write = envdiff.WriteJSON
+	}
+	if err := write(os.Stdout, names, diffs); err != nil {
+		log.Fatal(err)
+	}
+	if *failOnDiff && len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseEnvironments(values []string) ([]envdiff.Environment, error) {
+	if len(values) < 2 {
+		return nil, fmt.Errorf("at least two environments are needed: pass -env name=path for each")
+	}
+
+	var envs []envdiff.Environment
+	seen := map[string]bool{}
+	for _, value := range values {
+		name, varFile, ok := strings.Cut(value, "=")
+		if !ok || name == "" || varFile == "" {
+			return nil, fmt.Errorf("invalid -env %q: expected name=path", value)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("environment %s given twice", name)
+		}
+		seen[name] = true
+		envs = append(envs, envdiff.Environment{Name: name, VarFile: varFile})
+	}
+	return envs, nil
+}
@@ -0,0 +1,143 @@
+// Command estimator prints how long the selected tests are expected to run
+// and what their infrastructure will cost, before anything is applied.
+// Estimates come from the JSON reports of earlier runs under the -reports
+// directories, which default to TEST_REPORT_DIR and the scheduler's report
+// directory.
+//
+// Tests are selected with a -run pattern, or as suites from a scheduler
+// config:
+//
+//	estimator -run '^TestVPC'
+//	estimator -config schedule.json -suite vpc -suite canary
+//
+// With -confirm-above, an interactive run whose estimated cost exceeds the
+// threshold asks before going on and exits non-zero when declined, so it
+// can guard a make target or a script.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/company/iac-framework/testing/estimator"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/schedule"
+)
+
+// listFlag collects a flag given more than once
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	pattern := flag.String("run", "", "estimate the tests matching this -run pattern")
+	configPath := flag.String("config", "", "estimate the suites of this scheduler config")
+	suiteDir := flag.String("suite-dir", ".", "directory of the test suite")
+	parallel := flag.Int("parallel", defaultParallel(), "tests running at once")
+	confirmAbove := flag.Float64("confirm-above", 0, "ask before going on when the estimated cost exceeds this; 0 never asks")
+	var suiteNames, reportDirs listFlag
+	flag.Var(&suiteNames, "suite", "suite of -config to estimate; repeat as needed, default all")
+	flag.Var(&reportDirs, "reports", "directory of earlier run reports; repeat as needed")
+	flag.Parse()
+
+	if len(reportDirs) == 0 {
+		reportDirs = defaultReportDirs()
+	}
+	history, err := estimator.LoadHistory(reportDirs...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	selections, err := selectSuites(*pattern, *configPath, suiteNames)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var suites []estimator.Suite
+	for _, selection := range selections {
+		tests, err := estimator.ListTests(*suiteDir, selection.Tests)
+		if err != nil {
+			log.Fatal(err)
+		}
+		suites = append(suites, estimator.EstimateSuite(selection.Name, tests, history))
+	}
+
+	if err := estimator.Write(os.Stdout, suites, *parallel); err != nil {
+		log.Fatal(err)
+	}
+
+	totals := estimator.Total(suites, *parallel)
+	if *confirmAbove > 0 && totals.Cost > *confirmAbove && interactive() {
+		fmt.Printf("Estimated cost %.2f %s is above %.2f. Continue? [y/N] ", totals.Cost, totals.Currency, *confirmAbove)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			os.Exit(1)
+		}
+	}
+}
+
+// selectSuites turns the flags into named test patterns
+func selectSuites(pattern string, configPath string, names []string) ([]schedule.Suite, error) {
+	switch {
+	case pattern != "" && configPath != "":
+		return nil, fmt.Errorf("pass -run or -config, not both")
+	case pattern != "":
+		return []schedule.Suite{{Name: pattern, Tests: pattern}}, nil
+	case configPath == "":
+		return nil, fmt.Errorf("nothing to estimate: pass -run or -config")
+	}
+
+	config, err := schedule.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return config.Suites, nil
+	}
+	var selected []schedule.Suite
+	for _, name := range names {
+		found := false
+		for _, suite := range config.Suites {
+			if suite.Name == name {
+				selected = append(selected, suite)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s has no suite %q", configPath, name)
+		}
+	}
+	return selected, nil
+}
+
+func defaultReportDirs() []string {
+	dir := os.Getenv(harness.ReportDirEnvVar)
+	if dir == "" {
+		dir = "test-reports"
+	}
+	return []string{dir, "scheduled-reports"}
+}
+
+// defaultParallel follows the infrastructure limit the suite runs with
+func defaultParallel() int {
+	value, err := strconv.Atoi(os.Getenv(harness.MaxParallelInfraEnvVar))
+	if err != nil || value < 1 {
+		return harness.DefaultMaxParallelInfra
+	}
+	return value
+}
+
+// interactive reports whether stdin is a terminal someone can answer from
+func interactive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,66 @@
+// Command probe runs one check from the probe catalog and prints its result
+// as a line starting with PROBE_RESULT, exiting non-zero when the check
+// fails. It is built into the image in this directory's Dockerfile and run
+// by probe.InCluster inside the environment under test:
+//
+//	probe -check http-get -arg url=http://smoke.default.svc.cluster.local -arg contains=nginx
+//
+// -list prints the registered checks.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/company/iac-framework/testing/probe"
+)
+
+// listFlag collects a flag given more than once
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	check := flag.String("check", "", "check to run")
+	list := flag.Bool("list", false, "list the registered checks and exit")
+	var argFlags listFlag
+	flag.Var(&argFlags, "arg", "check argument as key=value; repeat as needed")
+	flag.Parse()
+
+	if *list {
+		for _, name := range probe.Names() {
+			fmt.Println(name)
+		}
+		return
+	}
+	if *check == "" {
+		log.Fatal("no check given: pass -check")
+	}
+
+	args := map[string]string{}
+	for _, arg := range argFlags {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			log.Fatalf("invalid -arg %q, expected key=value", arg)
+		}
+		args[key] = value
+	}
+
+	result := probe.Run(*check, args)
+	line, err := probe.Format(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(line)
+	if !result.Passed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,69 @@
+// Command scheduler runs the suite on a schedule, for long-lived jobs that
+// keep conformance and upgrade canary runs going between releases.
+//
+// The schedule is a JSON config of suites, see schedule.example.json. Each
+// run's reports go to a directory under -report-dir and the run is appended
+// to the -history file, which decides what is due next, so the scheduler can
+// be restarted at any time. With -once it runs whatever is due and exits,
+// for a cron job or a scheduled container task instead of a process that
+// stays up.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/company/iac-framework/testing/schedule"
+)
+
+func main() {
+	configPath := flag.String("config", "schedule.json", "schedule config")
+	historyPath := flag.String("history", "schedule-history.jsonl", "history of runs, appended to after each run")
+	reportDir := flag.String("report-dir", "scheduled-reports", "directory run reports are written under")
+	suiteDir := flag.String("suite-dir", ".", "directory of the test suite")
+	once := flag.Bool("once", false, "run the suites that are due and exit")
+	maxSleep := flag.Duration("max-sleep", 15*time.Minute, "longest wait before the config and history are read again")
+	flag.Parse()
+
+	for {
+		config, err := schedule.Load(*configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		history, err := schedule.ReadHistory(*historyPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, suite := range schedule.Due(config, history, time.Now()) {
+			log.Printf("running %s (%s, tests %s)", suite.Name, suite.Mode, suite.Tests)
+			run := schedule.Execute(*suiteDir, *reportDir, suite)
+			switch {
+			case run.Error != "":
+				log.Printf("%s could not run: %s", suite.Name, run.Error)
+			case run.Passed:
+				log.Printf("%s passed in %s", suite.Name, run.Duration.Round(time.Second))
+			default:
+				log.Printf("%s failed in %s: %v", suite.Name, run.Duration.Round(time.Second), run.Failed)
+			}
+			if err := schedule.AppendHistory(*historyPath, run); err != nil {
+				log.Fatal(err)
+			}
+			history = append(history, run)
+		}
+
+		if *once {
+			return
+		}
+
+		wait := time.Until(schedule.NextDue(config, history, time.Now()))
+		if wait > *maxSleep {
+			wait = *maxSleep
+		}
+		if wait > 0 {
+			log.Printf("next run due in %s", wait.Round(time.Second))
+			time.Sleep(wait)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Command sweeper deletes AWS resources leaked by interrupted Terratest runs.
+//
+// It only touches resources carrying the TerratestRun tag harness.Track adds
+// (or the tag given with -tag-key, narrowed to one value with -tag-value,
+// such as a single run's ID) that are older than -older-than, and does nothing but
+// list them unless -dry-run=false is passed. With -manifest it first retries
+// terraform destroy for the workspaces an interrupted run left behind.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/sweeper"
+)
+
+func main() {
+	regions := flag.String("regions", os.Getenv("AWS_REGION"), "comma-separated regions to sweep, or \"all\" for every enabled region")
+	tagKey := flag.String("tag-key", sweeper.DefaultTagKey, "tag key identifying test resources")
+	tagValue := flag.String("tag-value", "", "only sweep this value of the tag, e.g. one run's ID; empty matches any")
+	olderThan := flag.Duration("older-than", sweeper.DefaultOlderThan, "only sweep resources created longer ago than this")
+	dryRun := flag.Bool("dry-run", true, "list resources without deleting them")
+	manifest := flag.String("manifest", "", "workspace manifest written by an interrupted test run")
+	flag.Parse()
+
+	if *manifest != "" {
+		if err := destroyManifest(*manifest, *dryRun); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	regionList, err := resolveRegions(*regions)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resources, err := sweeper.Sweep(sweeper.Options{
+		Regions:   regionList,
+		TagKey:    *tagKey,
+		TagValue:  *tagValue,
+		OlderThan: *olderThan,
+		DryRun:    *dryRun,
+		Logf:      log.Printf,
+	})
+
+	verb := "Deleted"
+	if *dryRun {
+		verb = "Found"
+	}
+	fmt.Printf("%s %d leaked resource(s) across %d region(s)\n", verb, len(resources), len(regionList))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func resolveRegions(value string) ([]string, error) {
+	switch value {
+	case "":
+		return nil, fmt.Errorf("no regions given: pass -regions or set AWS_REGION")
+	case "all":
+		return sweeper.EnabledRegions()
+	}
+
+	var regions []string
+	for _, region := range strings.Split(value, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
+
+// destroyManifest destroys the workspaces recorded in the manifest, keeping
+// any that still fail so the next sweep can try again
+func destroyManifest(path string, dryRun bool) error {
+	manifest, err := harness.ReadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, workspace := range manifest.Workspaces {
+			log.Printf("would destroy %s (%s, started %s)", workspace.TerraformDir, workspace.Test, workspace.StartedAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	remaining := harness.DestroyWorkspaces(manifest.Workspaces)
+	fmt.Printf("Destroyed %d of %d workspace(s) from %s\n", len(manifest.Workspaces)-len(remaining), len(manifest.Workspaces), path)
+	return harness.WriteManifest(path, &harness.Manifest{Workspaces: remaining})
+}
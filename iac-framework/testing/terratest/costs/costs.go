@@ -0,0 +1,80 @@
+// Package costs prices Terraform plans with Infracost so the run report can
+// show what each test's infrastructure costs to keep running.
+package costs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// Binary is the Infracost CLI
+	Binary = "infracost"
+
+	// APIKeyEnvVar holds the Infracost API key; estimates are skipped
+	// without it
+	APIKeyEnvVar = "INFRACOST_API_KEY"
+)
+
+// Estimate is the projected monthly cost of a plan
+type Estimate struct {
+	MonthlyCost float64
+	Currency    string
+}
+
+func (e Estimate) String() string {
+	return fmt.Sprintf("%.2f %s/month", e.MonthlyCost, e.Currency)
+}
+
+// Enabled reports whether Infracost is installed and configured
+func Enabled() bool {
+	if os.Getenv(APIKeyEnvVar) == "" {
+		return false
+	}
+	_, err := exec.LookPath(Binary)
+	return err == nil
+}
+
+// EstimatePlanE prices a plan previously written out by terraform show -json
+func EstimatePlanE(t testing.TestingT, planJSONPath string) (Estimate, error) {
+	out, err := shell.RunCommandAndGetStdOutE(t, shell.Command{
+		Command: Binary,
+		Args:    []string{"breakdown", "--path", planJSONPath, "--format", "json", "--no-color"},
+		Logger:  logger.Discard,
+	})
+	if err != nil {
+		return Estimate{}, err
+	}
+	return parseBreakdown([]byte(out))
+}
+
+// breakdown is the part of infracost's JSON output the report needs. Costs
+// are decimal strings, and null when nothing in the plan is priced.
+type breakdown struct {
+	Currency         string  `json:"currency"`
+	TotalMonthlyCost *string `json:"totalMonthlyCost"`
+}
+
+func parseBreakdown(src []byte) (Estimate, error) {
+	var parsed breakdown
+	if err := json.Unmarshal(src, &parsed); err != nil {
+		return Estimate{}, fmt.Errorf("parsing infracost output: %w", err)
+	}
+	estimate := Estimate{Currency: parsed.Currency}
+	if parsed.TotalMonthlyCost == nil {
+		return estimate, nil
+	}
+	cost, err := strconv.ParseFloat(*parsed.TotalMonthlyCost, 64)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("parsing infracost total %q: %w", *parsed.TotalMonthlyCost, err)
+	}
+	estimate.MonthlyCost = cost
+	return estimate, nil
+}
@@ -0,0 +1,27 @@
+package costs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBreakdown(t *testing.T) {
+	estimate, err := parseBreakdown([]byte(`{"version":"0.2","currency":"USD","projects":[],"totalHourlyCost":"0.0448","totalMonthlyCost":"32.704"}`))
+	require.NoError(t, err)
+	assert.Equal(t, Estimate{MonthlyCost: 32.704, Currency: "USD"}, estimate)
+	assert.Equal(t, "32.70 USD/month", estimate.String())
+
+	estimate, err = parseBreakdown([]byte(`{"currency":"USD","totalMonthlyCost":null}`))
+	require.NoError(t, err)
+	assert.Zero(t, estimate.MonthlyCost, "Plans with nothing priced cost nothing")
+
+	_, err = parseBreakdown([]byte(`{"currency":"USD","totalMonthlyCost":"lots"}`))
+	assert.Error(t, err)
+}
+
+func TestEnabledNeedsAPIKey(t *testing.T) {
+	t.Setenv(APIKeyEnvVar, "")
+	assert.False(t, Enabled())
+}
@@ -0,0 +1,213 @@
+// Package cwagent checks that a CloudWatch agent configuration delivered to
+// an instance is valid, that the agent runs with it, and that the metrics
+// it describes reach CloudWatch. Commands on the instance go through SSM,
+// so instances need AmazonSSMManagedInstanceCore as well as
+// CloudWatchAgentServerPolicy.
+package cwagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/ssmexec"
+)
+
+const (
+	// ConfigPath is where the agent reads its configuration from
+	ConfigPath = "/opt/aws/amazon-cloudwatch-agent/etc/amazon-cloudwatch-agent.json"
+
+	// DefaultNamespace is used when the configuration names none
+	DefaultNamespace = "CWAgent"
+
+	// MetricsTimeout bounds how long the first datapoints may take to show
+	// up after the agent starts
+	MetricsTimeout = 10 * time.Minute
+
+	pollInterval = 30 * time.Second
+
+	agentCtl = "/opt/aws/amazon-cloudwatch-agent/bin/amazon-cloudwatch-agent-ctl"
+)
+
+// Config is the part of the agent configuration the checks need
+type Config struct {
+	Metrics struct {
+		Namespace        string                     `json:"namespace"`
+		MetricsCollected map[string]json.RawMessage `json:"metrics_collected"`
+	} `json:"metrics"`
+}
+
+// plugin is one entry of metrics_collected. Measurements are either names
+// or objects with a name and an optional rename.
+type plugin struct {
+	Measurement []json.RawMessage `json:"measurement"`
+}
+
+type measurement struct {
+	Name   string `json:"name"`
+	Rename string `json:"rename"`
+}
+
+// Parse parses an agent configuration and requires it to collect at least
+// one metric
+func Parse(document []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(document, &config); err != nil {
+		return nil, fmt.Errorf("parsing agent configuration: %w", err)
+	}
+	if len(config.Metrics.MetricsCollected) == 0 {
+		return nil, fmt.Errorf("agent configuration collects no metrics")
+	}
+	return &config, nil
+}
+
+// Namespace returns the namespace the agent publishes to
+func (c *Config) Namespace() string {
+	if c.Metrics.Namespace == "" {
+		return DefaultNamespace
+	}
+	return c.Metrics.Namespace
+}
+
+// MetricNames returns the CloudWatch metric names the configuration
+// produces. The agent prefixes a measurement with its plugin name unless it
+// already carries the prefix or is renamed.
+func (c *Config) MetricNames() ([]string, error) {
+	var names []string
+	for name, raw := range c.Metrics.MetricsCollected {
+		var p plugin
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		for _, rawMeasurement := range p.Measurement {
+			var m measurement
+			if err := json.Unmarshal(rawMeasurement, &m.Name); err != nil {
+				if err := json.Unmarshal(rawMeasurement, &m); err != nil {
+					return nil, fmt.Errorf("parsing %s measurement %s: %w", name, rawMeasurement, err)
+				}
+			}
+			names = append(names, metricName(name, m))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func metricName(plugin string, m measurement) string {
+	switch {
+	case m.Rename != "":
+		return m.Rename
+	case strings.HasPrefix(m.Name, plugin+"_"):
+		return m.Name
+	default:
+		return plugin + "_" + m.Name
+	}
+}
+
+// UserData returns a script that installs the agent on Amazon Linux, writes
+// the configuration and starts the agent with it
+func UserData(config string) string {
+	return fmt.Sprintf(`#!/bin/bash
+set -euo pipefail
+yum install -y amazon-cloudwatch-agent
+cat > %[1]s <<'CWAGENT_CONFIG'
+%[2]s
+CWAGENT_CONFIG
+%[3]s -a fetch-config -m ec2 -s -c file:%[1]s`, ConfigPath, strings.TrimSpace(config), agentCtl)
+}
+
+// AssertDelivered reads the configuration back from the instance, asserts
+// it is valid and returns it
+func AssertDelivered(t testing.TestingT, region string, instanceId string) *Config {
+	document := ssmexec.AssertSucceeds(t, region, instanceId, "cat "+ConfigPath)
+	config, err := Parse([]byte(document))
+	require.NoError(t, err, "configuration at %s on %s", ConfigPath, instanceId)
+	return config
+}
+
+// status is the JSON amazon-cloudwatch-agent-ctl prints for -a status
+type status struct {
+	Status       string `json:"status"`
+	ConfigStatus string `json:"configstatus"`
+}
+
+func parseStatus(output string) (status, error) {
+	var s status
+	err := json.Unmarshal([]byte(output), &s)
+	return s, err
+}
+
+// AssertRunning asserts the agent is running with a configuration
+func AssertRunning(t testing.TestingT, region string, instanceId string) {
+	output := ssmexec.AssertSucceeds(t, region, instanceId, agentCtl+" -a status")
+	s, err := parseStatus(output)
+	require.NoError(t, err, "agent status on %s: %s", instanceId, output)
+	assert.Equal(t, "running", s.Status, "agent status on %s", instanceId)
+	assert.Equal(t, "configured", s.ConfigStatus, "agent configuration status on %s", instanceId)
+}
+
+// Metrics returns the names of the metrics the instance has published to
+// namespace
+func Metrics(region string, namespace string, instanceId string) (map[string]bool, error) {
+	sess, err := backend.NewSessionE(region)
+	if err != nil {
+		return nil, err
+	}
+	client := cloudwatch.New(sess)
+
+	names := map[string]bool{}
+	err = client.ListMetricsPages(&cloudwatch.ListMetricsInput{
+		Namespace: awssdk.String(namespace),
+		Dimensions: []*cloudwatch.DimensionFilter{{
+			Name:  awssdk.String("InstanceId"),
+			Value: awssdk.String(instanceId),
+		}},
+	}, func(page *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		for _, metric := range page.Metrics {
+			names[awssdk.StringValue(metric.MetricName)] = true
+		}
+		return true
+	})
+	return names, err
+}
+
+func missing(published map[string]bool, expected []string) []string {
+	var names []string
+	for _, name := range expected {
+		if !published[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// WaitForMetrics waits until every metric the configuration describes has
+// been published for the instance. The configuration must append the
+// InstanceId dimension.
+func WaitForMetrics(t testing.TestingT, region string, instanceId string, config *Config) {
+	expected, err := config.MetricNames()
+	require.NoError(t, err)
+
+	_, err = retry.DoWithRetryE(t, fmt.Sprintf("waiting for %s metrics from %s", config.Namespace(), instanceId), int(MetricsTimeout/pollInterval), pollInterval,
+		func() (string, error) {
+			published, err := Metrics(region, config.Namespace(), instanceId)
+			if err != nil {
+				return "", err
+			}
+			if names := missing(published, expected); len(names) > 0 {
+				return "", fmt.Errorf("not published yet: %s", strings.Join(names, ", "))
+			}
+			return "", nil
+		})
+	require.NoError(t, err)
+}
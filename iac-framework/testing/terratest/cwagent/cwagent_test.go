@@ -0,0 +1,81 @@
+package cwagent
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte(`{"metrics": {`))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`{"agent": {"metrics_collection_interval": 60}}`))
+	assert.EqualError(t, err, "agent configuration collects no metrics")
+
+	config, err := Parse([]byte(`{"metrics": {"metrics_collected": {"mem": {"measurement": ["used_percent"]}}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultNamespace, config.Namespace())
+}
+
+func TestMetricNames(t *testing.T) {
+	config, err := Parse([]byte(`{
+		"metrics": {
+			"namespace": "Custom/Agent",
+			"metrics_collected": {
+				"mem": {"measurement": ["mem_used_percent", "available"]},
+				"disk": {"measurement": [{"name": "used_percent"}, {"name": "free", "rename": "DiskFree"}]}
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Custom/Agent", config.Namespace())
+
+	names, err := config.MetricNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DiskFree", "disk_used_percent", "mem_available", "mem_used_percent"}, names)
+
+	bad, err := Parse([]byte(`{"metrics": {"metrics_collected": {"mem": {"measurement": [42]}}}}`))
+	require.NoError(t, err)
+	_, err = bad.MetricNames()
+	assert.Error(t, err)
+}
+
+func TestFixtureConfig(t *testing.T) {
+	document, err := os.ReadFile("../fixtures/cwagent/amazon-cloudwatch-agent.json")
+	require.NoError(t, err)
+
+	config, err := Parse(document)
+	require.NoError(t, err)
+	names, err := config.MetricNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"disk_used_percent", "mem_used_percent"}, names)
+
+	userData := UserData(string(document))
+	assert.True(t, strings.HasPrefix(userData, "#!/bin/bash\n"))
+	assert.Contains(t, userData, `"InstanceId": "${aws:InstanceId}"`)
+	assert.Contains(t, userData, "-c file:"+ConfigPath)
+}
+
+func TestParseStatus(t *testing.T) {
+	s, err := parseStatus(`{
+  "status": "running",
+  "starttime": "2024-01-01T00:00:00+00:00",
+  "configstatus": "configured",
+  "version": "1.300032.2"
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, status{Status: "running", ConfigStatus: "configured"}, s)
+
+	_, err = parseStatus("command not found")
+	assert.Error(t, err)
+}
+
+func TestMissing(t *testing.T) {
+	published := map[string]bool{"mem_used_percent": true}
+	assert.Equal(t, []string{"disk_used_percent"}, missing(published, []string{"disk_used_percent", "mem_used_percent"}))
+	assert.Empty(t, missing(published, []string{"mem_used_percent"}))
+}
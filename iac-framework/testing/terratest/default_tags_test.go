@@ -0,0 +1,145 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/amis"
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+	"github.com/company/iac-framework/testing/tagcheck"
+)
+
+// providerDefaultTags are set on the provider the way consumers usually tag
+// everything. Environment deliberately repeats a module tag with the same
+// value, which older providers turned into a perpetual diff.
+var providerDefaultTags = map[string]string{
+	"Environment": "test",
+	"CostCenter":  "platform-testing",
+	"ManagedBy":   "terraform",
+}
+
+// TestModulesWithProviderDefaultTags runs the standard module configurations
+// behind a provider with default_tags set and asserts the apply succeeds, a
+// second plan is empty and the resources carry both tag sets. The EC2 module
+// needs a real subnet, so it goes into the VPC applied first.
+func TestModulesWithProviderDefaultTags(t *testing.T) {
+	t.Parallel()
+
+	awsRegion := regions.PickRegion(t,
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(quotas.VPCs(1), quotas.InternetGateways(1)))
+	uniqueId := random.UniqueId()
+	moduleTags := map[string]string{
+		"Environment": "test",
+		"TestType":    "default-tags",
+	}
+
+	vpcOptions := &terraform.Options{
+		TerraformDir: moduleWithDefaultTags(t, "aws/vpc"),
+		Vars: map[string]interface{}{
+			"project_name":             fmt.Sprintf("tags-%s", uniqueId),
+			"environment":              "test",
+			"vpc_cidr":                 "10.2.0.0/16",
+			"availability_zones_count": 2,
+			"enable_nat_gateway":       false,
+			"tags":                     moduleTags,
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+
+	harness.Track(t, vpcOptions)
+	defer harness.Destroy(t, vpcOptions)
+
+	// A duplicate tag key between provider and resource used to fail the apply
+	harness.InitAndApply(t, vpcOptions)
+
+	t.Run("aws/vpc", func(t *testing.T) {
+		assertDefaultTags(t, vpcOptions, moduleTags, func() map[string]string {
+			return aws.GetTagsForVpc(t, terraform.Output(t, vpcOptions, "vpc_id"), awsRegion)
+		})
+	})
+
+	// Grouped so the VPC is only destroyed once the instance is
+	t.Run("aws/ec2", func(t *testing.T) {
+		ec2Options := &terraform.Options{
+			TerraformDir: moduleWithDefaultTags(t, "aws/ec2"),
+			Vars: map[string]interface{}{
+				"project_name":  fmt.Sprintf("tags-%s-ec2", uniqueId),
+				"environment":   "test",
+				"instance_type": "t3.micro",
+				"ami_id":        amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+				"vpc_id":        terraform.Output(t, vpcOptions, "vpc_id"),
+				"subnet_id":     terraform.OutputList(t, vpcOptions, "public_subnets")[0],
+				"tags":          moduleTags,
+			},
+			EnvVars: map[string]string{
+				"AWS_DEFAULT_REGION": awsRegion,
+			},
+		}
+
+		harness.Track(t, ec2Options)
+		defer harness.Destroy(t, ec2Options)
+		harness.InitAndApply(t, ec2Options)
+
+		assertDefaultTags(t, ec2Options, moduleTags, func() map[string]string {
+			instanceIds := terraform.OutputList(t, ec2Options, "instance_ids")
+			require.NotEmpty(t, instanceIds)
+			return aws.GetTagsForEc2Instance(t, awsRegion, instanceIds[0])
+		})
+	})
+}
+
+// moduleWithDefaultTags is a private module copy behind a provider with
+// providerDefaultTags
+func moduleWithDefaultTags(t *testing.T, module string) string {
+	moduleDir := harness.ModuleDir(t, module)
+	harness.WriteProviders(t, moduleDir, harness.ProviderConfig{
+		Name:        "aws",
+		DefaultTags: providerDefaultTags,
+	})
+	return moduleDir
+}
+
+// assertDefaultTags checks an applied module: the plan after apply is empty,
+// and its resources carry the provider tags, the test's module tags and the
+// Project tag the module sets from project_name
+func assertDefaultTags(t *testing.T, terraformOptions *terraform.Options, moduleTags map[string]string, tagsOf func() map[string]string) {
+	// Exit code 0 means no changes; 2 would be the perpetual tag diff
+	exitCode := terraform.PlanExitCode(t, terraformOptions)
+	assert.Equal(t, 0, exitCode, "Plan after apply should be empty with provider default_tags set")
+
+	// Resources should carry the provider and module tags merged
+	expectedTags := map[string]string{}
+	for key, value := range providerDefaultTags {
+		expectedTags[key] = value
+	}
+	for key, value := range moduleTags {
+		expectedTags[key] = value
+	}
+
+	// The module's own Project wins over anything passed in tags
+	expectedTags["Project"] = terraformOptions.Vars["project_name"].(string)
+
+	// Every taggable resource in state, not just the one looked up below
+	required := make([]string, 0, len(expectedTags))
+	for key := range expectedTags {
+		required = append(required, key)
+	}
+	sort.Strings(required)
+	tagcheck.AssertState(t, terraformOptions, required...)
+
+	backend.SkipOnLocalStack(t, "tag lookups use terratest's AWS clients")
+	validateResourceTags(t, expectedTags, tagsOf())
+}
@@ -3,15 +3,33 @@ package test
 import (
 	"testing"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/retry"
-	"github.com/gruntwork-io/terratest/modules/ssh"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/amis"
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/cwagent"
+	"github.com/company/iac-framework/testing/expectfail"
+	"github.com/company/iac-framework/testing/exposure"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/iamcheck"
+	"github.com/company/iac-framework/testing/imds"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+	"github.com/company/iac-framework/testing/sshcheck"
+	"github.com/company/iac-framework/testing/ssmcompliance"
+	"github.com/company/iac-framework/testing/ssmexec"
+	"github.com/company/iac-framework/testing/volumes"
 )
 
 // TestEC2Module validates the EC2 module functionality
@@ -19,28 +37,31 @@ func TestEC2Module(t *testing.T) {
 	t.Parallel()
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-%s", uniqueId)
-	awsRegion := "us-west-2"
+	projectName := fmt.Sprintf("ec2-%s", uniqueId)
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC(), regions.InstanceType("t3.micro"))
+
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316", // Amazon Linux 2
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678", // This would be from VPC output
-			"security_group_ids":  []string{"sg-12345678"},
-			"user_data":           "",
-			"enable_monitoring":   true,
-			"enable_eip":          false,
-			"root_volume_size":    20,
-			"root_volume_type":    "gp3",
-			"root_volume_encrypted": true,
+			"project_name":               projectName,
+			"environment":                "test",
+			"instance_type":              "t3.micro",
+			"ami_id":                     amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":                     defaultVpc.Id,
+			"subnet_id":                  defaultSubnets[0],
+			"enable_ssh_access":          false,
+			"enable_detailed_monitoring": true,
+			"root_block_device": map[string]string{
+				"volume_type": "gp3",
+				"volume_size": "20",
+				"encrypted":   "true",
+			},
 			"tags": map[string]string{
-				"Environment": "test",
-				"Project":     "terratest",
-				"Owner":       "infrastructure-team",
+				"Owner": "infrastructure-team",
 			},
 		},
 		EnvVars: map[string]string{
@@ -48,41 +69,41 @@ func TestEC2Module(t *testing.T) {
 		},
 	}
 
-	// We'll need to set up VPC first for a complete test
-	// For now, this is the structure of the test
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Validate outputs
-	instanceId := terraform.Output(t, terraformOptions, "instance_id")
-	privateIp := terraform.Output(t, terraformOptions, "private_ip")
-	publicIp := terraform.Output(t, terraformOptions, "public_ip")
+	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
+	privateIps := terraform.OutputList(t, terraformOptions, "instance_private_ips")
 
 	// Verify instance was created
-	assert.NotEmpty(t, instanceId, "Instance ID should not be empty")
-	assert.NotEmpty(t, privateIp, "Private IP should not be empty")
+	require.Len(t, instanceIds, 1, "The module should create one instance")
+	require.Len(t, privateIps, 1, "The instance should have a private IP")
+	assert.NotEmpty(t, privateIps[0], "Private IP should not be empty")
+	instanceId := instanceIds[0]
 
 	// Verify instance exists and is running
-	ec2Instance := aws.GetEc2InstanceById(t, instanceId, awsRegion)
+	ec2Instance := describeInstance(t, awsRegion, instanceId)
 	assert.Equal(t, "running", *ec2Instance.State.Name, "Instance should be running")
 	assert.Equal(t, "t3.micro", *ec2Instance.InstanceType, "Instance type should match")
 
-	// Verify tags
+	// Verify tags; the module sets Environment and Project itself
 	instanceTags := aws.GetTagsForEc2Instance(t, instanceId, awsRegion)
 	assert.Equal(t, "test", instanceTags["Environment"], "Environment tag should match")
-	assert.Equal(t, "terratest", instanceTags["Project"], "Project tag should match")
+	assert.Equal(t, projectName, instanceTags["Project"], "Project tag should match")
 	assert.Equal(t, "infrastructure-team", instanceTags["Owner"], "Owner tag should match")
 
 	// Verify root volume
-	volumes := aws.GetEbsVolumesForInstance(t, instanceId, awsRegion)
-	assert.Len(t, volumes, 1, "Should have one root volume")
-	rootVolume := volumes[0]
-	assert.Equal(t, int64(20), *rootVolume.Size, "Root volume size should be 20 GB")
-	assert.Equal(t, "gp3", *rootVolume.VolumeType, "Root volume type should be gp3")
-	assert.True(t, *rootVolume.Encrypted, "Root volume should be encrypted")
+	attached := volumes.Attached(t, awsRegion, instanceId)
+	assert.Len(t, attached, 1, "Should have one root volume")
+	rootVolume := attached[*ec2Instance.RootDeviceName]
+	assert.Equal(t, int64(20), rootVolume.SizeGiB, "Root volume size should be 20 GB")
+	assert.Equal(t, "gp3", rootVolume.Type, "Root volume type should be gp3")
+	assert.True(t, rootVolume.Encrypted, "Root volume should be encrypted")
 
 	// Verify monitoring is enabled
-	assert.True(t, *ec2Instance.Monitoring.State == "enabled", "Monitoring should be enabled")
+	assert.Equal(t, "enabled", *ec2Instance.Monitoring.State, "Monitoring should be enabled")
 }
 
 // TestEC2WithEIP tests EC2 instance with Elastic IP
@@ -90,22 +111,26 @@ func TestEC2WithEIP(t *testing.T) {
 	t.Parallel()
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-eip-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t,
+		regions.DefaultVPC(),
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(quotas.ElasticIPs(1)))
+
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678",
-			"security_group_ids":  []string{"sg-12345678"},
-			"enable_monitoring":   true,
-			"enable_eip":          true,
-			"root_volume_size":    10,
-			"root_volume_type":    "gp3",
+			"project_name":      fmt.Sprintf("eip-%s", uniqueId),
+			"environment":       "test",
+			"instance_type":     "t3.micro",
+			"ami_id":            amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":            defaultVpc.Id,
+			"subnet_id":         defaultSubnets[0],
+			"enable_ssh_access": false,
+			"create_eip":        true,
 			"tags": map[string]string{
 				"Environment": "test",
 				"TestType":    "eip",
@@ -116,29 +141,39 @@ func TestEC2WithEIP(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify EIP was created and associated
-	eipId := terraform.Output(t, terraformOptions, "eip_id")
-	eipPublicIp := terraform.Output(t, terraformOptions, "eip_public_ip")
-	
-	assert.NotEmpty(t, eipId, "EIP ID should not be empty")
-	assert.NotEmpty(t, eipPublicIp, "EIP public IP should not be empty")
+	eipIds := terraform.OutputList(t, terraformOptions, "eip_ids")
+	eipPublicIps := terraform.OutputList(t, terraformOptions, "eip_public_ips")
+
+	require.Len(t, eipIds, 1, "Should have one EIP")
+	require.Len(t, eipPublicIps, 1, "Should have one EIP public IP")
+	assert.NotEmpty(t, eipPublicIps[0], "EIP public IP should not be empty")
 
 	// Verify the EIP is associated with the instance
-	instanceId := terraform.Output(t, terraformOptions, "instance_id")
-	eip := aws.GetAddressById(t, eipId, awsRegion)
-	assert.Equal(t, instanceId, *eip.InstanceId, "EIP should be associated with the instance")
+	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
+	require.Len(t, instanceIds, 1, "The module should create one instance")
+	eip := describeAddress(t, awsRegion, eipIds[0])
+	assert.Equal(t, instanceIds[0], *eip.InstanceId, "EIP should be associated with the instance")
+	assert.Equal(t, eipPublicIps[0], *eip.PublicIp, "EIP output should match the address")
 }
 
 // TestEC2UserData tests EC2 instance with custom user data
 func TestEC2UserData(t *testing.T) {
 	t.Parallel()
+	backend.SkipOnLocalStack(t, "instances do not boot or run user data")
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-userdata-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC(), regions.InstanceType("t3.micro"))
+
+	// A default subnet routes to an internet gateway, so the instance is
+	// reachable over SSH once it has a public IP
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
 
 	userData := `#!/bin/bash
 yum update -y
@@ -148,18 +183,20 @@ systemctl enable httpd
 echo "<h1>Hello from Terratest!</h1>" > /var/www/html/index.html`
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678",
-			"security_group_ids":  []string{"sg-12345678"},
-			"user_data":           userData,
-			"enable_monitoring":   true,
-			"enable_eip":          true,
-			"root_volume_size":    10,
+			"project_name":                fmt.Sprintf("userdata-%s", uniqueId),
+			"environment":                 "test",
+			"instance_type":               "t3.micro",
+			"ami_id":                      amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":                      defaultVpc.Id,
+			"subnet_id":                   defaultSubnets[0],
+			"associate_public_ip_address": true,
+			// The module's security group lets only this runner in on port 22
+			"create_security_group": true,
+			"enable_ssh_access":     true,
+			"ssh_cidr_blocks":       []string{sshcheck.RunnerCidr(t)},
+			"user_data":             userData,
 			"tags": map[string]string{
 				"Environment": "test",
 				"TestType":    "userdata",
@@ -170,19 +207,23 @@ echo "<h1>Hello from Terratest!</h1>" > /var/www/html/index.html`
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	keyPair := harness.InjectKeyPair(t, awsRegion, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
-	// Wait for instance to be ready
-	instanceId := terraform.Output(t, terraformOptions, "instance_id")
-	aws.WaitForInstanceRunning(t, instanceId, awsRegion)
-
-	// Verify instance is running
-	ec2Instance := aws.GetEc2InstanceById(t, instanceId, awsRegion)
-	assert.Equal(t, "running", *ec2Instance.State.Name, "Instance should be running")
-
-	// You could add SSH connectivity test here if you have the key pair
-	// This would require setting up proper security groups and key pairs
+	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
+	require.Len(t, instanceIds, 1, "The module should create one instance")
+
+	// Verify the user data actually ran and httpd serves the page it wrote.
+	// Waiting for cloud-init also waits for the instance to boot.
+	publicIps := terraform.OutputList(t, terraformOptions, "instance_public_ips")
+	require.NotEmpty(t, publicIps, "The instance should have a public IP")
+	require.NotEmpty(t, publicIps[0], "The instance should have a public IP")
+	host := sshcheck.Host(publicIps[0], keyPair)
+	sshcheck.WaitForCloudInit(t, host)
+	page := sshcheck.Curl(t, host, "http://localhost/")
+	assert.Contains(t, page, "Hello from Terratest!", "httpd should serve the page from user data")
 }
 
 // TestEC2MultipleInstances tests creating multiple EC2 instances
@@ -190,21 +231,26 @@ func TestEC2MultipleInstances(t *testing.T) {
 	t.Parallel()
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-multi-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t,
+		regions.DefaultVPC(),
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(quotas.OnDemandVCPUs(6)))
+
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678",
-			"security_group_ids":  []string{"sg-12345678"},
-			"instance_count":      3,
-			"enable_monitoring":   true,
-			"root_volume_size":    10,
+			"project_name":      fmt.Sprintf("multi-%s", uniqueId),
+			"environment":       "test",
+			"instance_type":     "t3.micro",
+			"ami_id":            amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":            defaultVpc.Id,
+			"subnet_id":         defaultSubnets[0],
+			"enable_ssh_access": false,
+			"instance_count":    3,
 			"tags": map[string]string{
 				"Environment": "test",
 				"TestType":    "multiple",
@@ -215,8 +261,9 @@ func TestEC2MultipleInstances(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify multiple instances were created
 	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
@@ -224,45 +271,50 @@ func TestEC2MultipleInstances(t *testing.T) {
 
 	// Verify all instances are running
 	for i, instanceId := range instanceIds {
-		ec2Instance := aws.GetEc2InstanceById(t, instanceId, awsRegion)
+		ec2Instance := describeInstance(t, awsRegion, instanceId)
 		assert.Equal(t, "running", *ec2Instance.State.Name, fmt.Sprintf("Instance %d should be running", i))
 		assert.Equal(t, "t3.micro", *ec2Instance.InstanceType, fmt.Sprintf("Instance %d type should match", i))
 	}
 }
 
-// TestEC2SecurityGroups tests EC2 security group configuration
+// TestEC2SecurityGroups tests the custom ingress rules of the module's
+// security group
 func TestEC2SecurityGroups(t *testing.T) {
 	t.Parallel()
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-sg-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC(), regions.InstanceType("t3.micro"))
+
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678",
-			"security_group_ids":  []string{"sg-12345678", "sg-87654321"},
-			"enable_monitoring":   true,
+			"project_name":  fmt.Sprintf("sg-%s", uniqueId),
+			"environment":   "test",
+			"instance_type": "t3.micro",
+			"ami_id":        amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":        defaultVpc.Id,
+			"subnet_id":     defaultSubnets[0],
+			// Only the custom rules, without the module's default SSH rule
 			"create_security_group": true,
-			"security_group_rules": []map[string]interface{}{
+			"enable_ssh_access":     false,
+			"ingress_rules": []map[string]interface{}{
 				{
-					"type":        "ingress",
 					"from_port":   80,
 					"to_port":     80,
 					"protocol":    "tcp",
 					"cidr_blocks": []string{"0.0.0.0/0"},
+					"description": "HTTP from anywhere",
 				},
 				{
-					"type":        "ingress",
 					"from_port":   22,
 					"to_port":     22,
 					"protocol":    "tcp",
 					"cidr_blocks": []string{"10.0.0.0/16"},
+					"description": "SSH from the internal network",
 				},
 			},
 			"tags": map[string]string{
@@ -275,29 +327,43 @@ func TestEC2SecurityGroups(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
-	// Verify security group was created
+	// Verify security group was created and attached to the instance
 	securityGroupId := terraform.Output(t, terraformOptions, "security_group_id")
-	assert.NotEmpty(t, securityGroupId, "Security group ID should not be empty")
+	require.NotEmpty(t, securityGroupId, "Security group ID should not be empty")
+	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
+	require.Len(t, instanceIds, 1, "The module should create one instance")
+	ec2Instance := describeInstance(t, awsRegion, instanceIds[0])
+	require.Len(t, ec2Instance.SecurityGroups, 1, "The instance should only be in the module's security group")
+	assert.Equal(t, securityGroupId, *ec2Instance.SecurityGroups[0].GroupId)
 
 	// Verify security group rules
-	securityGroup := aws.GetSecurityGroupById(t, securityGroupId, awsRegion)
-	assert.NotNil(t, securityGroup, "Security group should exist")
-	
+	securityGroup := describeSecurityGroup(t, awsRegion, securityGroupId)
+
 	// Check ingress rules
 	assert.Len(t, securityGroup.IpPermissions, 2, "Should have 2 ingress rules")
-	
+
 	// Verify HTTP rule
 	httpRule := findRuleByPort(securityGroup.IpPermissions, 80)
-	assert.NotNil(t, httpRule, "HTTP rule should exist")
+	require.NotNil(t, httpRule, "HTTP rule should exist")
 	assert.Equal(t, "tcp", *httpRule.IpProtocol, "HTTP rule should be TCP")
-	
+	require.Len(t, httpRule.IpRanges, 1)
+	assert.Equal(t, "0.0.0.0/0", *httpRule.IpRanges[0].CidrIp, "HTTP rule should be open to the world")
+
 	// Verify SSH rule
 	sshRule := findRuleByPort(securityGroup.IpPermissions, 22)
-	assert.NotNil(t, sshRule, "SSH rule should exist")
+	require.NotNil(t, sshRule, "SSH rule should exist")
 	assert.Equal(t, "tcp", *sshRule.IpProtocol, "SSH rule should be TCP")
+	require.Len(t, sshRule.IpRanges, 1)
+	assert.Equal(t, "10.0.0.0/16", *sshRule.IpRanges[0].CidrIp, "SSH rule should only admit the internal network")
+
+	// Only the HTTP rule is meant to be reachable from the internet
+	exposure.Assert(t, terraformOptions, awsRegion, exposure.Allow{
+		"aws_security_group.this[0]:tcp/80": "the test opens HTTP to the world on purpose",
+	})
 }
 
 // TestEC2IAMRole tests EC2 instance with IAM role
@@ -305,21 +371,24 @@ func TestEC2IAMRole(t *testing.T) {
 	t.Parallel()
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-iam-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC(), regions.InstanceType("t3.micro"))
+
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678",
-			"security_group_ids":  []string{"sg-12345678"},
-			"enable_monitoring":   true,
-			"create_iam_role":     true,
-			"iam_role_policies": []string{
+			"project_name":      fmt.Sprintf("iam-%s", uniqueId),
+			"environment":       "test",
+			"instance_type":     "t3.micro",
+			"ami_id":            amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":            defaultVpc.Id,
+			"subnet_id":         defaultSubnets[0],
+			"enable_ssh_access": false,
+			"create_iam_role":   true,
+			"iam_policy_arns": []string{
 				"arn:aws:iam::aws:policy/CloudWatchAgentServerPolicy",
 				"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
 			},
@@ -333,37 +402,50 @@ func TestEC2IAMRole(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify IAM role was created
 	iamRoleArn := terraform.Output(t, terraformOptions, "iam_role_arn")
-	instanceProfileArn := terraform.Output(t, terraformOptions, "instance_profile_arn")
-	
+	instanceProfileArn := terraform.Output(t, terraformOptions, "iam_instance_profile_arn")
+
 	assert.NotEmpty(t, iamRoleArn, "IAM role ARN should not be empty")
-	assert.NotEmpty(t, instanceProfileArn, "Instance profile ARN should not be empty")
+	require.NotEmpty(t, instanceProfileArn, "Instance profile ARN should not be empty")
 
-	// Verify instance is associated with the IAM role
-	instanceId := terraform.Output(t, terraformOptions, "instance_id")
-	ec2Instance := aws.GetEc2InstanceById(t, instanceId, awsRegion)
-	assert.NotNil(t, ec2Instance.IamInstanceProfile, "Instance should have IAM instance profile")
+	// Verify instance is associated with the instance profile
+	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
+	require.Len(t, instanceIds, 1, "The module should create one instance")
+	ec2Instance := describeInstance(t, awsRegion, instanceIds[0])
+	require.NotNil(t, ec2Instance.IamInstanceProfile, "Instance should have IAM instance profile")
+	assert.Equal(t, instanceProfileArn, *ec2Instance.IamInstanceProfile.Arn, "Instance should use the module's instance profile")
+
+	// The role should grant what the attached policies are for and nothing
+	// beyond them
+	backend.SkipOnLocalStack(t, "the policy simulator and Access Analyzer are not emulated")
+	iamcheck.AssertActions(t, awsRegion, iamRoleArn,
+		[]string{"ssm:UpdateInstanceInformation", "cloudwatch:PutMetricData"},
+		[]string{"iam:CreateUser", "s3:DeleteBucket", "ec2:TerminateInstances"})
+	iamcheck.AssertLeastPrivilege(t, awsRegion, terraform.Output(t, terraformOptions, "iam_role_name"))
 }
 
 // TestEC2SpotInstance tests EC2 spot instance creation
 func TestEC2SpotInstance(t *testing.T) {
 	t.Parallel()
+	// use_spot_instance, spot_price, spot_type and the spot request output
+	// are not part of the ec2 module, so the apply below cannot succeed
+	t.Skip("the ec2 module does not support spot instances yet")
 
 	uniqueId := random.UniqueId()
 	instanceName := fmt.Sprintf("test-ec2-spot-%s", uniqueId)
 	awsRegion := "us-west-2"
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
 			"instance_name":        instanceName,
 			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
+			"ami_id":              amis.LatestId(t, awsRegion, amis.AmazonLinux2),
 			"subnet_id":           "subnet-12345678",
 			"security_group_ids":  []string{"sg-12345678"},
 			"enable_monitoring":   true,
@@ -380,8 +462,9 @@ func TestEC2SpotInstance(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify spot instance request was created
 	spotInstanceRequestId := terraform.Output(t, terraformOptions, "spot_instance_request_id")
@@ -397,7 +480,7 @@ func TestEC2SpotInstance(t *testing.T) {
 			return "", fmt.Errorf("Spot instance not yet fulfilled")
 		}
 		
-		ec2Instance := aws.GetEc2InstanceById(t, instanceId, awsRegion)
+		ec2Instance := describeInstance(t, awsRegion, instanceId)
 		if *ec2Instance.State.Name != "running" {
 			return "", fmt.Errorf("Instance not yet running: %s", *ec2Instance.State.Name)
 		}
@@ -409,45 +492,106 @@ func TestEC2SpotInstance(t *testing.T) {
 // Helper function to find security group rule by port
 func findRuleByPort(rules []*ec2.IpPermission, port int64) *ec2.IpPermission {
 	for _, rule := range rules {
-		if *rule.FromPort == port && *rule.ToPort == port {
+		if rule.FromPort != nil && *rule.FromPort == port && *rule.ToPort == port {
 			return rule
 		}
 	}
 	return nil
 }
 
+// describeInstance looks an instance up directly, since terratest only
+// offers tag and IP lookups for instances
+func describeInstance(t *testing.T, region string, instanceId string) *ec2.Instance {
+	out, err := backend.NewEc2Client(t, region).DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: awssdk.StringSlice([]string{instanceId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Reservations, 1)
+	require.Len(t, out.Reservations[0].Instances, 1)
+	return out.Reservations[0].Instances[0]
+}
+
+// describeAddress looks an Elastic IP up by allocation ID
+func describeAddress(t *testing.T, region string, allocationId string) *ec2.Address {
+	out, err := backend.NewEc2Client(t, region).DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: awssdk.StringSlice([]string{allocationId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Addresses, 1)
+	return out.Addresses[0]
+}
+
+// describeSecurityGroup looks a security group up by ID
+func describeSecurityGroup(t *testing.T, region string, groupId string) *ec2.SecurityGroup {
+	out, err := backend.NewEc2Client(t, region).DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: awssdk.StringSlice([]string{groupId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, out.SecurityGroups, 1)
+	return out.SecurityGroups[0]
+}
+
 // TestEC2DataVolumes tests EC2 instance with additional EBS volumes
 func TestEC2DataVolumes(t *testing.T) {
 	t.Parallel()
 
 	uniqueId := random.UniqueId()
-	instanceName := fmt.Sprintf("test-ec2-volumes-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC(), regions.InstanceType("t3.micro"))
+
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
+
+	// The module creates and attaches the volumes; the user data below is
+	// how a consumer formats and mounts them, so the test covers both
+	expectedVolumes := []volumes.Volume{
+		{DeviceName: "/dev/sdf", SizeGiB: 10, Type: "gp3", Encrypted: true},
+		{DeviceName: "/dev/sdg", SizeGiB: 20, Type: "gp3", Encrypted: true},
+	}
+	mountPoints := map[string]string{
+		"/dev/sdf": "/data1",
+		"/dev/sdg": "/data2",
+	}
+
+	// On Nitro instances the volumes appear as NVMe devices; Amazon Linux
+	// links the requested device names to them once udev has run
+	var userData strings.Builder
+	userData.WriteString("#!/bin/bash\nset -euo pipefail\n")
+	for _, volume := range expectedVolumes {
+		mountPoint := mountPoints[volume.DeviceName]
+		fmt.Fprintf(&userData, `for i in $(seq 60); do [ -e %[1]s ] && break; sleep 5; done
+blkid %[1]s || mkfs -t xfs %[1]s
+mkdir -p %[2]s
+echo "UUID=$(blkid -s UUID -o value %[1]s) %[2]s xfs defaults,nofail 0 2" >> /etc/fstab
+`, volume.DeviceName, mountPoint)
+	}
+	userData.WriteString("mount -a\n")
+
+	blockDevices := make([]map[string]string, len(expectedVolumes))
+	for i, volume := range expectedVolumes {
+		blockDevices[i] = map[string]string{
+			"device_name": volume.DeviceName,
+			"volume_size": fmt.Sprint(volume.SizeGiB),
+			"volume_type": volume.Type,
+			"encrypted":   fmt.Sprint(volume.Encrypted),
+		}
+	}
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/ec2",
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
 		Vars: map[string]interface{}{
-			"instance_name":        instanceName,
-			"instance_type":        "t3.micro",
-			"ami_id":              "ami-0c02fb55956c7d316",
-			"key_name":            "test-key",
-			"subnet_id":           "subnet-12345678",
-			"security_group_ids":  []string{"sg-12345678"},
-			"enable_monitoring":   true,
-			"additional_volumes": []map[string]interface{}{
-				{
-					"device_name": "/dev/sdf",
-					"volume_size": 10,
-					"volume_type": "gp3",
-					"encrypted":   true,
-				},
-				{
-					"device_name": "/dev/sdg",
-					"volume_size": 20,
-					"volume_type": "gp3",
-					"encrypted":   true,
-				},
-			},
+			"project_name":                fmt.Sprintf("volumes-%s", uniqueId),
+			"environment":                 "test",
+			"instance_type":               "t3.micro",
+			"ami_id":                      amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":                      defaultVpc.Id,
+			"subnet_id":                   defaultSubnets[0],
+			"associate_public_ip_address": true,
+			"enable_ssh_access":           false,
+			"create_iam_role":             true,
+			"iam_policy_arns":             []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"},
+			"ebs_block_devices":           blockDevices,
+			"user_data":                   userData.String(),
 			"tags": map[string]string{
 				"Environment": "test",
 				"TestType":    "data-volumes",
@@ -458,23 +602,317 @@ func TestEC2DataVolumes(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
-	// Verify additional volumes were created
-	instanceId := terraform.Output(t, terraformOptions, "instance_id")
-	volumes := aws.GetEbsVolumesForInstance(t, instanceId, awsRegion)
-	
-	// Should have root volume + 2 additional volumes
-	assert.Len(t, volumes, 3, "Should have 3 volumes total")
+	instanceIds := terraform.OutputList(t, terraformOptions, "instance_ids")
+	require.Len(t, instanceIds, 1, "The module should create one instance")
+	instanceId := instanceIds[0]
+
+	// The root volume plus one volume per requested block device
+	attached := volumes.Attached(t, awsRegion, instanceId)
+	assert.Len(t, attached, len(expectedVolumes)+1, "Should have the root volume and the data volumes")
+	volumes.AssertAttached(t, awsRegion, instanceId, expectedVolumes)
+
+	// Verify from inside the instance that each device is visible at its
+	// size, carries an XFS filesystem and is mounted from fstab and writable
+	backend.SkipOnLocalStack(t, "instances do not boot or run the SSM agent")
+	ssmexec.WaitForInstance(t, awsRegion, instanceId)
+	ssmexec.AssertSucceeds(t, awsRegion, instanceId, "cloud-init status --wait")
+	for _, volume := range expectedVolumes {
+		mountPoint := mountPoints[volume.DeviceName]
+
+		size := ssmexec.AssertSucceeds(t, awsRegion, instanceId, fmt.Sprintf("lsblk --nodeps --noheadings --bytes --output SIZE %s", volume.DeviceName))
+		assert.Equal(t, fmt.Sprint(volume.SizeGiB<<30), strings.TrimSpace(size), "%s should be visible with the requested size", volume.DeviceName)
+
+		fsType := ssmexec.AssertSucceeds(t, awsRegion, instanceId, fmt.Sprintf("blkid -s TYPE -o value %s", volume.DeviceName))
+		assert.Equal(t, "xfs", strings.TrimSpace(fsType), "%s should be formatted", volume.DeviceName)
+
+		mount := ssmexec.AssertMounted(t, awsRegion, instanceId, mountPoint, "xfs")
+		device := ssmexec.AssertSucceeds(t, awsRegion, instanceId, fmt.Sprintf("readlink -f %s", volume.DeviceName))
+		assert.Equal(t, strings.TrimSpace(device), mount.Source, "%s should be mounted from %s", mountPoint, volume.DeviceName)
+
+		ssmexec.AssertSucceeds(t, awsRegion, instanceId, fmt.Sprintf("findmnt --fstab %s", mountPoint))
+		ssmexec.AssertStdoutContains(t, awsRegion, instanceId,
+			fmt.Sprintf("echo terratest > %[1]s/probe && cat %[1]s/probe", mountPoint), "terratest")
+	}
+}
+
+// TestEC2IMDSv2 tests that instances require IMDSv2, with the module's
+// default metadata options and with a raised hop limit for containers
+func TestEC2IMDSv2(t *testing.T) {
+	t.Parallel()
+
+	uniqueId := random.UniqueId()
+	awsRegion := regions.PickRegion(t, privateVPCRequirement(2))
+
+	vpcOptions := privateVPCOptions(t, fmt.Sprintf("imds-%s", uniqueId), awsRegion, "10.6.0.0/16")
+	harness.Track(t, vpcOptions)
+	defer harness.Destroy(t, vpcOptions)
+	harness.InitAndApply(t, vpcOptions)
+
+	vpcId := terraform.Output(t, vpcOptions, "vpc_id")
+	privateSubnets := terraform.OutputList(t, vpcOptions, "private_subnets")
+	require.NotEmpty(t, privateSubnets)
+
+	cases := []struct {
+		name             string
+		metadataOptions  map[string]string
+		expectedHopLimit int64
+	}{
+		{
+			name:             "defaults",
+			expectedHopLimit: 1,
+		},
+		{
+			name: "container-hop-limit",
+			metadataOptions: map[string]string{
+				"http_endpoint":               "enabled",
+				"http_tokens":                 "required",
+				"http_put_response_hop_limit": "2",
+				"instance_metadata_tags":      "disabled",
+			},
+			expectedHopLimit: 2,
+		},
+	}
+
+	// Grouped so the VPC is only destroyed once every instance is done
+	t.Run("instances", func(t *testing.T) {
+		for _, tc := range cases {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				terraformOptions := &terraform.Options{
+					TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+					Vars: map[string]interface{}{
+						"project_name":    fmt.Sprintf("imds-%s-%s", uniqueId, tc.name),
+						"environment":     "test",
+						"instance_type":   "t3.micro",
+						"ami_id":          amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+						"vpc_id":          vpcId,
+						"subnet_id":       privateSubnets[0],
+						"create_iam_role": true,
+						"iam_policy_arns": []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"},
+						"tags": map[string]string{
+							"Environment": "test",
+							"TestType":    "imdsv2",
+						},
+					},
+					EnvVars: map[string]string{
+						"AWS_DEFAULT_REGION": awsRegion,
+					},
+				}
+				if tc.metadataOptions != nil {
+					terraformOptions.Vars["metadata_options"] = tc.metadataOptions
+				}
+
+				harness.Track(t, terraformOptions)
+				defer harness.Destroy(t, terraformOptions)
+				harness.InitAndApply(t, terraformOptions)
+
+				instanceId := terraform.OutputList(t, terraformOptions, "instance_ids")[0]
+				imds.AssertRequired(t, awsRegion, instanceId, tc.expectedHopLimit)
+
+				// An IMDSv1 call from inside the instance must be refused
+				backend.SkipOnLocalStack(t, "instances do not boot or run the SSM agent")
+				ssmexec.WaitForInstance(t, awsRegion, instanceId)
+				imds.AssertV1Rejected(t, awsRegion, instanceId)
+			})
+		}
+	})
+}
+
+// TestEC2SSMCompliance tests that instances register with the SSM inventory
+// and that the account's baseline associations succeed and report compliant
+func TestEC2SSMCompliance(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "instances do not boot or run the SSM agent")
+
+	uniqueId := random.UniqueId()
+	awsRegion := regions.PickRegion(t, privateVPCRequirement(1))
 
-	// Verify additional volumes
-	additionalVolumeIds := terraform.OutputList(t, terraformOptions, "additional_volume_ids")
-	assert.Len(t, additionalVolumeIds, 2, "Should have 2 additional volumes")
+	vpcOptions := privateVPCOptions(t, fmt.Sprintf("ssm-%s", uniqueId), awsRegion, "10.7.0.0/16")
+	harness.Track(t, vpcOptions)
+	defer harness.Destroy(t, vpcOptions)
+	harness.InitAndApply(t, vpcOptions)
 
-	for _, volumeId := range additionalVolumeIds {
-		volume := aws.GetEbsVolumeById(t, volumeId, awsRegion)
-		assert.True(t, *volume.Encrypted, "Additional volume should be encrypted")
-		assert.Equal(t, "gp3", *volume.VolumeType, "Additional volume should be gp3")
+	privateSubnets := terraform.OutputList(t, vpcOptions, "private_subnets")
+	require.NotEmpty(t, privateSubnets)
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+		Vars: map[string]interface{}{
+			"project_name":    fmt.Sprintf("ssm-%s", uniqueId),
+			"environment":     "test",
+			"instance_type":   "t3.micro",
+			"ami_id":          amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":          terraform.Output(t, vpcOptions, "vpc_id"),
+			"subnet_id":       privateSubnets[0],
+			"create_iam_role": true,
+			"iam_policy_arns": []string{
+				"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
+				"arn:aws:iam::aws:policy/CloudWatchAgentServerPolicy",
+			},
+			"tags": map[string]string{
+				"Environment": "test",
+				"TestType":    "ssm-compliance",
+			},
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
 	}
-}
\ No newline at end of file
+
+	// Deferred after the VPC's destroy, so the instance goes first
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	instanceId := terraform.OutputList(t, terraformOptions, "instance_ids")[0]
+	ssmexec.WaitForInstance(t, awsRegion, instanceId)
+	ssmcompliance.AssertBaseline(t, awsRegion, instanceId)
+}
+
+// TestEC2CloudWatchAgent tests that a CloudWatch agent configuration passed
+// through the module's user data arrives intact, starts the agent and
+// produces memory and disk metrics
+func TestEC2CloudWatchAgent(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "instances do not boot or run the SSM agent")
+
+	uniqueId := random.UniqueId()
+	awsRegion := regions.PickRegion(t, privateVPCRequirement(1))
+
+	agentConfig, err := os.ReadFile("fixtures/cwagent/amazon-cloudwatch-agent.json")
+	require.NoError(t, err)
+
+	vpcOptions := privateVPCOptions(t, fmt.Sprintf("cwagent-%s", uniqueId), awsRegion, "10.8.0.0/16")
+	harness.Track(t, vpcOptions)
+	defer harness.Destroy(t, vpcOptions)
+	harness.InitAndApply(t, vpcOptions)
+
+	privateSubnets := terraform.OutputList(t, vpcOptions, "private_subnets")
+	require.NotEmpty(t, privateSubnets)
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+		Vars: map[string]interface{}{
+			"project_name":    fmt.Sprintf("cwagent-%s", uniqueId),
+			"environment":     "test",
+			"instance_type":   "t3.micro",
+			"ami_id":          amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":          terraform.Output(t, vpcOptions, "vpc_id"),
+			"subnet_id":       privateSubnets[0],
+			"user_data":       cwagent.UserData(string(agentConfig)),
+			"create_iam_role": true,
+			"iam_policy_arns": []string{
+				"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
+				"arn:aws:iam::aws:policy/CloudWatchAgentServerPolicy",
+			},
+			"tags": map[string]string{
+				"Environment": "test",
+				"TestType":    "cloudwatch-agent",
+			},
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+
+	// Deferred after the VPC's destroy, so the instance goes first
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	instanceId := terraform.OutputList(t, terraformOptions, "instance_ids")[0]
+	ssmexec.WaitForInstance(t, awsRegion, instanceId)
+	ssmexec.AssertSucceeds(t, awsRegion, instanceId, "cloud-init status --wait")
+
+	config := cwagent.AssertDelivered(t, awsRegion, instanceId)
+	cwagent.AssertRunning(t, awsRegion, instanceId)
+	cwagent.WaitForMetrics(t, awsRegion, instanceId, config)
+}
+
+// TestEC2Validation checks the variable validations reject bad input with
+// their own error message
+func TestEC2Validation(t *testing.T) {
+	t.Parallel()
+
+	awsRegion := regions.PickRegion(t)
+
+	cases := []struct {
+		name    string
+		vars    map[string]interface{}
+		pattern string
+	}{
+		{
+			name:    "no-instances",
+			vars:    map[string]interface{}{"instance_count": 0},
+			pattern: `Instance count must be between 1 and 100`,
+		},
+		{
+			name:    "too-many-instances",
+			vars:    map[string]interface{}{"instance_count": 101},
+			pattern: `Instance count must be between 1 and 100`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vars := map[string]interface{}{
+				"project_name": fmt.Sprintf("ec2-validation-%s", random.UniqueId()),
+				"environment":  "test",
+				"subnet_id":    "subnet-12345678",
+			}
+			for name, value := range tc.vars {
+				vars[name] = value
+			}
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+				Vars:         vars,
+				EnvVars: map[string]string{
+					"AWS_DEFAULT_REGION": awsRegion,
+				},
+			}
+
+			expectfail.Plan(t, terraformOptions, tc.pattern)
+		})
+	}
+}
+
+// privateVPCRequirement asks for room for a VPC with one NAT gateway and
+// the given number of t3.micro instances behind it
+func privateVPCRequirement(instances int) regions.Requirement {
+	return quotas.Requirement(
+		quotas.VPCs(1),
+		quotas.InternetGateways(1),
+		quotas.NATGatewaysPerAZ(1),
+		quotas.ElasticIPs(1),
+		quotas.OnDemandVCPUs(2*instances))
+}
+
+// privateVPCOptions returns the options for a VPC whose private subnets
+// reach the SSM endpoints through a single NAT gateway, for tests that run
+// commands on instances without giving them a public IP
+func privateVPCOptions(t *testing.T, projectName string, awsRegion string, cidr string) *terraform.Options {
+	return &terraform.Options{
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
+		Vars: map[string]interface{}{
+			"project_name":             projectName,
+			"environment":              "test",
+			"vpc_cidr":                 cidr,
+			"availability_zones_count": 2,
+			"enable_nat_gateway":       true,
+			"single_nat_gateway":       true,
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+}
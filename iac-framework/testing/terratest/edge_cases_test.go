@@ -0,0 +1,95 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/modvars"
+	"github.com/company/iac-framework/testing/outputs"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// TestModuleVariableEdgeCases applies each module with only its required
+// variables, with every optional collection empty and with every nullable
+// optional set to null. Each variant must apply, keep the module's output
+// contract and leave nothing to change on a second plan.
+func TestModuleVariableEdgeCases(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the EC2 module needs a real subnet to plan against")
+
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC())
+
+	// The EC2 module looks its subnet up while planning, so it needs a real one
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
+
+	modules := []struct {
+		module   string
+		required map[string]interface{}
+	}{
+		{
+			module: "aws/vpc",
+			required: map[string]interface{}{
+				"project_name": "edge",
+				"environment":  "dev",
+			},
+		},
+		{
+			module: "aws/ec2",
+			required: map[string]interface{}{
+				"project_name": "edge",
+				"environment":  "dev",
+				"subnet_id":    defaultSubnets[0],
+			},
+		},
+	}
+
+	for _, m := range modules {
+		m := m
+		variables, err := modvars.Load(filepath.Join(harness.ModulesRoot, m.module))
+		require.NoError(t, err)
+
+		cases, err := modvars.EdgeCases(variables, m.required)
+		require.NoError(t, err)
+
+		for _, c := range cases {
+			c := c
+			t.Run(fmt.Sprintf("%s/%s", m.module, c.Name), func(t *testing.T) {
+				t.Parallel()
+
+				// Every case names its resources uniquely so variants can run side by side
+				c.Vars["project_name"] = fmt.Sprintf("%s-%s", c.Vars["project_name"], random.UniqueId())
+
+				terraformOptions := &terraform.Options{
+					TerraformDir: harness.ModuleDir(t, m.module),
+					Vars:         c.Vars,
+					EnvVars: map[string]string{
+						"AWS_DEFAULT_REGION": awsRegion,
+					},
+				}
+
+				harness.Track(t, terraformOptions)
+				defer harness.Destroy(t, terraformOptions)
+				harness.InitAndApply(t, terraformOptions)
+
+				// Outputs must still evaluate when optional inputs are empty or null
+				_, err := terraform.OutputAllE(t, terraformOptions)
+				assert.NoError(t, err, "Outputs should evaluate for %s", c.Name)
+				outputs.Assert(t, terraformOptions, m.module)
+
+				exitCode := terraform.PlanExitCode(t, terraformOptions)
+				assert.Equal(t, 0, exitCode, "Plan after apply should be empty for %s", c.Name)
+			})
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/efs"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/amis"
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+	"github.com/company/iac-framework/testing/ssmexec"
+)
+
+// mlSharedStorageMountTargets are the ML platform's EFS mount targets; a
+// targeted apply of them brings up the file system and the VPC it sits in
+// without the EKS cluster
+const mlSharedStorageMountTargets = "aws_efs_mount_target.ml_shared_storage"
+
+// TestMLPlatformSharedStorage applies the ML platform's shared EFS storage,
+// checks its throughput mode, encryption and mount targets, then mounts it
+// from an instance in a private subnet and writes a file through it. The
+// stack creates no access points, so their POSIX settings are not covered.
+func TestMLPlatformSharedStorage(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the platform stack configures its own AWS provider")
+
+	uniqueId := strings.ToLower(random.UniqueId())
+	// The stack spreads its VPC over three AZs with a NAT gateway in each
+	awsRegion := regions.PickRegion(t,
+		regions.Service("efs"),
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(
+			quotas.VPCs(1),
+			quotas.InternetGateways(1),
+			quotas.NATGatewaysPerAZ(1),
+			quotas.ElasticIPs(3),
+			quotas.OnDemandVCPUs(2)))
+
+	stackOptions := &terraform.Options{
+		TerraformDir: harness.PlatformDir(t, "aws"),
+		Vars: map[string]interface{}{
+			"aws_region":   awsRegion,
+			"project_name": fmt.Sprintf("tt-%s", uniqueId),
+			"environment":  "test",
+			"tags": map[string]string{
+				"Environment": "test",
+				"TestType":    "efs",
+			},
+		},
+		Targets: []string{mlSharedStorageMountTargets},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+
+	harness.Track(t, stackOptions)
+	defer harness.Destroy(t, stackOptions)
+	harness.InitAndApply(t, stackOptions)
+
+	fileSystemId := terraform.Output(t, stackOptions, "efs_file_system_id")
+	dnsName := terraform.Output(t, stackOptions, "efs_file_system_dns_name")
+	privateSubnets := terraform.OutputList(t, stackOptions, "private_subnets")
+	require.NotEmpty(t, privateSubnets)
+
+	sess, err := backend.NewSessionE(awsRegion)
+	require.NoError(t, err)
+	efsClient := efs.New(sess)
+
+	fileSystems, err := efsClient.DescribeFileSystems(&efs.DescribeFileSystemsInput{
+		FileSystemId: awssdk.String(fileSystemId),
+	})
+	require.NoError(t, err)
+	require.Len(t, fileSystems.FileSystems, 1)
+	fileSystem := fileSystems.FileSystems[0]
+	assert.Equal(t, efs.ThroughputModeProvisioned, awssdk.StringValue(fileSystem.ThroughputMode), "Throughput mode should be provisioned")
+	assert.Equal(t, float64(1000), awssdk.Float64Value(fileSystem.ProvisionedThroughputInMibps), "Provisioned throughput should match")
+	assert.Equal(t, efs.PerformanceModeGeneralPurpose, awssdk.StringValue(fileSystem.PerformanceMode), "Performance mode should be general purpose")
+	// The stack does not set encrypted, and turning it on replaces the file
+	// system of existing deployments. Once it is on, assert True here.
+	assert.False(t, awssdk.BoolValue(fileSystem.Encrypted), "The stack now encrypts EFS; assert encryption instead")
+
+	// One available mount target in each private subnet
+	mountTargets, err := efsClient.DescribeMountTargets(&efs.DescribeMountTargetsInput{
+		FileSystemId: awssdk.String(fileSystemId),
+	})
+	require.NoError(t, err)
+	var mountedSubnets []string
+	for _, mountTarget := range mountTargets.MountTargets {
+		mountedSubnets = append(mountedSubnets, awssdk.StringValue(mountTarget.SubnetId))
+		assert.Equal(t, efs.LifeCycleStateAvailable, awssdk.StringValue(mountTarget.LifeCycleState),
+			"Mount target %s should be available", awssdk.StringValue(mountTarget.MountTargetId))
+	}
+	sort.Strings(mountedSubnets)
+	sort.Strings(privateSubnets)
+	assert.Equal(t, privateSubnets, mountedSubnets, "Every private subnet should have a mount target")
+
+	// Mount it from an instance in a private subnet, as the training nodes do
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+		Vars: map[string]interface{}{
+			"project_name":      fmt.Sprintf("efs-%s", uniqueId),
+			"environment":       "test",
+			"instance_type":     "t3.micro",
+			"ami_id":            amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+			"vpc_id":            terraform.Output(t, stackOptions, "vpc_id"),
+			"subnet_id":         privateSubnets[0],
+			"enable_ssh_access": false,
+			"create_iam_role":   true,
+			"iam_policy_arns":   []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"},
+			"tags": map[string]string{
+				"Environment": "test",
+				"TestType":    "efs",
+			},
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+
+	// Deferred after the stack's destroy, so the instance goes first
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	instanceId := terraform.OutputList(t, terraformOptions, "instance_ids")[0]
+	ssmexec.WaitForInstance(t, awsRegion, instanceId)
+	ssmexec.AssertSucceeds(t, awsRegion, instanceId, fmt.Sprintf(
+		"mkdir -p /mnt/efs && mount -t nfs4 -o nfsvers=4.1,rsize=1048576,wsize=1048576,hard,timeo=600,retrans=2 %s:/ /mnt/efs", dnsName))
+	ssmexec.AssertMounted(t, awsRegion, instanceId, "/mnt/efs", "nfs4")
+
+	probeFile := fmt.Sprintf("/mnt/efs/terratest-%s", uniqueId)
+	ssmexec.AssertStdoutContains(t, awsRegion, instanceId,
+		fmt.Sprintf("echo %[2]s > %[1]s && sync && cat %[1]s && rm %[1]s", probeFile, uniqueId), uniqueId)
+}
@@ -0,0 +1,86 @@
+// Package egress checks which public IP outbound traffic from an instance
+// leaves with. Consumers hand the NAT gateway EIPs to partners to allowlist,
+// so traffic leaving by any other address is an outage for them.
+package egress
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/ssmexec"
+)
+
+// EchoURLEnvVar points the checks at a different echo-IP service, e.g. the
+// fixture app deployed publicly
+const EchoURLEnvVar = "TEST_EGRESS_ECHO_URL"
+
+// DefaultEchoURL answers with the caller's public IP and nothing else
+const DefaultEchoURL = "https://checkip.amazonaws.com"
+
+// Samples is how many requests are made per check. NAT gateways keep one
+// address, so every request should agree.
+const Samples = 3
+
+// EchoURL returns the echo-IP service to use
+func EchoURL() string {
+	if url := os.Getenv(EchoURLEnvVar); url != "" {
+		return url
+	}
+	return DefaultEchoURL
+}
+
+// ObservedIPs asks the echo service for the instance's public IP Samples
+// times over SSM and returns the answers
+func ObservedIPs(t testing.TestingT, region string, instanceId string) []string {
+	command := fmt.Sprintf("for i in $(seq %d); do curl --silent --fail --max-time 10 %s; echo; done", Samples, EchoURL())
+	ips, err := parseIPs(ssmexec.AssertSucceeds(t, region, instanceId, command))
+	require.NoError(t, err)
+	return ips
+}
+
+func parseIPs(output string) ([]string, error) {
+	var ips []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if net.ParseIP(line) == nil {
+			return nil, fmt.Errorf("echo service answered %q, not an IP address", line)
+		}
+		ips = append(ips, line)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("echo service returned nothing")
+	}
+	return ips, nil
+}
+
+// AssertVia asserts every request from the instance left with one of the
+// expected addresses, usually the VPC's nat_public_ips
+func AssertVia(t testing.TestingT, region string, instanceId string, expected ...string) {
+	for _, problem := range check(ObservedIPs(t, region, instanceId), expected) {
+		assert.Fail(t, problem)
+	}
+}
+
+func check(observed []string, expected []string) []string {
+	allowed := map[string]bool{}
+	for _, ip := range expected {
+		allowed[ip] = true
+	}
+
+	var problems []string
+	for _, ip := range observed {
+		if !allowed[ip] {
+			problems = append(problems, fmt.Sprintf("Outbound traffic left from %s, expected one of %s", ip, strings.Join(expected, ", ")))
+		}
+	}
+	return problems
+}
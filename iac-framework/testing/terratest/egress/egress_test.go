@@ -0,0 +1,30 @@
+package egress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPs(t *testing.T) {
+	ips, err := parseIPs("52.10.1.1\n\n52.10.1.1\n52.10.1.1\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"52.10.1.1", "52.10.1.1", "52.10.1.1"}, ips)
+
+	_, err = parseIPs("<html>blocked</html>\n")
+	assert.Error(t, err, "Anything but an address should be rejected")
+
+	_, err = parseIPs("\n\n")
+	assert.Error(t, err, "No answer at all should be rejected")
+}
+
+func TestCheck(t *testing.T) {
+	expected := []string{"52.10.1.1", "52.10.2.2"}
+
+	assert.Empty(t, check([]string{"52.10.1.1", "52.10.1.1"}, expected))
+	assert.Equal(t,
+		[]string{"Outbound traffic left from 34.1.1.1, expected one of 52.10.1.1, 52.10.2.2"},
+		check([]string{"52.10.1.1", "34.1.1.1"}, expected),
+	)
+}
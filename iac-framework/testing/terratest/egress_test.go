@@ -0,0 +1,104 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/amis"
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/egress"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+	"github.com/company/iac-framework/testing/ssmexec"
+)
+
+// TestVPCNATEgressIPs places an instance in each private subnet of a VPC
+// with a NAT gateway per AZ and asserts its outbound requests arrive from
+// that AZ's NAT EIP, the address consumers give out for allowlisting
+func TestVPCNATEgressIPs(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "instances do not boot or reach the internet")
+
+	uniqueId := random.UniqueId()
+
+	// A NAT gateway and EIP in each of two AZs plus a t3.micro behind each
+	awsRegion := regions.PickRegion(t,
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(
+			quotas.VPCs(1),
+			quotas.InternetGateways(1),
+			quotas.NATGatewaysPerAZ(1),
+			quotas.ElasticIPs(2),
+			quotas.OnDemandVCPUs(4)))
+	tags := map[string]string{
+		"Environment": "test",
+		"TestType":    "egress-ip",
+	}
+
+	vpcOptions := &terraform.Options{
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
+		Vars: map[string]interface{}{
+			"project_name":             fmt.Sprintf("egress-%s", uniqueId),
+			"environment":              "test",
+			"vpc_cidr":                 "10.4.0.0/16",
+			"availability_zones_count": 2,
+			"enable_nat_gateway":       true,
+			"single_nat_gateway":       false,
+			"tags":                     tags,
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+
+	harness.Track(t, vpcOptions)
+	defer harness.Destroy(t, vpcOptions)
+	harness.InitAndApply(t, vpcOptions)
+
+	vpcId := terraform.Output(t, vpcOptions, "vpc_id")
+	privateSubnets := terraform.OutputList(t, vpcOptions, "private_subnets")
+	natIps := terraform.OutputList(t, vpcOptions, "nat_public_ips")
+	require.Len(t, natIps, len(privateSubnets), "Expected one NAT gateway per private subnet")
+
+	// Grouped so the VPC is only destroyed once every instance is done
+	t.Run("instances", func(t *testing.T) {
+		for i, subnetId := range privateSubnets {
+			i, subnetId := i, subnetId
+			t.Run(fmt.Sprintf("subnet-%d", i), func(t *testing.T) {
+				t.Parallel()
+
+				ec2Options := &terraform.Options{
+					TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+					Vars: map[string]interface{}{
+						"project_name":                fmt.Sprintf("egress-%s-%d", uniqueId, i),
+						"environment":                 "test",
+						"ami_id":                      amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+						"vpc_id":                      vpcId,
+						"subnet_id":                   subnetId,
+						"associate_public_ip_address": false,
+						"create_iam_role":             true,
+						"iam_policy_arns":             []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"},
+						"tags":                        tags,
+					},
+					EnvVars: map[string]string{
+						"AWS_DEFAULT_REGION": awsRegion,
+					},
+				}
+
+				harness.Track(t, ec2Options)
+				defer harness.Destroy(t, ec2Options)
+				harness.InitAndApply(t, ec2Options)
+
+				// Private subnet i routes through the NAT gateway in the same AZ
+				instanceId := terraform.OutputList(t, ec2Options, "instance_ids")[0]
+				ssmexec.WaitForInstance(t, awsRegion, instanceId)
+				egress.AssertVia(t, awsRegion, instanceId, natIps[i])
+			})
+		}
+	})
+}
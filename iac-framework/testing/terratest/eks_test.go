@@ -0,0 +1,107 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/k8scleanup"
+	"github.com/company/iac-framework/testing/probe"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+	"github.com/company/iac-framework/testing/workload"
+)
+
+// TestEKSCluster applies the EKS stack, deploys a sample workload behind a
+// LoadBalancer service and fetches a page through it. The load balancer is
+// created by Kubernetes, not terraform, so k8scleanup removes it before the
+// stack is destroyed.
+func TestEKSCluster(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "EKS control planes are not emulated")
+	if _, err := exec.LookPath("aws"); err != nil {
+		t.Skip("The generated kubeconfig needs the aws CLI")
+	}
+
+	awsRegion := regions.PickRegion(t,
+		regions.Service("eks"),
+		regions.InstanceType("t3.medium"),
+		quotas.Requirement(
+			quotas.VPCs(1),
+			quotas.InternetGateways(1),
+			quotas.NATGatewaysPerAZ(1),
+			quotas.ElasticIPs(1),
+			quotas.OnDemandVCPUs(4)))
+
+	uniqueId := strings.ToLower(random.UniqueId())
+	kubernetesVersion := "1.28"
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ClusterDir(t, "aws"),
+		Vars: map[string]interface{}{
+			"aws_region":              awsRegion,
+			"project_name":            fmt.Sprintf("tt-%s", uniqueId),
+			"environment":             "test",
+			"kubernetes_version":      kubernetesVersion,
+			"single_nat_gateway":      true,
+			"node_instance_types":     []string{"t3.medium"},
+			"node_group_min_size":     1,
+			"node_group_max_size":     2,
+			"node_group_desired_size": 2,
+			"tags": map[string]string{
+				"Environment": "test",
+				"TestType":    "eks",
+			},
+		},
+		EnvVars: map[string]string{
+			"AWS_DEFAULT_REGION": awsRegion,
+		},
+	}
+
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	clusterName := terraform.Output(t, terraformOptions, "cluster_name")
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	kubeconfig := terraform.Output(t, terraformOptions, "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0o600))
+	kubectl := k8s.NewKubectlOptions("", kubeconfigPath, "default")
+
+	// Deferred after Destroy, so it runs first
+	defer k8scleanup.Run(t, kubectl, awsRegion, clusterName)
+
+	k8s.WaitUntilAllNodesReady(t, kubectl, 30, 10*time.Second)
+	nodes := k8s.GetNodes(t, kubectl)
+	assert.Equal(t, 2, workload.NodesByLabel(nodes, "role")["main"], "Node group main should have its desired nodes")
+	for _, node := range nodes {
+		assert.True(t, strings.HasPrefix(node.Status.NodeInfo.KubeletVersion, "v"+kubernetesVersion+"."),
+			"Node %s runs kubelet %s, expected %s", node.Name, node.Status.NodeInfo.KubeletVersion, kubernetesVersion)
+	}
+
+	scoped := workload.Deploy(t, kubectl)
+	workload.AssertServes(t, scoped)
+	workload.AssertServesAt(t, workload.Expose(t, scoped))
+
+	// Service discovery as pods see it, which the runner cannot reach
+	if image := probe.Image(); image != "" {
+		probe.AssertInCluster(t, scoped, image, "http-get", map[string]string{
+			"url":      fmt.Sprintf("http://%s.%s.svc.cluster.local", workload.Name, scoped.Namespace),
+			"contains": "Welcome to nginx",
+		})
+	} else {
+		t.Logf("%s is not set, skipping the in-cluster probe", probe.ImageEnvVar)
+	}
+}
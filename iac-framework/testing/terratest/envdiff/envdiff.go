@@ -0,0 +1,243 @@
+// Package envdiff compares what the same module plans to in each
+// environment. Every environment's plan is flattened to attribute paths and
+// any attribute whose planned value is not the same everywhere is reported,
+// so differences beyond those the tfvars are meant to introduce stand out.
+package envdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/tfshow"
+)
+
+// Absent stands in for an attribute or resource an environment does not plan
+const Absent = "<absent>"
+
+// DefaultIgnore are attributes expected to differ between environments.
+// Patterns use path.Match syntax against the attribute path.
+var DefaultIgnore = []string{
+	"tags.Environment",
+	"tags_all.Environment",
+	"tags.Name",
+	"tags_all.Name",
+}
+
+// Environment is one environment's variables for the stack
+type Environment struct {
+	Name    string
+	VarFile string
+}
+
+// Difference is an attribute that is not planned the same in every
+// environment. Values holds the JSON encoded value per environment, or
+// Absent. An empty Attribute means the resource itself is not planned
+// everywhere.
+type Difference struct {
+	Address   string            `json:"address"`
+	Attribute string            `json:"attribute,omitempty"`
+	Values    map[string]string `json:"values"`
+}
+
+// Plan plans a copy of the module with the environment's var file and
+// returns the planned resources. It runs outside of a test.
+func Plan(moduleDir string, env Environment) ([]tfshow.Resource, error) {
+	varFile, err := filepath.Abs(env.VarFile)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := files.CopyTerraformFolderToTemp(moduleDir, "envdiff-"+env.Name)
+	if err != nil {
+		return nil, fmt.Errorf("copying %s: %w", moduleDir, err)
+	}
+	defer os.RemoveAll(dir)
+
+	options := &terraform.Options{
+		TerraformDir: dir,
+		VarFiles:     []string{varFile},
+		PlanFilePath: filepath.Join(dir, "envdiff.tfplan"),
+		NoColor:      true,
+	}
+	out, err := terraform.InitAndPlanAndShowE(harness.HeadlessT("envdiff "+env.Name), options)
+	if err != nil {
+		return nil, fmt.Errorf("planning %s: %w", env.Name, err)
+	}
+	return tfshow.Parse([]byte(out))
+}
+
+// Diff compares the planned resources of each environment, skipping
+// attributes matching any of the ignore patterns. Differences are sorted by
+// address and attribute.
+func Diff(plans map[string][]tfshow.Resource, ignore []string) []Difference {
+	envs := make([]string, 0, len(plans))
+	for env := range plans {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	// address -> attribute -> env -> value
+	planned := map[string]map[string]map[string]string{}
+	for _, env := range envs {
+		for _, resource := range plans[env] {
+			attributes, ok := planned[resource.Address]
+			if !ok {
+				attributes = map[string]map[string]string{}
+				planned[resource.Address] = attributes
+			}
+			// The resource itself is recorded under the empty attribute
+			flat := map[string]string{"": ""}
+			flatten("", resource.Values, flat)
+			for attribute, value := range flat {
+				if attributes[attribute] == nil {
+					attributes[attribute] = map[string]string{}
+				}
+				attributes[attribute][env] = value
+			}
+		}
+	}
+
+	var diffs []Difference
+	for address, attributes := range planned {
+		_, resourceEverywhere := allEqual(envs, attributes[""])
+		if !resourceEverywhere {
+			values := map[string]string{}
+			for _, env := range envs {
+				values[env] = Absent
+				if _, ok := attributes[""][env]; ok {
+					values[env] = "planned"
+				}
+			}
+			diffs = append(diffs, Difference{Address: address, Values: values})
+			continue
+		}
+		for attribute, byEnv := range attributes {
+			if attribute == "" || ignored(attribute, ignore) {
+				continue
+			}
+			if equal, _ := allEqual(envs, byEnv); equal {
+				continue
+			}
+			values := map[string]string{}
+			for _, env := range envs {
+				values[env] = Absent
+				if value, ok := byEnv[env]; ok {
+					values[env] = value
+				}
+			}
+			diffs = append(diffs, Difference{Address: address, Attribute: attribute, Values: values})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Address != diffs[j].Address {
+			return diffs[i].Address < diffs[j].Address
+		}
+		return diffs[i].Attribute < diffs[j].Attribute
+	})
+	return diffs
+}
+
+// allEqual reports whether every environment has the same value, and
+// whether every environment has a value at all
+func allEqual(envs []string, byEnv map[string]string) (equal bool, everywhere bool) {
+	equal, everywhere = true, true
+	first, seen := "", false
+	for _, env := range envs {
+		value, ok := byEnv[env]
+		if !ok {
+			everywhere, equal = false, false
+			continue
+		}
+		if seen && value != first {
+			equal = false
+		}
+		first, seen = value, true
+	}
+	return equal, everywhere
+}
+
+// flatten records each leaf value of an attribute tree under a path like
+// "tags.Name" or "ingress[0].from_port". Empty maps and lists are leaves so
+// that an empty value still differs from a populated one.
+func flatten(prefix string, value interface{}, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			out[prefix] = "{}"
+			return
+		}
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(key, child, out)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			out[prefix] = "[]"
+			return
+		}
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	default:
+		encoded, _ := json.Marshal(v)
+		out[prefix] = string(encoded)
+	}
+}
+
+func ignored(attribute string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, attribute); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the differences as a table with a column per environment
+func WriteText(w io.Writer, envs []string, diffs []Difference) error {
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintf(w, "No differences across %s\n", strings.Join(envs, ", "))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "RESOURCE\tATTRIBUTE\t%s\n", strings.ToUpper(strings.Join(envs, "\t")))
+	for _, diff := range diffs {
+		attribute := diff.Attribute
+		if attribute == "" {
+			attribute = "-"
+		}
+		values := make([]string, len(envs))
+		for i, env := range envs {
+			values[i] = diff.Values[env]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", diff.Address, attribute, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the differences as a JSON document
+func WriteJSON(w io.Writer, envs []string, diffs []Difference) error {
+	if diffs == nil {
+		diffs = []Difference{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Environments []string     `json:"environments"`
+		Differences  []Difference `json:"differences"`
+	}{envs, diffs})
+}
@@ -0,0 +1,87 @@
+package envdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/tfshow"
+)
+
+func vpc(cidr string, tags map[string]interface{}) tfshow.Resource {
+	return tfshow.Resource{
+		Address: "aws_vpc.this",
+		Type:    "aws_vpc",
+		Values: map[string]interface{}{
+			"cidr_block":           cidr,
+			"enable_dns_hostnames": true,
+			"tags":                 tags,
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	nat := tfshow.Resource{Address: "aws_nat_gateway.this[1]", Type: "aws_nat_gateway", Values: map[string]interface{}{}}
+	plans := map[string][]tfshow.Resource{
+		"dev": {
+			vpc("10.40.0.0/16", map[string]interface{}{"Environment": "dev", "Owner": "platform"}),
+		},
+		"prod": {
+			vpc("10.42.0.0/16", map[string]interface{}{"Environment": "prod"}),
+			nat,
+		},
+	}
+
+	diffs := Diff(plans, DefaultIgnore)
+
+	assert.Equal(t, []Difference{
+		{Address: "aws_nat_gateway.this[1]", Values: map[string]string{"dev": Absent, "prod": "planned"}},
+		{Address: "aws_vpc.this", Attribute: "cidr_block", Values: map[string]string{"dev": `"10.40.0.0/16"`, "prod": `"10.42.0.0/16"`}},
+		{Address: "aws_vpc.this", Attribute: "tags.Owner", Values: map[string]string{"dev": `"platform"`, "prod": Absent}},
+	}, diffs)
+
+	assert.Len(t, Diff(plans, append([]string{"cidr_block", "tags.*"}, DefaultIgnore...)), 1, "Only the missing NAT gateway should remain")
+}
+
+func TestDiffIdenticalPlans(t *testing.T) {
+	plans := map[string][]tfshow.Resource{
+		"dev":     {vpc("10.0.0.0/16", map[string]interface{}{})},
+		"staging": {vpc("10.0.0.0/16", map[string]interface{}{})},
+	}
+	assert.Empty(t, Diff(plans, nil))
+}
+
+func TestFlatten(t *testing.T) {
+	out := map[string]string{}
+	flatten("", map[string]interface{}{
+		"ingress": []interface{}{
+			map[string]interface{}{"from_port": float64(22), "cidr_blocks": []interface{}{"0.0.0.0/0"}},
+		},
+		"tags":   map[string]interface{}{},
+		"egress": []interface{}{},
+		"name":   nil,
+	}, out)
+
+	assert.Equal(t, map[string]string{
+		"ingress[0].from_port":      "22",
+		"ingress[0].cidr_blocks[0]": `"0.0.0.0/0"`,
+		"tags":                      "{}",
+		"egress":                    "[]",
+		"name":                      "null",
+	}, out)
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteText(&buf, []string{"dev", "prod"}, nil))
+	assert.Equal(t, "No differences across dev, prod\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteText(&buf, []string{"dev", "prod"}, []Difference{
+		{Address: "aws_nat_gateway.this[1]", Values: map[string]string{"dev": Absent, "prod": "planned"}},
+	}))
+	assert.Contains(t, buf.String(), "RESOURCE")
+	assert.Contains(t, buf.String(), "aws_nat_gateway.this[1]  -          <absent>  planned")
+}
@@ -0,0 +1,245 @@
+// Package estimator predicts what running a selection of tests will take
+// before anything is applied: how long each test runs, from the durations
+// earlier runs recorded in their reports, and what its infrastructure
+// costs while it is up, from the Infracost estimates recorded alongside
+// them. Tests with no history are listed as unknown rather than guessed.
+package estimator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/company/iac-framework/testing/report"
+)
+
+// History is the records earlier runs kept, by test name, oldest first
+type History map[string][]report.TestRecord
+
+// LoadHistory reads every JSON report under the given directories, such
+// as TEST_REPORT_DIR and the scheduler's report directory. Directories
+// that do not exist are skipped.
+func LoadHistory(dirs ...string) (History, error) {
+	history := History{}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if entry.IsDir() || entry.Name() != report.JSONFileName {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			var parsed report.Report
+			if err := json.Unmarshal(data, &parsed); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			history.add(parsed.Tests)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, records := range history {
+		sort.Slice(records, func(i, j int) bool { return records[i].StartedAt.Before(records[j].StartedAt) })
+	}
+	return history, nil
+}
+
+// add keeps the records of tests that ran. Skipped tests say nothing about
+// how long a real run takes.
+func (h History) add(records []report.TestRecord) {
+	for _, record := range records {
+		if record.Skipped || record.Duration <= 0 {
+			continue
+		}
+		h[record.Name] = append(h[record.Name], record)
+	}
+}
+
+// Estimate is what one test is expected to take
+type Estimate struct {
+	Test string
+
+	// Runs is how many earlier runs the estimate is based on; with none
+	// the test's duration and cost are unknown
+	Runs int
+
+	// Duration is the median of the earlier runs
+	Duration time.Duration
+
+	// Cost is the latest monthly cost estimate prorated to Duration
+	Cost     float64
+	Currency string
+}
+
+// Estimate predicts a test from its history
+func (h History) Estimate(test string) Estimate {
+	records := h[test]
+	estimate := Estimate{Test: test, Runs: len(records)}
+	if len(records) == 0 {
+		return estimate
+	}
+
+	durations := make([]time.Duration, len(records))
+	for i, record := range records {
+		durations[i] = record.Duration
+	}
+	estimate.Duration = median(durations)
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].MonthlyCost > 0 {
+			estimate.Cost = records[i].MonthlyCost * estimate.Duration.Hours() / report.HoursPerMonth
+			estimate.Currency = records[i].CostCurrency
+			break
+		}
+	}
+	return estimate
+}
+
+func median(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// Suite is a named selection of tests and their estimates
+type Suite struct {
+	Name  string
+	Tests []Estimate
+}
+
+// EstimateSuite estimates each of the tests
+func EstimateSuite(name string, tests []string, history History) Suite {
+	suite := Suite{Name: name}
+	for _, test := range tests {
+		suite.Tests = append(suite.Tests, history.Estimate(test))
+	}
+	return suite
+}
+
+// Totals adds up estimates. Wall is the expected elapsed time with tests
+// running in parallel: no shorter than the longest test, nor than the
+// total spread over the parallel slots.
+type Totals struct {
+	Duration time.Duration
+	Wall     time.Duration
+	Cost     float64
+	Currency string
+	Unknown  int
+}
+
+// Total adds up the estimates of every suite, run with parallel tests at
+// a time
+func Total(suites []Suite, parallel int) Totals {
+	if parallel < 1 {
+		parallel = 1
+	}
+	var totals Totals
+	var longest time.Duration
+	for _, suite := range suites {
+		for _, estimate := range suite.Tests {
+			if estimate.Runs == 0 {
+				totals.Unknown++
+				continue
+			}
+			totals.Duration += estimate.Duration
+			totals.Cost += estimate.Cost
+			if totals.Currency == "" {
+				totals.Currency = estimate.Currency
+			}
+			if estimate.Duration > longest {
+				longest = estimate.Duration
+			}
+		}
+	}
+	totals.Wall = totals.Duration / time.Duration(parallel)
+	if longest > totals.Wall {
+		totals.Wall = longest
+	}
+	return totals
+}
+
+// ListTests returns the top-level tests in suiteDir matching the -run
+// pattern, without running them
+func ListTests(suiteDir string, pattern string) ([]string, error) {
+	cmd := exec.Command("go", "test", "-list", pattern, ".")
+	cmd.Dir = suiteDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("listing tests matching %q: %v\n%s", pattern, err, output)
+	}
+	return parseList(string(output)), nil
+}
+
+// parseList picks the test names out of go test -list output, which ends
+// with the package's ok line
+func parseList(output string) []string {
+	var tests []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Test") && !strings.ContainsAny(line, " \t") {
+			tests = append(tests, line)
+		}
+	}
+	return tests
+}
+
+// Write prints each suite's tests and totals, then the run total
+func Write(w io.Writer, suites []Suite, parallel int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUITE\tTEST\tRUNS\tDURATION\tCOST")
+	for _, suite := range suites {
+		for _, estimate := range suite.Tests {
+			if estimate.Runs == 0 {
+				fmt.Fprintf(tw, "%s\t%s\t0\tunknown\tunknown\n", suite.Name, estimate.Test)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", suite.Name, estimate.Test, estimate.Runs,
+				estimate.Duration.Round(time.Second), formatCost(estimate.Cost, estimate.Currency))
+		}
+		totals := Total([]Suite{suite}, parallel)
+		fmt.Fprintf(tw, "%s\ttotal\t\t%s\t%s\n", suite.Name, totals.Wall.Round(time.Second), formatCost(totals.Cost, totals.Currency))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	totals := Total(suites, parallel)
+	_, err := fmt.Fprintf(w, "\nRun total: about %s elapsed (%s of test time, %d at a time), %s",
+		totals.Wall.Round(time.Second), totals.Duration.Round(time.Second), parallel, formatCost(totals.Cost, totals.Currency))
+	if err == nil && totals.Unknown > 0 {
+		_, err = fmt.Fprintf(w, ", plus %d tests with no history", totals.Unknown)
+	}
+	if err == nil {
+		_, err = fmt.Fprintln(w)
+	}
+	return err
+}
+
+func formatCost(cost float64, currency string) string {
+	if currency == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f %s", cost, currency)
+}
@@ -0,0 +1,109 @@
+package estimator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/report"
+)
+
+func writeReport(t *testing.T, dir string, records ...report.TestRecord) {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	data, err := json.Marshal(report.Report{Tests: records})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, report.JSONFileName), data, 0o644))
+}
+
+func TestLoadHistory(t *testing.T) {
+	root := t.TempDir()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	writeReport(t, filepath.Join(root, "vpc", "20240502T000000Z"),
+		report.TestRecord{Name: "TestVPCModule", StartedAt: day.Add(24 * time.Hour), Duration: 12 * time.Minute},
+		report.TestRecord{Name: "TestEC2Module", Skipped: true, Duration: time.Second})
+	writeReport(t, filepath.Join(root, "vpc", "20240501T000000Z"),
+		report.TestRecord{Name: "TestVPCModule", StartedAt: day, Duration: 10 * time.Minute})
+
+	history, err := LoadHistory(root, filepath.Join(root, "missing"))
+	require.NoError(t, err)
+	require.Len(t, history["TestVPCModule"], 2)
+	assert.Equal(t, 10*time.Minute, history["TestVPCModule"][0].Duration, "History should be oldest first")
+	assert.NotContains(t, history, "TestEC2Module", "Skipped runs should not count")
+
+	writeReport(t, filepath.Join(root, "broken"))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "broken", report.JSONFileName), []byte("{"), 0o644))
+	_, err = LoadHistory(root)
+	assert.Error(t, err)
+}
+
+func TestEstimate(t *testing.T) {
+	history := History{}
+	history.add([]report.TestRecord{
+		{Name: "TestVPCModule", Duration: 10 * time.Minute, MonthlyCost: 73, CostCurrency: "USD"},
+		{Name: "TestVPCModule", Duration: 20 * time.Minute},
+		{Name: "TestVPCModule", Duration: 30 * time.Minute},
+		{Name: "TestEC2Module", Duration: 4 * time.Minute},
+		{Name: "TestEC2Module", Duration: 6 * time.Minute},
+	})
+
+	vpc := history.Estimate("TestVPCModule")
+	assert.Equal(t, 3, vpc.Runs)
+	assert.Equal(t, 20*time.Minute, vpc.Duration)
+	assert.InDelta(t, 73.0/730/3, vpc.Cost, 1e-9, "The monthly cost should be prorated to the expected duration")
+	assert.Equal(t, "USD", vpc.Currency)
+
+	ec2 := history.Estimate("TestEC2Module")
+	assert.Equal(t, 5*time.Minute, ec2.Duration)
+	assert.Zero(t, ec2.Cost)
+
+	assert.Equal(t, Estimate{Test: "TestNew"}, history.Estimate("TestNew"))
+}
+
+func TestTotal(t *testing.T) {
+	suites := []Suite{
+		{Name: "vpc", Tests: []Estimate{
+			{Test: "TestVPCModule", Runs: 3, Duration: 20 * time.Minute, Cost: 0.5, Currency: "USD"},
+			{Test: "TestVPCNew"},
+		}},
+		{Name: "ec2", Tests: []Estimate{
+			{Test: "TestEC2Module", Runs: 2, Duration: 5 * time.Minute, Cost: 0.25, Currency: "USD"},
+			{Test: "TestEC2WithEIP", Runs: 1, Duration: 5 * time.Minute},
+		}},
+	}
+
+	totals := Total(suites, 4)
+	assert.Equal(t, 30*time.Minute, totals.Duration)
+	assert.Equal(t, 20*time.Minute, totals.Wall, "The longest test bounds the elapsed time")
+	assert.InDelta(t, 0.75, totals.Cost, 1e-9)
+	assert.Equal(t, "USD", totals.Currency)
+	assert.Equal(t, 1, totals.Unknown)
+
+	assert.Equal(t, 30*time.Minute, Total(suites, 0).Wall)
+	assert.Equal(t, 30*time.Minute, Total(suites, 1).Wall)
+}
+
+func TestParseList(t *testing.T) {
+	output := "TestVPCModule\nTestVPCFlowLogs\nExampleFoo\nok  \tgithub.com/company/iac-framework/testing\t0.012s\n"
+	assert.Equal(t, []string{"TestVPCModule", "TestVPCFlowLogs"}, parseList(output))
+	assert.Empty(t, parseList("no test files\n"))
+}
+
+func TestWrite(t *testing.T) {
+	suites := []Suite{{Name: "vpc", Tests: []Estimate{
+		{Test: "TestVPCModule", Runs: 3, Duration: 20 * time.Minute, Cost: 0.5, Currency: "USD"},
+		{Test: "TestVPCNew"},
+	}}}
+
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, suites, 4))
+	assert.Contains(t, out.String(), "TestVPCModule")
+	assert.Contains(t, out.String(), "0.50 USD")
+	assert.Contains(t, out.String(), "unknown")
+	assert.Contains(t, out.String(), "Run total: about 20m0s elapsed (20m0s of test time, 4 at a time), 0.50 USD, plus 1 tests with no history")
+}
@@ -0,0 +1,82 @@
+// Package expectfail runs terraform against inputs that must be rejected
+// and checks they were rejected for the right reason. A negative test that
+// only asserts "it failed" also passes when the module fails for an
+// unrelated reason, such as a typo in the test's own variables.
+package expectfail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/company/iac-framework/testing/harness"
+)
+
+// ValidationErrorPattern matches terraform rejecting a variable, either for
+// failing a validation block or for not converting to the declared type
+const ValidationErrorPattern = `Invalid value for (input )?variable`
+
+// Plan runs init and plan and asserts they fail with an error matching
+// pattern. Variable validations fail here, so nothing is ever created.
+func Plan(t *testing.T, options *terraform.Options, pattern string) {
+	_, err := terraform.InitAndPlanE(t, options)
+	if err := check(err, pattern); err != nil {
+		assert.Fail(t, "Plan did not fail as expected", err.Error())
+	}
+}
+
+// Apply runs init and apply and asserts they fail with an error matching
+// pattern, for failures only the provider or AWS can detect. Whatever the
+// apply managed to create is destroyed again.
+func Apply(t *testing.T, options *terraform.Options, pattern string) {
+	harness.Track(t, options)
+	defer harness.Destroy(t, options)
+
+	_, err := harness.InitAndApplyE(t, options)
+	if err := check(err, pattern); err != nil {
+		assert.Fail(t, "Apply did not fail as expected", err.Error())
+	}
+}
+
+// PlanValidates runs init and plan and reports whether terraform accepted
+// the input. Input it turns away must be rejected by a variable validation;
+// any other failure means the bad value got past the module's interface.
+func PlanValidates(t *testing.T, options *terraform.Options) bool {
+	_, err := terraform.InitAndPlanE(t, options)
+	if err == nil {
+		return true
+	}
+	if err := check(err, ValidationErrorPattern); err != nil {
+		assert.Fail(t, "Input was not rejected by a variable validation", err.Error())
+	}
+	return false
+}
+
+// check returns why err is not the expected failure, or nil if it is
+func check(err error, pattern string) error {
+	if err == nil {
+		return fmt.Errorf("expected an error matching %q but terraform succeeded", pattern)
+	}
+	re, compileErr := regexp.Compile(pattern)
+	if compileErr != nil {
+		return fmt.Errorf("invalid pattern %q: %v", pattern, compileErr)
+	}
+	if message := normalize(err.Error()); !re.MatchString(message) {
+		return fmt.Errorf("expected an error matching %q, got: %s", pattern, message)
+	}
+	return nil
+}
+
+// normalize undoes terraform's diagnostic formatting, which draws a box
+// around each error and wraps long messages, so patterns can be written
+// as the error_message appears in the module
+func normalize(message string) string {
+	message = strings.ReplaceAll(message, "│", " ")
+	message = strings.ReplaceAll(message, "╷", " ")
+	message = strings.ReplaceAll(message, "╵", " ")
+	return strings.Join(strings.Fields(message), " ")
+}
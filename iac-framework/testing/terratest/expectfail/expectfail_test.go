@@ -0,0 +1,73 @@
+package expectfail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wrappedDiagnostic is how terraform prints a failed variable validation
+const wrappedDiagnostic = `error while running command: exit status 1;
+╷
+│ Error: Invalid value for variable
+│
+│   on variables.tf line 1:
+│    1: variable "project_name" {
+│
+│ Project name must be between 1 and 32
+│ characters.
+╵`
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		name    string
+		err     error
+		pattern string
+		ok      bool
+	}{
+		{
+			name:    "matching error",
+			err:     errors.New(wrappedDiagnostic),
+			pattern: `Project name must be between 1 and 32 characters`,
+			ok:      true,
+		},
+		{
+			name:    "different error",
+			err:     errors.New(wrappedDiagnostic),
+			pattern: `Environment must be one of`,
+		},
+		{
+			name:    "no error",
+			pattern: `anything`,
+		},
+		{
+			name:    "invalid pattern",
+			err:     errors.New(wrappedDiagnostic),
+			pattern: `(`,
+		},
+
+		{
+			name:    "validation pattern",
+			err:     errors.New(wrappedDiagnostic),
+			pattern: ValidationErrorPattern,
+			ok:      true,
+		},
+		{
+			name:    "provider error is not a validation error",
+			err:     errors.New("error while running command: exit status 1; Error: creating EC2 Subnet: InvalidSubnet.Range"),
+			pattern: ValidationErrorPattern,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := check(tc.err, tc.pattern)
+			if tc.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package exposure scans what a test applied for anything reachable from
+// the internet: security group rules open to the world, public S3 buckets,
+// publicly accessible RDS instances and internet-facing load balancers.
+// Resources meant to be public are allowlisted in the test with a reason.
+package exposure
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/tfshow"
+)
+
+// Finding is one way a resource can be reached from the internet. Key is
+// what an Allow entry matches: the resource address, followed for security
+// groups by the rule, e.g. "aws_security_group.this[0]:tcp/80".
+type Finding struct {
+	Address string
+	ID      string
+	Key     string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s (%s) %s", f.Address, f.ID, f.Message)
+}
+
+// Allow maps a finding key, or a whole resource address, to the reason it
+// is meant to be public
+type Allow map[string]string
+
+func (a Allow) reason(f Finding) (string, bool) {
+	if reason, ok := a[f.Key]; ok {
+		return reason, true
+	}
+	reason, ok := a[f.Address]
+	return reason, ok
+}
+
+// Scan looks up the resources in region and returns every exposure found
+func Scan(t *testing.T, region string, resources []tfshow.Resource) []Finding {
+	sess, err := backend.NewSessionE(region)
+	require.NoError(t, err)
+
+	var findings []Finding
+	if groups := tfshow.OfType(resources, "aws_security_group"); len(groups) > 0 {
+		found, err := scanSecurityGroups(ec2.New(sess), groups)
+		require.NoError(t, err)
+		findings = append(findings, found...)
+	}
+	if buckets := tfshow.OfType(resources, "aws_s3_bucket"); len(buckets) > 0 {
+		found, err := scanBuckets(s3.New(sess), buckets)
+		require.NoError(t, err)
+		findings = append(findings, found...)
+	}
+	if instances := tfshow.OfType(resources, "aws_db_instance"); len(instances) > 0 {
+		found, err := scanDBInstances(rds.New(sess), instances)
+		require.NoError(t, err)
+		findings = append(findings, found...)
+	}
+	balancers := append(tfshow.OfType(resources, "aws_lb"), tfshow.OfType(resources, "aws_alb")...)
+	if len(balancers) > 0 {
+		found, err := scanLoadBalancers(elbv2.New(sess), balancers)
+		require.NoError(t, err)
+		findings = append(findings, found...)
+	}
+	return findings
+}
+
+// Assert scans the applied state of options and fails the test for every
+// exposure not in allow. Allowed exposures are logged with their reason.
+func Assert(t *testing.T, options *terraform.Options, region string, allow Allow) {
+	for _, finding := range Scan(t, region, tfshow.State(t, options)) {
+		if reason, ok := allow.reason(finding); ok {
+			t.Logf("Allowed public exposure %s: %s", finding, reason)
+			continue
+		}
+		assert.Fail(t, "Resource is reachable from the internet", "%s; allowlist %q if intended", finding, finding.Key)
+	}
+}
+
+func scanSecurityGroups(client *ec2.EC2, resources []tfshow.Resource) ([]Finding, error) {
+	addresses := map[string]string{}
+	var ids []string
+	for _, resource := range resources {
+		addresses[resource.String("id")] = resource.Address
+		ids = append(ids, resource.String("id"))
+	}
+
+	out, err := client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: awssdk.StringSlice(ids)})
+	if err != nil {
+		return nil, fmt.Errorf("describing security groups: %w", err)
+	}
+	var findings []Finding
+	for _, group := range out.SecurityGroups {
+		findings = append(findings, securityGroupFindings(addresses[awssdk.StringValue(group.GroupId)], group)...)
+	}
+	return findings, nil
+}
+
+// securityGroupFindings returns one finding per ingress rule open to
+// 0.0.0.0/0 or ::/0
+func securityGroupFindings(address string, group *ec2.SecurityGroup) []Finding {
+	var findings []Finding
+	for _, permission := range group.IpPermissions {
+		var sources []string
+		for _, ipRange := range permission.IpRanges {
+			if awssdk.StringValue(ipRange.CidrIp) == "0.0.0.0/0" {
+				sources = append(sources, "0.0.0.0/0")
+			}
+		}
+		for _, ipRange := range permission.Ipv6Ranges {
+			if awssdk.StringValue(ipRange.CidrIpv6) == "::/0" {
+				sources = append(sources, "::/0")
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+
+		rule := ruleName(permission)
+		findings = append(findings, Finding{
+			Address: address,
+			ID:      awssdk.StringValue(group.GroupId),
+			Key:     address + ":" + rule,
+			Message: fmt.Sprintf("allows %s from %v", rule, sources),
+		})
+	}
+	return findings
+}
+
+// ruleName formats a permission as protocol/ports, e.g. "tcp/22" or "all"
+func ruleName(permission *ec2.IpPermission) string {
+	protocol := awssdk.StringValue(permission.IpProtocol)
+	if protocol == "-1" {
+		return "all"
+	}
+	from, to := awssdk.Int64Value(permission.FromPort), awssdk.Int64Value(permission.ToPort)
+	if from == to {
+		return fmt.Sprintf("%s/%d", protocol, from)
+	}
+	return fmt.Sprintf("%s/%d-%d", protocol, from, to)
+}
+
+func scanBuckets(client *s3.S3, resources []tfshow.Resource) ([]Finding, error) {
+	var findings []Finding
+	for _, resource := range resources {
+		bucket := resource.String("id")
+
+		status, err := client.GetBucketPolicyStatus(&s3.GetBucketPolicyStatusInput{Bucket: awssdk.String(bucket)})
+		var aerr awserr.Error
+		switch {
+		case errors.As(err, &aerr) && aerr.Code() == "NoSuchBucketPolicy":
+			status = nil
+		case err != nil:
+			return nil, fmt.Errorf("getting policy status of %s: %w", bucket, err)
+		}
+
+		acl, err := client.GetBucketAcl(&s3.GetBucketAclInput{Bucket: awssdk.String(bucket)})
+		if err != nil {
+			return nil, fmt.Errorf("getting ACL of %s: %w", bucket, err)
+		}
+		findings = append(findings, bucketFindings(resource.Address, bucket, status, acl.Grants)...)
+	}
+	return findings, nil
+}
+
+// publicGroups are the ACL grantees that mean anyone on the internet
+var publicGroups = map[string]bool{
+	"http://acs.amazonaws.com/groups/global/AllUsers":           true,
+	"http://acs.amazonaws.com/groups/global/AuthenticatedUsers": true,
+}
+
+func bucketFindings(address string, bucket string, status *s3.GetBucketPolicyStatusOutput, grants []*s3.Grant) []Finding {
+	var findings []Finding
+	if status != nil && status.PolicyStatus != nil && awssdk.BoolValue(status.PolicyStatus.IsPublic) {
+		findings = append(findings, Finding{Address: address, ID: bucket, Key: address, Message: "has a public bucket policy"})
+	}
+	for _, grant := range grants {
+		if grant.Grantee != nil && publicGroups[awssdk.StringValue(grant.Grantee.URI)] {
+			findings = append(findings, Finding{
+				Address: address,
+				ID:      bucket,
+				Key:     address,
+				Message: fmt.Sprintf("grants %s to %s", awssdk.StringValue(grant.Permission), awssdk.StringValue(grant.Grantee.URI)),
+			})
+		}
+	}
+	return findings
+}
+
+func scanDBInstances(client *rds.RDS, resources []tfshow.Resource) ([]Finding, error) {
+	var findings []Finding
+	for _, resource := range resources {
+		// The state id of a DB instance is its resource id, not its identifier
+		identifier := resource.String("identifier")
+		out, err := client.DescribeDBInstances(&rds.DescribeDBInstancesInput{DBInstanceIdentifier: awssdk.String(identifier)})
+		if err != nil {
+			return nil, fmt.Errorf("describing DB instance %s: %w", identifier, err)
+		}
+		for _, instance := range out.DBInstances {
+			if awssdk.BoolValue(instance.PubliclyAccessible) {
+				findings = append(findings, Finding{Address: resource.Address, ID: identifier, Key: resource.Address, Message: "is publicly accessible"})
+			}
+		}
+	}
+	return findings, nil
+}
+
+func scanLoadBalancers(client *elbv2.ELBV2, resources []tfshow.Resource) ([]Finding, error) {
+	addresses := map[string]string{}
+	var arns []string
+	for _, resource := range resources {
+		addresses[resource.String("arn")] = resource.Address
+		arns = append(arns, resource.String("arn"))
+	}
+
+	out, err := client.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{LoadBalancerArns: awssdk.StringSlice(arns)})
+	if err != nil {
+		return nil, fmt.Errorf("describing load balancers: %w", err)
+	}
+	var findings []Finding
+	for _, balancer := range out.LoadBalancers {
+		if awssdk.StringValue(balancer.Scheme) == elbv2.LoadBalancerSchemeEnumInternetFacing {
+			address := addresses[awssdk.StringValue(balancer.LoadBalancerArn)]
+			findings = append(findings, Finding{
+				Address: address,
+				ID:      awssdk.StringValue(balancer.LoadBalancerName),
+				Key:     address,
+				Message: "is internet-facing",
+			})
+		}
+	}
+	return findings, nil
+}
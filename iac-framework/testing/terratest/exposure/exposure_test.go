@@ -0,0 +1,88 @@
+package exposure
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityGroupFindings(t *testing.T) {
+	group := &ec2.SecurityGroup{
+		GroupId: awssdk.String("sg-1"),
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol: awssdk.String("tcp"),
+				FromPort:   awssdk.Int64(22),
+				ToPort:     awssdk.Int64(22),
+				IpRanges:   []*ec2.IpRange{{CidrIp: awssdk.String("10.0.0.0/16")}},
+			},
+			{
+				IpProtocol: awssdk.String("tcp"),
+				FromPort:   awssdk.Int64(80),
+				ToPort:     awssdk.Int64(80),
+				IpRanges:   []*ec2.IpRange{{CidrIp: awssdk.String("0.0.0.0/0")}},
+				Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: awssdk.String("::/0")}},
+			},
+			{
+				IpProtocol: awssdk.String("-1"),
+				Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: awssdk.String("::/0")}},
+			},
+		},
+	}
+
+	assert.Equal(t, []Finding{
+		{
+			Address: "aws_security_group.this[0]",
+			ID:      "sg-1",
+			Key:     "aws_security_group.this[0]:tcp/80",
+			Message: "allows tcp/80 from [0.0.0.0/0 ::/0]",
+		},
+		{
+			Address: "aws_security_group.this[0]",
+			ID:      "sg-1",
+			Key:     "aws_security_group.this[0]:all",
+			Message: "allows all from [::/0]",
+		},
+	}, securityGroupFindings("aws_security_group.this[0]", group))
+}
+
+func TestRuleName(t *testing.T) {
+	assert.Equal(t, "udp/1000-2000", ruleName(&ec2.IpPermission{
+		IpProtocol: awssdk.String("udp"),
+		FromPort:   awssdk.Int64(1000),
+		ToPort:     awssdk.Int64(2000),
+	}))
+}
+
+func TestBucketFindings(t *testing.T) {
+	public := &s3.GetBucketPolicyStatusOutput{PolicyStatus: &s3.PolicyStatus{IsPublic: awssdk.Bool(true)}}
+	grants := []*s3.Grant{
+		{Grantee: &s3.Grantee{ID: awssdk.String("owner")}, Permission: awssdk.String("FULL_CONTROL")},
+		{Grantee: &s3.Grantee{URI: awssdk.String("http://acs.amazonaws.com/groups/global/AllUsers")}, Permission: awssdk.String("READ")},
+	}
+
+	findings := bucketFindings("aws_s3_bucket.logs", "logs-bucket", public, grants)
+	assert.Equal(t, []string{
+		"aws_s3_bucket.logs (logs-bucket) has a public bucket policy",
+		"aws_s3_bucket.logs (logs-bucket) grants READ to http://acs.amazonaws.com/groups/global/AllUsers",
+	}, []string{findings[0].String(), findings[1].String()})
+
+	assert.Empty(t, bucketFindings("aws_s3_bucket.logs", "logs-bucket", nil, grants[:1]))
+}
+
+func TestAllow(t *testing.T) {
+	finding := Finding{Address: "aws_security_group.this[0]", Key: "aws_security_group.this[0]:tcp/80"}
+
+	_, ok := Allow{"aws_security_group.this[0]:tcp/443": "TLS"}.reason(finding)
+	assert.False(t, ok, "Allowing one rule should not allow another")
+
+	reason, ok := Allow{"aws_security_group.this[0]:tcp/80": "web server"}.reason(finding)
+	assert.True(t, ok)
+	assert.Equal(t, "web server", reason)
+
+	_, ok = Allow{"aws_security_group.this[0]": "bastion"}.reason(finding)
+	assert.True(t, ok, "Allowing the address should allow every rule")
+}
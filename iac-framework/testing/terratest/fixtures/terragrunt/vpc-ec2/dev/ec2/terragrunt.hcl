@@ -0,0 +1,29 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+terraform {
+  source = "${get_env("TG_MODULES_ROOT")}//aws/ec2"
+}
+
+# Mocks let run-all validate work before the VPC exists. They are kept away
+# from plan and apply so a unit can never be deployed against made-up IDs.
+dependency "vpc" {
+  config_path = "../vpc"
+
+  mock_outputs = {
+    vpc_id         = "vpc-00000000000000000"
+    public_subnets = ["subnet-00000000000000000"]
+  }
+  mock_outputs_allowed_terraform_commands = ["validate"]
+}
+
+inputs = {
+  instance_type = "t3.micro"
+  vpc_id        = dependency.vpc.outputs.vpc_id
+  subnet_id     = dependency.vpc.outputs.public_subnets[0]
+
+  # The module defaults to no public IP, overriding the subnet's mapping
+  associate_public_ip_address = true
+  enable_ssh_access           = false
+}
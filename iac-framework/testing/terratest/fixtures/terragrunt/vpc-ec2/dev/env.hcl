@@ -0,0 +1,7 @@
+locals {
+  inputs = {
+    environment        = "dev"
+    vpc_cidr           = "10.40.0.0/16"
+    enable_nat_gateway = false
+  }
+}
@@ -0,0 +1,7 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+terraform {
+  source = "${get_env("TG_MODULES_ROOT")}//aws/vpc"
+}
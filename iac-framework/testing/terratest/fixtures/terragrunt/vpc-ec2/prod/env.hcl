@@ -0,0 +1,8 @@
+locals {
+  inputs = {
+    environment        = "prod"
+    vpc_cidr           = "10.41.0.0/16"
+    enable_nat_gateway = true
+    single_nat_gateway = true
+  }
+}
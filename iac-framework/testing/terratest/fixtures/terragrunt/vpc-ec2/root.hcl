@@ -0,0 +1,18 @@
+# Shared configuration for the vpc-ec2 terragrunt stack. The harness points
+# TG_MODULES_ROOT at the module tree and TG_NAME_PREFIX at a unique name so
+# parallel runs of the stack never collide.
+
+locals {
+  env = read_terragrunt_config(find_in_parent_folders("env.hcl"))
+}
+
+inputs = merge(
+  {
+    project_name = get_env("TG_NAME_PREFIX", "terratest")
+    tags = {
+      Project  = "terratest"
+      TestType = "terragrunt"
+    }
+  },
+  local.env.locals.inputs,
+)
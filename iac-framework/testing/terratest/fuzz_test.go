@@ -0,0 +1,145 @@
+package test
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/expectfail"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/modvars"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// validInputs are generated cases that are not malformed at all: empty is
+// the default, or switches a feature off
+var validInputs = map[string]bool{
+	"aws/vpc/flow_logs_destination_arn/empty": true,
+	"aws/vpc/flow_logs_iam_role_arn/empty":    true,
+	"aws/ec2/ami_id/empty":                    true,
+	"aws/ec2/block_device_mappings/empty":     true,
+	"aws/ec2/ebs_block_devices/empty":         true,
+	"aws/ec2/iam_instance_profile_name/empty": true,
+	"aws/ec2/iam_policy_arns/empty":           true,
+	"aws/ec2/ingress_rules/empty":             true,
+	"aws/ec2/key_name/empty":                  true,
+	"aws/ec2/name/empty":                      true,
+	"aws/ec2/public_key/empty":                true,
+	"aws/ec2/security_group_ids/empty":        true,
+	"aws/ec2/user_data/empty":                 true,
+	"aws/ec2/user_data_base64/empty":          true,
+	"aws/ec2/vpc_id/empty":                    true,
+}
+
+// knownGaps are the variables that have no validation yet, so every
+// malformed value of theirs is still accepted. Add a validation and drop
+// the entry; the test fails on entries that have become stale.
+var knownGaps = map[string]bool{
+	"aws/vpc/flow_logs_destination_arn": true,
+	"aws/vpc/flow_logs_iam_role_arn":    true,
+	"aws/ec2/ami_id":                    true,
+	"aws/ec2/ami_name_filter":           true,
+	"aws/ec2/ami_owners":                true,
+	"aws/ec2/environment":               true,
+	"aws/ec2/http_cidr_blocks":          true,
+	"aws/ec2/https_cidr_blocks":         true,
+	"aws/ec2/iam_instance_profile_name": true,
+	"aws/ec2/instance_type":             true,
+	"aws/ec2/key_name":                  true,
+	"aws/ec2/launch_template_version":   true,
+	"aws/ec2/name":                      true,
+	"aws/ec2/project_name":              true,
+	"aws/ec2/public_key":                true,
+	"aws/ec2/ssh_cidr_blocks":           true,
+	"aws/ec2/subnet_id":                 true,
+	"aws/ec2/user_data":                 true,
+	"aws/ec2/user_data_base64":          true,
+	"aws/ec2/vpc_id":                    true,
+}
+
+// TestModuleInputFuzzing plans each module with one malformed value at a
+// time. Terraform must reject the value with a variable validation; an
+// error from anywhere else means the value got past the module's interface,
+// and an accepted value is a missing validation unless it is listed in
+// validInputs or knownGaps. Nothing is applied.
+func TestModuleInputFuzzing(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the EC2 module needs a real subnet to plan against")
+
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC())
+
+	// The EC2 module looks its subnet up while planning, so it needs a real one
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
+
+	modules := []struct {
+		module   string
+		required map[string]interface{}
+	}{
+		{
+			module: "aws/vpc",
+			required: map[string]interface{}{
+				"project_name": "fuzz",
+				"environment":  "test",
+			},
+		},
+		{
+			module: "aws/ec2",
+			required: map[string]interface{}{
+				"project_name": "fuzz",
+				"environment":  "test",
+				"subnet_id":    defaultSubnets[0],
+			},
+		},
+	}
+
+	for _, m := range modules {
+		m := m
+		variables, err := modvars.Load(filepath.Join(harness.ModulesRoot, m.module))
+		require.NoError(t, err)
+
+		cases, err := modvars.Malformed(variables, m.required)
+		require.NoError(t, err)
+
+		for _, c := range cases {
+			c := c
+			t.Run(fmt.Sprintf("%s/%s", m.module, c.Name), func(t *testing.T) {
+				t.Parallel()
+
+				// Only prefix valid names; the malformed ones are the point of the case
+				if c.Vars["project_name"] == m.required["project_name"] {
+					c.Vars["project_name"] = fmt.Sprintf("fuzz-%s", random.UniqueId())
+				}
+
+				terraformOptions := &terraform.Options{
+					TerraformDir: harness.ModuleDir(t, m.module),
+					Vars:         c.Vars,
+					EnvVars: map[string]string{
+						"AWS_DEFAULT_REGION": awsRegion,
+					},
+				}
+
+				key := path.Join(m.module, c.Name)
+				gap := knownGaps[path.Dir(key)]
+				accepted := expectfail.PlanValidates(t, terraformOptions)
+				switch {
+				case validInputs[key] && !accepted:
+					t.Errorf("%s rejects %s, which is a valid value", m.module, c.Name)
+				case validInputs[key]:
+				case accepted && !gap:
+					t.Errorf("%s accepts %s without a validation error", m.module, c.Name)
+				case !accepted && gap:
+					t.Errorf("%s now rejects %s; drop %s from knownGaps", m.module, c.Name, path.Dir(key))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/tfshow"
+	"github.com/company/iac-framework/testing/workload"
+)
+
+// TestGKECluster applies the GKE stack, checks its network, private
+// cluster, workload identity and node pool settings, then deploys a sample
+// workload through the kubeconfig the stack outputs
+func TestGKECluster(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "GKE runs on Google Cloud")
+	projectId := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectId == "" {
+		t.Skip("GOOGLE_CLOUD_PROJECT is not set")
+	}
+	if _, err := exec.LookPath("gke-gcloud-auth-plugin"); err != nil {
+		t.Skip("The generated kubeconfig needs gke-gcloud-auth-plugin")
+	}
+
+	uniqueId := strings.ToLower(random.UniqueId())
+	machineType := "e2-standard-2"
+	podCidr := "10.48.0.0/14"
+	masterCidr := "172.16.0.0/28"
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ClusterDir(t, "gcp"),
+		Vars: map[string]interface{}{
+			"gcp_project_id": projectId,
+			"gcp_region":     "us-central1",
+			"project_name":   fmt.Sprintf("tt-%s", uniqueId),
+			"environment":    "test",
+			"subnet_cidr":    "10.10.0.0/24",
+			"pod_cidr":       podCidr,
+			"service_cidr":   "10.52.0.0/20",
+			"master_cidr":    masterCidr,
+			"machine_type":   machineType,
+			"node_count":     1,
+			"min_node_count": 1,
+			"max_node_count": 2,
+			"labels": map[string]string{
+				"environment": "test",
+				"test_type":   "gke",
+			},
+		},
+	}
+
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	resources := tfshow.State(t, terraformOptions)
+
+	// Custom mode network with the secondary ranges VPC-native pods and
+	// services come from
+	networks := tfshow.OfType(resources, "google_compute_network")
+	require.Len(t, networks, 1)
+	assert.Equal(t, false, networks[0].Values["auto_create_subnetworks"], "Network should be in custom subnet mode")
+
+	subnets := tfshow.OfType(resources, "google_compute_subnetwork")
+	require.Len(t, subnets, 1)
+	ranges := map[string]string{}
+	secondaryRanges, _ := subnets[0].Values["secondary_ip_range"].([]interface{})
+	for _, value := range secondaryRanges {
+		secondary, _ := value.(map[string]interface{})
+		ranges[fmt.Sprint(secondary["range_name"])] = fmt.Sprint(secondary["ip_cidr_range"])
+	}
+	assert.Equal(t, map[string]string{"k8s-pod-range": podCidr, "k8s-service-range": "10.52.0.0/20"}, ranges)
+
+	clusters := tfshow.OfType(resources, "google_container_cluster")
+	require.Len(t, clusters, 1)
+	cluster := clusters[0]
+
+	// The stack leaves the channel to GKE's default, which auto-upgrading
+	// node pools then follow
+	channel := cluster.Block("release_channel")
+	assert.Contains(t, []interface{}{"RAPID", "REGULAR", "STABLE"}, channel["channel"], "Cluster should be enrolled in a release channel")
+
+	private := cluster.Block("private_cluster_config")
+	assert.Equal(t, true, private["enable_private_nodes"], "Nodes should have no public IPs")
+	assert.Equal(t, false, private["enable_private_endpoint"], "The control plane should stay reachable for kubectl")
+	assert.Equal(t, masterCidr, private["master_ipv4_cidr_block"])
+
+	identity := cluster.Block("workload_identity_config")
+	assert.Equal(t, projectId+".svc.id.goog", identity["workload_pool"])
+
+	pools := tfshow.OfType(resources, "google_container_node_pool")
+	require.Len(t, pools, 1)
+	nodeConfig := pools[0].Block("node_config")
+	assert.Equal(t, machineType, nodeConfig["machine_type"])
+	metadata, _ := nodeConfig["workload_metadata_config"].([]interface{})
+	require.Len(t, metadata, 1)
+	assert.Equal(t, "GKE_METADATA", metadata[0].(map[string]interface{})["mode"], "Nodes should serve workload identity metadata")
+
+	// What the cluster actually runs
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	kubeconfig := terraform.Output(t, terraformOptions, "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0o600))
+	kubectl := k8s.NewKubectlOptions("", kubeconfigPath, "default")
+
+	k8s.WaitUntilAllNodesReady(t, kubectl, 30, 10*time.Second)
+	nodes := k8s.GetNodes(t, kubectl)
+	for instanceType, count := range workload.NodesByLabel(nodes, "node.kubernetes.io/instance-type") {
+		assert.Equal(t, machineType, instanceType, "%d node(s) run the wrong machine type", count)
+	}
+
+	// Private nodes have no route to Docker Hub, so pull through Google's
+	// mirror of it
+	scoped := workload.DeployImage(t, kubectl, "mirror.gcr.io/library/"+workload.Image)
+	workload.AssertServes(t, scoped)
+
+	// VPC-native pods get addresses from the pod secondary range
+	workload.AssertPodIPsIn(t, workload.Pods(t, scoped), podCidr)
+}
@@ -0,0 +1,179 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/company/iac-framework/testing/report"
+)
+
+const (
+	// ArtifactBucketEnvVar names the S3 bucket evidence from failed tests is
+	// uploaded to. Nothing is captured when it is unset.
+	ArtifactBucketEnvVar = "TEST_ARTIFACT_BUCKET"
+
+	// ArtifactRegionEnvVar is the bucket's region, defaulting to AWS_REGION
+	ArtifactRegionEnvVar = "TEST_ARTIFACT_REGION"
+
+	// RunIDEnvVar keys the uploads of one run; CI should set it to its run
+	// or build number
+	RunIDEnvVar = "TEST_RUN_ID"
+)
+
+var (
+	runId     string
+	runIdOnce sync.Once
+)
+
+// RunID identifies this run in artifact keys. It comes from TEST_RUN_ID,
+// or is generated from the start time when that is unset.
+func RunID() string {
+	runIdOnce.Do(func() {
+		runId = os.Getenv(RunIDEnvVar)
+		if runId == "" {
+			runId = fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), random.UniqueId())
+		}
+	})
+	return runId
+}
+
+// staging holds the local directory each capturing test collects its
+// artifacts in until it finishes
+var staging sync.Map
+
+// captureArtifacts prepares a test to keep evidence for a failure: terraform
+// debug logs go to its staging directory, and once the test and its
+// deferred destroy are done the directory is uploaded if the test failed
+func captureArtifacts(t *testing.T, options *terraform.Options) {
+	bucket := os.Getenv(ArtifactBucketEnvVar)
+	if bucket == "" {
+		return
+	}
+
+	// Registered before the upload so the directory outlives it
+	dir := t.TempDir()
+	staging.Store(t.Name(), dir)
+
+	if options.EnvVars == nil {
+		options.EnvVars = map[string]string{}
+	}
+	if _, ok := options.EnvVars["TF_LOG"]; !ok {
+		options.EnvVars["TF_LOG"] = "DEBUG"
+		options.EnvVars["TF_LOG_PATH"] = filepath.Join(dir, "terraform.log")
+	}
+
+	t.Cleanup(func() {
+		defer staging.Delete(t.Name())
+		if !t.Failed() {
+			return
+		}
+		prefix := path.Join(RunID(), artifactDirName(t.Name()))
+		if err := uploadDir(t, bucket, prefix, dir); err != nil {
+			logger.Logf(t, "Uploading artifacts to s3://%s/%s: %v", bucket, prefix, err)
+			return
+		}
+		location := fmt.Sprintf("s3://%s/%s/", bucket, prefix)
+		logger.Logf(t, "Uploaded artifacts of failed test to %s", location)
+		records.Update(t.Name(), func(record *report.TestRecord) {
+			if record.Artifacts == nil {
+				record.Artifacts = map[string]string{}
+			}
+			record.Artifacts["s3"] = location
+		})
+	})
+}
+
+// stageArtifact adds a file to the test's staging directory, if it has one
+func stageArtifact(t *testing.T, name string, contents string) {
+	dir, ok := staging.Load(t.Name())
+	if !ok {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir.(string), name), []byte(contents), 0o644); err != nil {
+		logger.Logf(t, "Staging %s: %v", name, err)
+	}
+}
+
+// captureFailureState snapshots the state and a fresh plan of a failed test
+// before its workspace is destroyed, since both are gone afterwards
+func captureFailureState(t *testing.T, options *terraform.Options) {
+	if _, ok := staging.Load(t.Name()); !ok || !t.Failed() {
+		return
+	}
+	if state, err := terraform.RunTerraformCommandAndGetStdoutE(t, options, "state", "pull"); err == nil {
+		stageArtifact(t, "terraform.tfstate", state)
+	} else {
+		logger.Logf(t, "Capturing state: %v", err)
+	}
+	if plan, err := planJSON(t, options); err == nil {
+		stageArtifact(t, "plan.json", plan)
+	} else {
+		logger.Logf(t, "Capturing plan: %v", err)
+	}
+}
+
+func uploadDir(t *testing.T, bucket string, prefix string, dir string) error {
+	region := os.Getenv(ArtifactRegionEnvVar)
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	uploader, err := aws.NewS3UploaderE(t, region)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := uploadFile(uploader, bucket, path.Join(prefix, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uploadFile(uploader *s3manager.Uploader, bucket string, key string, file string) error {
+	body, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = uploader.Upload(&s3manager.UploadInput{
+		Bucket: awssdk.String(bucket),
+		Key:    awssdk.String(key),
+		Body:   body,
+	})
+	return err
+}
+
+// planJSON plans the workspace into a temporary plan file and returns it as
+// JSON
+func planJSON(t *testing.T, options *terraform.Options) (string, error) {
+	planFile, err := os.CreateTemp("", "terratest-plan-")
+	if err != nil {
+		return "", err
+	}
+	planFile.Close()
+	defer os.Remove(planFile.Name())
+
+	planOptions := *options
+	planOptions.PlanFilePath = planFile.Name()
+	return terraform.InitAndPlanAndShowE(t, &planOptions)
+}
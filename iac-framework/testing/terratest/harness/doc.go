@@ -0,0 +1,8 @@
+// Package harness holds the plumbing shared by every suite: private copies
+// of the modules under test and the provider configuration generated into
+// them, throttling of applies and destroys across parallel tests, tracking
+// of applied workspaces so an interrupted run can still clean up after
+// itself, the per-test records behind the run reports, evidence kept from
+// failed tests before their infrastructure is destroyed, and a check for
+// resources a destroy left behind.
+package harness
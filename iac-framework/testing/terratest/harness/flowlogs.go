@@ -0,0 +1,114 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// flowLogsTrustPolicy lets the flow logs service assume the delivery role
+const flowLogsTrustPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Effect": "Allow",
+    "Principal": {"Service": "vpc-flow-logs.amazonaws.com"},
+    "Action": "sts:AssumeRole"
+  }]
+}`
+
+// flowLogsDeliveryPolicy is what the service needs to write to the group
+const flowLogsDeliveryPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Effect": "Allow",
+    "Action": [
+      "logs:CreateLogStream",
+      "logs:PutLogEvents",
+      "logs:DescribeLogGroups",
+      "logs:DescribeLogStreams"
+    ],
+    "Resource": "*"
+  }]
+}`
+
+// FlowLogsDestination is a log group and the role that delivers to it
+type FlowLogsDestination struct {
+	LogGroupName string
+	LogGroupArn  string
+	RoleArn      string
+}
+
+// EphemeralFlowLogsDestination creates a CloudWatch log group and a delivery
+// role for VPC flow logs, which the vpc module expects to exist, and deletes
+// both once the test and its deferred destroys finish. Both carry the run
+// tag so the sweeper can find them if the cleanup never runs.
+func EphemeralFlowLogsDestination(t *testing.T, region string) FlowLogsDestination {
+	name := fmt.Sprintf("terratest-flow-logs-%s", random.UniqueId())
+
+	sess, err := backend.NewSessionE(region)
+	require.NoError(t, err)
+	logsClient := cloudwatchlogs.New(sess)
+	iamClient := iam.New(sess)
+
+	_, err = logsClient.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: awssdk.String(name),
+		Tags:         awssdk.StringMap(map[string]string{RunTagKey: RunID()}),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := logsClient.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{
+			LogGroupName: awssdk.String(name),
+		})
+		require.NoError(t, err)
+	})
+
+	groups, err := logsClient.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: awssdk.String(name),
+	})
+	require.NoError(t, err)
+	require.Len(t, groups.LogGroups, 1)
+
+	role, err := iamClient.CreateRole(&iam.CreateRoleInput{
+		RoleName:                 awssdk.String(name),
+		AssumeRolePolicyDocument: awssdk.String(flowLogsTrustPolicy),
+		Tags: []*iam.Tag{{
+			Key:   awssdk.String(RunTagKey),
+			Value: awssdk.String(RunID()),
+		}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := iamClient.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+			RoleName:   awssdk.String(name),
+			PolicyName: awssdk.String(name),
+		})
+		require.NoError(t, err)
+		_, err = iamClient.DeleteRole(&iam.DeleteRoleInput{
+			RoleName: awssdk.String(name),
+		})
+		require.NoError(t, err)
+	})
+
+	_, err = iamClient.PutRolePolicy(&iam.PutRolePolicyInput{
+		RoleName:       awssdk.String(name),
+		PolicyName:     awssdk.String(name),
+		PolicyDocument: awssdk.String(flowLogsDeliveryPolicy),
+	})
+	require.NoError(t, err)
+
+	return FlowLogsDestination{
+		LogGroupName: name,
+		// DescribeLogGroups reports the ARN with a trailing ":*", which the
+		// flow logs API rejects as a destination
+		LogGroupArn: strings.TrimSuffix(awssdk.StringValue(groups.LogGroups[0].Arn), ":*"),
+		RoleArn:     awssdk.StringValue(role.Role.Arn),
+	}
+}
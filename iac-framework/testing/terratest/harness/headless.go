@@ -0,0 +1,49 @@
+package harness
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// headlessT satisfies terratest's TestingT outside of a running test, so the
+// terraform helpers can be reused from signal handlers and commands. Only the
+// E variants of terratest functions should be called with it.
+type headlessT struct {
+	name string
+}
+
+// HeadlessT returns a TestingT that logs failures instead of reporting them
+// to a test. FailNow panics since there is no test goroutine to stop.
+func HeadlessT(name string) testing.TestingT {
+	return headlessT{name: name}
+}
+
+func (h headlessT) Fail() {}
+
+func (h headlessT) FailNow() {
+	panic(fmt.Sprintf("%s: FailNow called outside of a test", h.name))
+}
+
+func (h headlessT) Fatal(args ...interface{}) {
+	log.Print(args...)
+	h.FailNow()
+}
+
+func (h headlessT) Fatalf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+	h.FailNow()
+}
+
+func (h headlessT) Error(args ...interface{}) {
+	log.Print(args...)
+}
+
+func (h headlessT) Errorf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func (h headlessT) Name() string {
+	return h.name
+}
@@ -0,0 +1,93 @@
+package harness
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// interruptedExitCode mirrors the shell convention for death by SIGINT
+const interruptedExitCode = 130
+
+// interruptHooks run after an interrupted run has cleaned up, in the order
+// they were registered
+var interruptHooks struct {
+	sync.Mutex
+	hooks []func()
+}
+
+// OnInterrupt registers hook to run when RunWithInterruptHandling handles a
+// signal, after the workspaces are destroyed and the reports written and
+// before the process exits. It is for cleanup that otherwise happens once
+// the suite returns, such as releasing a leased account.
+func OnInterrupt(hook func()) {
+	interruptHooks.Lock()
+	defer interruptHooks.Unlock()
+	interruptHooks.hooks = append(interruptHooks.hooks, hook)
+}
+
+func runInterruptHooks() {
+	interruptHooks.Lock()
+	hooks := append([]func(){}, interruptHooks.hooks...)
+	interruptHooks.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// RunWithInterruptHandling runs the suite and, if SIGINT or SIGTERM arrives,
+// destroys every tracked workspace before exiting. Deferred destroys in the
+// tests never run once the process is signalled, so without this an aborted
+// run leaks whatever it had applied. Workspaces that fail to destroy stay in
+// the manifest for `sweeper -manifest` to retry. A second signal exits
+// immediately. Either way the run reports are written to TEST_REPORT_DIR
+// and, if a webhook is configured, a summary is posted to it. Hooks from
+// OnInterrupt run last, before the process exits.
+//
+// Use it from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(harness.RunWithInterruptHandling(m))
+//	}
+func RunWithInterruptHandling(m *testing.M) int {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		sig := <-signals
+		go func() {
+			<-signals
+			log.Printf("second signal received, exiting without cleanup; see %s", ManifestPath())
+			os.Exit(interruptedExitCode)
+		}()
+
+		// Freeze the manifest before destroying so tests finishing in the
+		// meantime cannot drop entries we have not dealt with yet
+		active.Lock()
+		workspaces := activeLocked()
+		active.interrupted = true
+		active.Unlock()
+
+		log.Printf("received %s, destroying %d active workspace(s)", sig, len(workspaces))
+		remaining := DestroyWorkspaces(workspaces)
+		if err := WriteManifest(ManifestPath(), &Manifest{Workspaces: remaining}); err != nil {
+			log.Printf("writing workspace manifest: %v", err)
+		}
+		if len(remaining) > 0 {
+			log.Printf("%d workspace(s) could not be destroyed; see %s", len(remaining), ManifestPath())
+		}
+		writeReports()
+		notifyRun()
+		runInterruptHooks()
+		os.Exit(interruptedExitCode)
+	}()
+
+	code := m.Run()
+	writeReports()
+	notifyRun()
+	return code
+}
@@ -0,0 +1,37 @@
+package harness
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// KeyPairPrefix starts the name of every key pair the suites import so
+// leftovers are easy to recognise
+const KeyPairPrefix = "terratest-"
+
+// EphemeralKeyPair generates an RSA key pair, imports it into EC2 under a
+// unique name and deletes it once the test and its deferred destroys finish.
+// The returned key pair carries the private key for SSH checks.
+func EphemeralKeyPair(t *testing.T, region string) *aws.Ec2Keypair {
+	name := fmt.Sprintf("%s%s", KeyPairPrefix, random.UniqueId())
+	keyPair := aws.CreateAndImportEC2KeyPair(t, region, name)
+	t.Cleanup(func() {
+		aws.DeleteEC2KeyPair(t, keyPair)
+	})
+	return keyPair
+}
+
+// InjectKeyPair creates an ephemeral key pair and sets it as the module's
+// key_name variable
+func InjectKeyPair(t *testing.T, region string, options *terraform.Options) *aws.Ec2Keypair {
+	keyPair := EphemeralKeyPair(t, region)
+	if options.Vars == nil {
+		options.Vars = map[string]interface{}{}
+	}
+	options.Vars["key_name"] = keyPair.Name
+	return keyPair
+}
@@ -0,0 +1,120 @@
+package harness
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// MaxParallelInfraEnvVar caps how many applies/destroys run at once
+	MaxParallelInfraEnvVar = "TEST_MAX_PARALLEL_INFRA"
+
+	// DefaultMaxParallelInfra keeps NAT gateways and EIPs within account limits
+	DefaultMaxParallelInfra = 4
+)
+
+// limiter is a counting semaphore over infrastructure operations
+type limiter struct {
+	slots chan struct{}
+}
+
+func newLimiter(size int) *limiter {
+	return &limiter{slots: make(chan struct{}, size)}
+}
+
+func (l *limiter) acquire() time.Duration {
+	start := time.Now()
+	l.slots <- struct{}{}
+	return time.Since(start)
+}
+
+func (l *limiter) release() {
+	<-l.slots
+}
+
+var (
+	infraLimiter     *limiter
+	infraLimiterOnce sync.Once
+)
+
+// maxParallelInfra reads the limit from the environment, falling back to
+// the default for unset or nonsensical values
+func maxParallelInfra() int {
+	value, err := strconv.Atoi(os.Getenv(MaxParallelInfraEnvVar))
+	if err != nil || value < 1 {
+		return DefaultMaxParallelInfra
+	}
+	return value
+}
+
+func infraSlots() *limiter {
+	infraLimiterOnce.Do(func() {
+		infraLimiter = newLimiter(maxParallelInfra())
+	})
+	return infraLimiter
+}
+
+// WithInfraSlot runs fn once one of the TEST_MAX_PARALLEL_INFRA slots is
+// free. Tests still call t.Parallel() so cheap stages (validation, plans,
+// assertions against outputs) overlap freely; only the operations that
+// create or delete real infrastructure queue up here.
+func WithInfraSlot(t *testing.T, operation string, fn func()) {
+	if waited := infraSlots().acquire(); waited > time.Second {
+		logger.Logf(t, "Waited %s for an infrastructure slot to %s", waited.Round(time.Second), operation)
+	}
+	defer infraSlots().release()
+	fn()
+}
+
+// InitAndApply is terraform.InitAndApply throttled by the infra limiter
+func InitAndApply(t *testing.T, options *terraform.Options) string {
+	out, err := InitAndApplyE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// InitAndApplyE is terraform.InitAndApplyE throttled by the infra limiter
+func InitAndApplyE(t *testing.T, options *terraform.Options) (string, error) {
+	recordCost(t, options)
+
+	var out string
+	var err error
+	WithInfraSlot(t, "apply", func() {
+		start := time.Now()
+		out, err = withRetries(t, "apply", options, func(once *terraform.Options) (string, error) {
+			return terraform.InitAndApplyE(t, once)
+		})
+		recordOperation(t, "apply", time.Since(start), err)
+	})
+	saveArtifact(t, "apply.log", out)
+	if err == nil {
+		recordResources(t, options)
+	}
+	return out, err
+}
+
+// Destroy is terraform.Destroy throttled by the infra limiter
+func Destroy(t *testing.T, options *terraform.Options) string {
+	captureFailureState(t, options)
+
+	var out string
+	var err error
+	WithInfraSlot(t, "destroy", func() {
+		start := time.Now()
+		out, err = withRetries(t, "destroy", options, func(once *terraform.Options) (string, error) {
+			return terraform.DestroyE(t, once)
+		})
+		recordOperation(t, "destroy", time.Since(start), err)
+	})
+	saveArtifact(t, "destroy.log", out)
+	require.NoError(t, err)
+	checkOrphans(t, options)
+	return out
+}
@@ -0,0 +1,58 @@
+package harness
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxParallelInfra(t *testing.T) {
+	cases := []struct {
+		value string
+		want  int
+	}{
+		{"", DefaultMaxParallelInfra},
+		{"2", 2},
+		{"0", DefaultMaxParallelInfra},
+		{"-3", DefaultMaxParallelInfra},
+		{"lots", DefaultMaxParallelInfra},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv(MaxParallelInfraEnvVar, tc.value)
+			assert.Equal(t, tc.want, maxParallelInfra())
+		})
+	}
+}
+
+func TestLimiterCapsConcurrency(t *testing.T) {
+	l := newLimiter(2)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.acquire()
+			defer l.release()
+
+			now := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), peak, "No more than two operations should run at once")
+}
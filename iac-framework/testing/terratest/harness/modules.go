@@ -0,0 +1,51 @@
+package harness
+
+import (
+	"testing"
+
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// ModulesRoot is the module tree relative to the suite directory
+const ModulesRoot = "../../modules"
+
+// ClustersRoot holds the Kubernetes cluster stacks, one directory per cloud
+const ClustersRoot = "../../../multi-cloud-k8s"
+
+// PlatformsRoot holds the ML platform stacks, one directory per cloud
+const PlatformsRoot = "../../../ai-ml-platform/infrastructure"
+
+// ModuleDir returns a private working copy of a module such as "aws/vpc".
+// Pointing every test at the shared module directory means parallel tests
+// share one .terraform directory and one state file; a copy per test lets
+// variants of the same module run side by side. The whole module tree is
+// copied so relative sources between modules keep resolving. When the suite
+// runs against LocalStack the copy gets a provider pointing there.
+func ModuleDir(t *testing.T, module string) string {
+	dir := test_structure.CopyTerraformFolderToTemp(t, ModulesRoot, module)
+	moduleDirs.Store(dir, module)
+	if backend.IsLocalStack() {
+		WriteProviders(t, dir, ProviderConfig{Name: "aws"})
+	}
+	return dir
+}
+
+// ClusterDir returns a private working copy of the cluster stack for a cloud
+// such as "azure". The stacks configure their own providers, so nothing is
+// generated into the copy.
+func ClusterDir(t *testing.T, cloud string) string {
+	dir := test_structure.CopyTerraformFolderToTemp(t, ClustersRoot, cloud)
+	moduleDirs.Store(dir, "multi-cloud-k8s/"+cloud)
+	return dir
+}
+
+// PlatformDir returns a private working copy of the ML platform stack for a
+// cloud such as "aws". Like the cluster stacks it configures its own
+// providers.
+func PlatformDir(t *testing.T, cloud string) string {
+	dir := test_structure.CopyTerraformFolderToTemp(t, PlatformsRoot, cloud)
+	moduleDirs.Store(dir, "ai-ml-platform/"+cloud)
+	return dir
+}
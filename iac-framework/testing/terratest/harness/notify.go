@@ -0,0 +1,35 @@
+package harness
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/company/iac-framework/testing/notify"
+)
+
+// notifyRun posts the run summary to the configured webhook. Workspaces
+// still in the manifest are reported as possible leaks.
+func notifyRun() {
+	config, ok, err := notify.ConfigFromEnv()
+	if err != nil {
+		log.Printf("notifications disabled: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	var leaked []string
+	manifest, err := ReadManifest(ManifestPath())
+	if err != nil {
+		log.Printf("reading workspace manifest for notification: %v", err)
+	} else {
+		for _, workspace := range manifest.Workspaces {
+			leaked = append(leaked, fmt.Sprintf("%s: %s", workspace.Test, workspace.TerraformDir))
+		}
+	}
+
+	if err := notify.Send(config, notify.Summarize(records.Records(), leaked)); err != nil {
+		log.Printf("sending run notification: %v", err)
+	}
+}
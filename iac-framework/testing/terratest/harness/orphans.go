@@ -0,0 +1,43 @@
+package harness
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/sweeper"
+)
+
+// TestIdTagKey is the tag Track adds to the resources of each workspace,
+// valued uniquely per workspace
+const TestIdTagKey = "TerratestId"
+
+// workspaceId returns the TerratestId Track gave the workspace, if any
+func workspaceId(options *terraform.Options) (string, bool) {
+	tags, ok := options.Vars["tags"].(map[string]string)
+	if !ok || tags[TestIdTagKey] == "" {
+		return "", false
+	}
+	return tags[TestIdTagKey], true
+}
+
+// checkOrphans fails the test for every resource its destroy left behind:
+// destroy reporting success does not mean a NAT gateway's EIP or a
+// detached volume went too. Each workspace has its own id, so destroying
+// one of several a test applied does not report the others.
+func checkOrphans(t *testing.T, options *terraform.Options) {
+	id, ok := workspaceId(options)
+	if !ok || backend.IsLocalStack() {
+		return
+	}
+
+	orphans, err := sweeper.Orphans(region(options), TestIdTagKey, id)
+	if err != nil {
+		t.Logf("Checking for resources left after destroy: %v", err)
+	}
+	for _, orphan := range orphans {
+		assert.Fail(t, "Destroy left a resource behind", "%s is still tagged %s=%s", orphan, TestIdTagKey, id)
+	}
+}
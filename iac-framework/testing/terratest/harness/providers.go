@@ -0,0 +1,197 @@
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// ProvidersFileName is the file provider configurations are written to. It
+// is generated into the test's private module copy, never the module itself.
+const ProvidersFileName = "terratest_providers.tf"
+
+// ProviderConfig is one provider block for a test's module copy
+type ProviderConfig struct {
+	// Name is the provider's local name, e.g. "aws"
+	Name string
+
+	// Alias names a non-default configuration, e.g. "us_east_1" for the
+	// CloudFront certificate region or "replica" for cross-region copies
+	Alias string
+
+	// Region is set for providers that take one; empty leaves it to the
+	// environment (AWS_DEFAULT_REGION)
+	Region string
+
+	// DefaultTags renders an AWS default_tags block, the way consumers
+	// usually tag everything a module creates
+	DefaultTags map[string]string
+
+	// Endpoint sends every AWS service to one URL, for LocalStack. Account
+	// and credential checks are skipped to match. WriteProviders fills it in
+	// for AWS providers when the suite runs against LocalStack.
+	Endpoint string
+}
+
+// endpointServices are the AWS provider endpoints overridden when Endpoint
+// is set
+var endpointServices = []string{
+	"apigateway", "autoscaling", "cloudformation", "cloudwatch", "dynamodb",
+	"ec2", "ecr", "ecs", "efs", "elasticache", "elbv2", "iam", "kinesis",
+	"kms", "lambda", "logs", "rds", "route53", "s3", "secretsmanager", "ses",
+	"sns", "sqs", "ssm", "stepfunctions", "sts",
+}
+
+// AwsAlias is the common case of an aliased AWS provider pinned to a region
+func AwsAlias(alias string, region string) ProviderConfig {
+	return ProviderConfig{Name: "aws", Alias: alias, Region: region}
+}
+
+// WriteProviders writes the given provider configurations into dir, which
+// should come from ModuleDir. Modules declaring configuration_aliases can
+// then be applied directly, with each aliased provider pointed wherever the
+// test needs it. Returns the path of the generated file.
+func WriteProviders(t *testing.T, dir string, configs ...ProviderConfig) string {
+	if backend.IsLocalStack() {
+		configs = append([]ProviderConfig(nil), configs...)
+		for i := range configs {
+			if configs[i].Name == "aws" && configs[i].Endpoint == "" {
+				configs[i].Endpoint = backend.Endpoint()
+			}
+		}
+	}
+
+	path := filepath.Join(dir, ProvidersFileName)
+	require.NoError(t, os.WriteFile(path, renderProviders(configs), 0o644))
+	return path
+}
+
+func renderProviders(configs []ProviderConfig) []byte {
+	file := hclwrite.NewEmptyFile()
+	root := file.Body()
+
+	for i, config := range configs {
+		if i > 0 {
+			root.AppendNewline()
+		}
+
+		body := root.AppendNewBlock("provider", []string{config.Name}).Body()
+		if config.Alias != "" {
+			body.SetAttributeValue("alias", cty.StringVal(config.Alias))
+		}
+		if config.Region != "" {
+			body.SetAttributeValue("region", cty.StringVal(config.Region))
+		}
+		if len(config.DefaultTags) > 0 {
+			tags := map[string]cty.Value{}
+			for key, value := range config.DefaultTags {
+				tags[key] = cty.StringVal(value)
+			}
+			body.AppendNewBlock("default_tags", nil).Body().SetAttributeValue("tags", cty.MapVal(tags))
+		}
+		if config.Endpoint != "" {
+			renderEndpoint(body, config.Endpoint)
+		}
+	}
+
+	return hclwrite.Format(file.Bytes())
+}
+
+func renderEndpoint(body *hclwrite.Body, endpoint string) {
+	body.SetAttributeValue("access_key", cty.StringVal("test"))
+	body.SetAttributeValue("secret_key", cty.StringVal("test"))
+	body.SetAttributeValue("s3_use_path_style", cty.True)
+	body.SetAttributeValue("skip_credentials_validation", cty.True)
+	body.SetAttributeValue("skip_metadata_api_check", cty.True)
+	body.SetAttributeValue("skip_requesting_account_id", cty.True)
+
+	endpoints := body.AppendNewBlock("endpoints", nil).Body()
+	for _, service := range endpointServices {
+		endpoints.SetAttributeValue(service, cty.StringVal(endpoint))
+	}
+}
+
+// CallerFileName is the root configuration ModuleCallerDir writes
+const CallerFileName = "terratest_caller.tf"
+
+// ModuleCallerDir returns a root configuration that calls a private copy of
+// module the way a consumer would, handing it the provider configurations in
+// providers, e.g. {"aws": "aws.secondary"}. Each of variables is declared and
+// passed through to the module, and each of outputs passed back out, so the
+// directory takes the same options as the module itself. Write the provider
+// configurations the mapping refers to with WriteProviders.
+func ModuleCallerDir(t *testing.T, module string, providers map[string]string, variables []string, outputs []string) string {
+	moduleCopy := ModuleDir(t, module)
+
+	// The copy must not configure its own providers, or the mapping is moot
+	require.NoError(t, os.RemoveAll(filepath.Join(moduleCopy, ProvidersFileName)))
+
+	dir := filepath.Join(filepath.Dir(moduleCopy), filepath.Base(moduleCopy)+"-caller")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	config, err := renderCaller("../"+filepath.Base(moduleCopy), providers, variables, outputs)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CallerFileName), config, 0o644))
+
+	moduleDirs.Store(dir, module)
+	return dir
+}
+
+func renderCaller(source string, providers map[string]string, variables []string, outputs []string) ([]byte, error) {
+	file := hclwrite.NewEmptyFile()
+	root := file.Body()
+
+	for _, name := range variables {
+		root.AppendNewBlock("variable", []string{name})
+	}
+	if len(variables) > 0 {
+		root.AppendNewline()
+	}
+
+	call := root.AppendNewBlock("module", []string{"under_test"}).Body()
+	call.SetAttributeValue("source", cty.StringVal(source))
+	if len(providers) > 0 {
+		names := make([]string, 0, len(providers))
+		for name := range providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var mapping []hclwrite.ObjectAttrTokens
+		for _, name := range names {
+			config, diags := hclsyntax.ParseTraversalAbs([]byte(providers[name]), "", hcl.InitialPos)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("provider %s: %w", name, diags)
+			}
+			mapping = append(mapping, hclwrite.ObjectAttrTokens{
+				Name:  hclwrite.TokensForIdentifier(name),
+				Value: hclwrite.TokensForTraversal(config),
+			})
+		}
+		call.SetAttributeRaw("providers", hclwrite.TokensForObject(mapping))
+	}
+	for _, name := range variables {
+		call.SetAttributeTraversal(name, hcl.Traversal{hcl.TraverseRoot{Name: "var"}, hcl.TraverseAttr{Name: name}})
+	}
+
+	for _, name := range outputs {
+		root.AppendNewline()
+		output := root.AppendNewBlock("output", []string{name}).Body()
+		output.SetAttributeTraversal("value", hcl.Traversal{
+			hcl.TraverseRoot{Name: "module"},
+			hcl.TraverseAttr{Name: "under_test"},
+			hcl.TraverseAttr{Name: name},
+		})
+	}
+
+	return hclwrite.Format(file.Bytes()), nil
+}
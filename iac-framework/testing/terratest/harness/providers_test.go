@@ -0,0 +1,96 @@
+package harness
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+func TestWriteProviders(t *testing.T) {
+	dir := t.TempDir()
+
+	path := WriteProviders(t, dir,
+		ProviderConfig{Name: "aws"},
+		AwsAlias("us_east_1", "us-east-1"),
+		AwsAlias("replica", "eu-west-1"),
+	)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `provider "aws" {
+}
+
+provider "aws" {
+  alias  = "us_east_1"
+  region = "us-east-1"
+}
+
+provider "aws" {
+  alias  = "replica"
+  region = "eu-west-1"
+}
+`, string(contents))
+}
+
+func TestWriteProvidersDefaultTags(t *testing.T) {
+	path := WriteProviders(t, t.TempDir(), ProviderConfig{
+		Name:        "aws",
+		DefaultTags: map[string]string{"Project": "terratest", "CostCenter": "platform"},
+	})
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `provider "aws" {
+  default_tags {
+    tags = {
+      CostCenter = "platform"
+      Project    = "terratest"
+    }
+  }
+}
+`, string(contents))
+}
+
+func TestWriteProvidersLocalStack(t *testing.T) {
+	t.Setenv(backend.EnvVar, backend.LocalStack)
+	t.Setenv(backend.EndpointEnvVar, "http://localstack:4566")
+
+	path := WriteProviders(t, t.TempDir(), ProviderConfig{Name: "aws"}, ProviderConfig{Name: "random"})
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), `  skip_credentials_validation = true`)
+	assert.Contains(t, string(contents), `    ec2            = "http://localstack:4566"`)
+	assert.Contains(t, string(contents), "provider \"random\" {\n}\n", "Only AWS providers are redirected")
+}
+
+func TestRenderCaller(t *testing.T) {
+	config, err := renderCaller("../vpc", map[string]string{"aws": "aws.secondary"},
+		[]string{"project_name", "tags"}, []string{"vpc_id"})
+	require.NoError(t, err)
+	assert.Equal(t, `variable "project_name" {
+}
+variable "tags" {
+}
+
+module "under_test" {
+  source = "../vpc"
+  providers = {
+    aws = aws.secondary
+  }
+  project_name = var.project_name
+  tags         = var.tags
+}
+
+output "vpc_id" {
+  value = module.under_test.vpc_id
+}
+`, string(config))
+
+	_, err = renderCaller("../vpc", map[string]string{"aws": "not a reference"}, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package harness
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/company/iac-framework/testing/costs"
+	"github.com/company/iac-framework/testing/report"
+	"github.com/company/iac-framework/testing/retrypolicy"
+)
+
+// ReportDirEnvVar names the directory the JUnit, JSON and HTML reports and
+// captured apply/destroy logs are written to. Reports are skipped when it is
+// unset.
+const ReportDirEnvVar = "TEST_REPORT_DIR"
+
+var records = report.NewRecorder()
+
+// moduleDirs maps the private copies handed out by ModuleDir back to the
+// module they were copied from
+var moduleDirs sync.Map
+
+// recordTest starts the report record for a test applying options and
+// finishes it when the test does
+func recordTest(t *testing.T, options *terraform.Options) {
+	started := time.Now()
+	records.Update(t.Name(), func(record *report.TestRecord) {
+		record.Module = moduleName(options.TerraformDir)
+		record.TerraformBinary = options.TerraformBinary
+		record.Region = region(options)
+		record.StartedAt = started.UTC()
+	})
+
+	t.Cleanup(func() {
+		records.Update(t.Name(), func(record *report.TestRecord) {
+			record.Duration = time.Since(started)
+			record.Skipped = t.Skipped()
+			record.Failed = t.Failed()
+			if record.Failed && record.FailureReason == "" {
+				record.FailureReason = "assertions failed; see the test log"
+			}
+		})
+	})
+}
+
+// recordOperation adds the duration of an apply or destroy to the test's
+// record. The first infrastructure error becomes the failure reason.
+func recordOperation(t *testing.T, operation string, took time.Duration, err error) {
+	records.Update(t.Name(), func(record *report.TestRecord) {
+		switch operation {
+		case "apply":
+			record.ApplyDuration += took
+		case "destroy":
+			record.DestroyDuration += took
+		}
+		if err != nil && record.FailureReason == "" {
+			record.FailureReason = operation + ": " + err.Error()
+		}
+	})
+}
+
+// recordResources lists the resources in the workspace's state
+func recordResources(t *testing.T, options *terraform.Options) {
+	out, err := terraform.RunTerraformCommandAndGetStdoutE(t, options, "state", "list")
+	if err != nil {
+		return
+	}
+	resources := strings.Fields(out)
+	records.Update(t.Name(), func(record *report.TestRecord) {
+		record.Resources = resources
+	})
+}
+
+func moduleName(dir string) string {
+	if module, ok := moduleDirs.Load(dir); ok {
+		return module.(string)
+	}
+	return filepath.Base(dir)
+}
+
+func region(options *terraform.Options) string {
+	for _, key := range []string{"AWS_DEFAULT_REGION", "AWS_REGION"} {
+		if value := options.EnvVars[key]; value != "" {
+			return value
+		}
+	}
+	for _, key := range []string{"aws_region", "region"} {
+		if value, ok := options.Vars[key].(string); ok {
+			return value
+		}
+	}
+	return os.Getenv("AWS_REGION")
+}
+
+// RecordRetry counts a retried infrastructure operation against the test in
+// the run report
+func RecordRetry(t *testing.T) {
+	records.Update(t.Name(), func(record *report.TestRecord) {
+		record.Retries++
+	})
+}
+
+// withRetries runs a terraform operation under the retry policy Track added
+// to options, logging and recording each retry
+func withRetries(t *testing.T, operation string, options *terraform.Options, action func(*terraform.Options) (string, error)) (string, error) {
+	return retrypolicy.Do(options, action, func(reason string) {
+		logger.Logf(t, "Retrying %s: %s", operation, reason)
+		RecordRetry(t)
+	})
+}
+
+// saveArtifact writes contents under the report directory and links it from
+// the test's record, and stages it for upload should the test fail. Nothing
+// is saved when neither reports nor uploads are enabled.
+func saveArtifact(t *testing.T, name string, contents string) {
+	stageArtifact(t, name, contents)
+
+	dir := os.Getenv(ReportDirEnvVar)
+	if dir == "" {
+		return
+	}
+	relative := filepath.Join("artifacts", artifactDirName(t.Name()), name)
+	path := filepath.Join(dir, relative)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Logf(t, "Saving %s for the report: %v", name, err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		logger.Logf(t, "Saving %s for the report: %v", name, err)
+		return
+	}
+	records.Update(t.Name(), func(record *report.TestRecord) {
+		if record.Artifacts == nil {
+			record.Artifacts = map[string]string{}
+		}
+		record.Artifacts[name] = filepath.ToSlash(relative)
+	})
+}
+
+// artifactDirName flattens subtest names into a single directory name
+func artifactDirName(test string) string {
+	return strings.NewReplacer("/", "_", " ", "_").Replace(test)
+}
+
+// recordCost prices the configuration with Infracost before it is applied.
+// Estimates are best effort; a failure is logged and the test carries on.
+func recordCost(t *testing.T, options *terraform.Options) {
+	if !costs.Enabled() {
+		return
+	}
+
+	plan, err := planJSON(t, options)
+	if err != nil {
+		logger.Logf(t, "Estimating cost: %v", err)
+		return
+	}
+	saveArtifact(t, "plan.json", plan)
+
+	jsonFile, err := os.CreateTemp("", "terratest-cost-plan-*.json")
+	if err != nil {
+		logger.Logf(t, "Estimating cost: %v", err)
+		return
+	}
+	defer os.Remove(jsonFile.Name())
+	_, err = jsonFile.WriteString(plan)
+	if closeErr := jsonFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.Logf(t, "Estimating cost: %v", err)
+		return
+	}
+
+	estimate, err := costs.EstimatePlanE(t, jsonFile.Name())
+	if err != nil {
+		logger.Logf(t, "Estimating cost: %v", err)
+		return
+	}
+	logger.Logf(t, "Estimated cost: %s", estimate)
+	records.Update(t.Name(), func(record *report.TestRecord) {
+		record.MonthlyCost = estimate.MonthlyCost
+		record.CostCurrency = estimate.Currency
+	})
+}
+
+// writeReports writes the reports if TEST_REPORT_DIR is set
+func writeReports() {
+	dir := os.Getenv(ReportDirEnvVar)
+	if dir == "" {
+		return
+	}
+	if err := report.WriteFiles(dir, records.Records()); err != nil {
+		log.Printf("writing test reports: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package harness
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/report"
+)
+
+func TestRecordTest(t *testing.T) {
+	t.Setenv(ManifestEnvVar, filepath.Join(t.TempDir(), "manifest.json"))
+	moduleDirs.Store("/tmp/copy/aws/vpc", "aws/vpc")
+
+	var name string
+	t.Run("recorded", func(t *testing.T) {
+		name = t.Name()
+		Track(t, &terraform.Options{
+			TerraformDir: "/tmp/copy/aws/vpc",
+			EnvVars:      map[string]string{"AWS_DEFAULT_REGION": "eu-west-1"},
+		})
+		recordOperation(t, "apply", 2*time.Second, nil)
+		recordOperation(t, "destroy", time.Second, errors.New("DependencyViolation"))
+	})
+
+	var record report.TestRecord
+	for _, candidate := range records.Records() {
+		if candidate.Name == name {
+			record = candidate
+		}
+	}
+	require.Equal(t, name, record.Name, "Test should have a record")
+	assert.Equal(t, "aws/vpc", record.Module)
+	assert.Equal(t, "eu-west-1", record.Region)
+	assert.Equal(t, 2*time.Second, record.ApplyDuration)
+	assert.Equal(t, time.Second, record.DestroyDuration)
+	assert.Equal(t, "destroy: DependencyViolation", record.FailureReason)
+	assert.False(t, record.Failed, "Subtest itself passed")
+	assert.NotZero(t, record.Duration, "Duration should be set once the test finishes")
+}
+
+func TestWithRetriesRecordsRetries(t *testing.T) {
+	options := &terraform.Options{
+		RetryableTerraformErrors: map[string]string{".*ThrottlingException.*": "AWS API throttling."},
+		MaxRetries:               3,
+		TimeBetweenRetries:       time.Millisecond,
+	}
+
+	failures := 2
+	out, err := withRetries(t, "apply", options, func(*terraform.Options) (string, error) {
+		if failures > 0 {
+			failures--
+			return "Error: ThrottlingException: Rate exceeded", errors.New("exit status 1")
+		}
+		return "Apply complete!", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Apply complete!", out)
+
+	for _, record := range records.Records() {
+		if record.Name == t.Name() {
+			assert.Equal(t, 2, record.Retries)
+			return
+		}
+	}
+	t.Fatal("Retries should be recorded against the test")
+}
+
+func TestWriteReportsNeedsDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+
+	t.Setenv(ReportDirEnvVar, "")
+	writeReports()
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "No reports without TEST_REPORT_DIR")
+
+	t.Setenv(ReportDirEnvVar, dir)
+	writeReports()
+	_, err = os.Stat(filepath.Join(dir, report.JUnitFileName))
+	assert.NoError(t, err)
+}
+
+func TestSaveArtifact(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ReportDirEnvVar, dir)
+
+	var name string
+	t.Run("with artifacts", func(t *testing.T) {
+		name = t.Name()
+		saveArtifact(t, "apply.log", "Apply complete! Resources: 3 added, 0 changed, 0 destroyed.")
+	})
+
+	relative := "artifacts/TestSaveArtifact_with_artifacts/apply.log"
+	contents, err := os.ReadFile(filepath.Join(dir, relative))
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), "Apply complete!")
+
+	for _, record := range records.Records() {
+		if record.Name == name {
+			assert.Equal(t, map[string]string{"apply.log": relative}, record.Artifacts)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package harness
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/company/iac-framework/testing/modvars"
+	"github.com/company/iac-framework/testing/sweeper"
+)
+
+// RunTagKey is the tag Track adds to every workspace, valued with RunID.
+// The modules overwrite Project with their project_name, so this is what
+// the sweeper looks for.
+const RunTagKey = sweeper.DefaultTagKey
+
+// tagResources adds the run tag to the module's tags variable and, for AWS
+// modules, a TerratestId unique to the workspace that Destroy checks for
+// leftovers. Modules without a map-typed tags variable, and terragrunt
+// stacks whose units take their tags from their own inputs, are left alone;
+// passing an undeclared variable would fail the plan.
+func tagResources(t *testing.T, options *terraform.Options) {
+	if options.TerraformBinary == TerragruntBinary || !declaresTags(t, options.TerraformDir) {
+		return
+	}
+	tags := map[string]string{RunTagKey: RunID()}
+	if isAWS(options.TerraformDir) {
+		tags[TestIdTagKey] = random.UniqueId()
+	}
+	addTags(options, tags)
+}
+
+// isAWS reports whether dir holds an AWS module or one of the AWS stacks,
+// the only ones the sweeper can look for leftovers of
+func isAWS(dir string) bool {
+	module := moduleName(dir)
+	return strings.HasPrefix(module, "aws/") || module == "multi-cloud-k8s/aws" || module == "ai-ml-platform/aws"
+}
+
+// declaresTags reports whether the module in dir has a tags variable that
+// takes a map of strings
+func declaresTags(t *testing.T, dir string) bool {
+	variables, err := modvars.Load(dir)
+	if err != nil {
+		logger.Logf(t, "Reading the variables of %s to tag its resources: %v", dir, err)
+		return false
+	}
+	for _, variable := range variables {
+		if variable.Name == "tags" {
+			return variable.Type == cty.DynamicPseudoType || variable.Type.IsMapType() || variable.Type.IsObjectType()
+		}
+	}
+	return false
+}
+
+// addTags merges extra into the tags variable. A test that sets no tags
+// gets only extra, in place of the module's default tags.
+func addTags(options *terraform.Options, extra map[string]string) {
+	if options.Vars == nil {
+		options.Vars = map[string]interface{}{}
+	}
+
+	tags := map[string]string{}
+	switch existing := options.Vars["tags"].(type) {
+	case map[string]string:
+		for key, value := range existing {
+			tags[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range existing {
+			if s, ok := value.(string); ok {
+				tags[key] = s
+			}
+		}
+	}
+	for key, value := range extra {
+		tags[key] = value
+	}
+	options.Vars["tags"] = tags
+}
@@ -0,0 +1,57 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModule(t *testing.T, variables string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(variables), 0o644))
+	return dir
+}
+
+func TestTagResources(t *testing.T) {
+	tagged := &terraform.Options{
+		TerraformDir: writeModule(t, `variable "tags" { type = map(string) }`),
+		Vars:         map[string]interface{}{"tags": map[string]string{"Project": "egress-abc"}},
+	}
+	tagResources(t, tagged)
+	assert.Equal(t, map[string]string{"Project": "egress-abc", RunTagKey: RunID()}, tagged.Vars["tags"])
+
+	_, ok := workspaceId(tagged)
+	assert.False(t, ok, "Only AWS modules can be checked for leftovers")
+
+	vpc := &terraform.Options{TerraformDir: writeModule(t, `variable "tags" { type = map(string) }`)}
+	moduleDirs.Store(vpc.TerraformDir, "aws/vpc")
+	tagResources(t, vpc)
+	id, ok := workspaceId(vpc)
+	require.True(t, ok, "Destroy should know to check for orphans")
+	assert.Equal(t, map[string]string{RunTagKey: RunID(), TestIdTagKey: id}, vpc.Vars["tags"])
+
+	ec2 := &terraform.Options{TerraformDir: writeModule(t, `variable "tags" { type = map(string) }`)}
+	moduleDirs.Store(ec2.TerraformDir, "aws/ec2")
+	tagResources(t, ec2)
+	other, _ := workspaceId(ec2)
+	assert.NotEqual(t, id, other, "Each workspace of a test should be checked on its own")
+
+	untyped := &terraform.Options{TerraformDir: writeModule(t, `variable "tags" {}`)}
+	tagResources(t, untyped)
+	assert.Equal(t, map[string]string{RunTagKey: RunID()}, untyped.Vars["tags"])
+
+	labels := &terraform.Options{TerraformDir: writeModule(t, `variable "labels" { type = map(string) }`)}
+	tagResources(t, labels)
+	assert.NotContains(t, labels.Vars, "tags", "A module without a tags variable would reject one")
+
+	stack := &terraform.Options{
+		TerraformDir:    writeModule(t, `variable "tags" { type = map(string) }`),
+		TerraformBinary: TerragruntBinary,
+	}
+	tagResources(t, stack)
+	assert.Nil(t, stack.Vars)
+}
@@ -0,0 +1,101 @@
+package harness
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// TerragruntBinary is what terratest's Tg* helpers insist on
+	TerragruntBinary = "terragrunt"
+
+	// TerragruntFixturesRoot holds the terragrunt stacks used by the suites
+	TerragruntFixturesRoot = "fixtures/terragrunt"
+
+	// TerragruntModulesRootEnvVar tells the fixture stacks where the module
+	// tree is, since the stacks run from a temporary copy
+	TerragruntModulesRootEnvVar = "TG_MODULES_ROOT"
+
+	// TerragruntNamePrefixEnvVar gives each run of a stack unique names
+	TerragruntNamePrefixEnvVar = "TG_NAME_PREFIX"
+)
+
+// TerragruntStack copies a fixture stack such as "vpc-ec2" to a private
+// directory and returns options for one of its environments, e.g. "dev".
+// The options run terragrunt from the environment directory, so run-all
+// covers every unit in it.
+func TerragruntStack(t *testing.T, stack string, env string) *terraform.Options {
+	modules, err := filepath.Abs(ModulesRoot)
+	require.NoError(t, err)
+
+	dir, err := files.CopyTerragruntFolderToDest(filepath.Join(TerragruntFixturesRoot, stack), t.TempDir(), stack)
+	require.NoError(t, err)
+	return &terraform.Options{
+		TerraformDir:    filepath.Join(dir, env),
+		TerraformBinary: TerragruntBinary,
+		EnvVars: map[string]string{
+			TerragruntModulesRootEnvVar:  modules,
+			TerragruntNamePrefixEnvVar:   "tg-" + random.UniqueId(),
+			"TERRAGRUNT_NON_INTERACTIVE": "true",
+		},
+	}
+}
+
+// TerragruntUnit returns options for a single unit of the stack, e.g. "vpc",
+// for reading its outputs
+func TerragruntUnit(options *terraform.Options, unit string) *terraform.Options {
+	unitOptions := *options
+	unitOptions.TerraformDir = filepath.Join(options.TerraformDir, unit)
+	return &unitOptions
+}
+
+// RunAllApply is terraform.TgApplyAll throttled by the infra limiter
+func RunAllApply(t *testing.T, options *terraform.Options) string {
+	var out string
+	var err error
+	WithInfraSlot(t, "run-all apply", func() {
+		start := time.Now()
+		out, err = withRetries(t, "run-all apply", options, func(once *terraform.Options) (string, error) {
+			return terraform.TgApplyAllE(t, once)
+		})
+		recordOperation(t, "apply", time.Since(start), err)
+	})
+	saveArtifact(t, "apply.log", out)
+	require.NoError(t, err)
+	return out
+}
+
+// RunAllDestroy is terraform.TgDestroyAll throttled by the infra limiter
+func RunAllDestroy(t *testing.T, options *terraform.Options) string {
+	captureFailureState(t, options)
+
+	var out string
+	var err error
+	WithInfraSlot(t, "run-all destroy", func() {
+		start := time.Now()
+		out, err = withRetries(t, "run-all destroy", options, func(once *terraform.Options) (string, error) {
+			return terraform.TgDestroyAllE(t, once)
+		})
+		recordOperation(t, "destroy", time.Since(start), err)
+	})
+	saveArtifact(t, "destroy.log", out)
+	require.NoError(t, err)
+	return out
+}
+
+// RunAllValidate runs run-all validate. Before the stack is applied,
+// dependencies resolve to their mock_outputs, which the fixtures only allow
+// for validate, so this checks the wiring between units without creating
+// anything.
+func RunAllValidate(t *testing.T, options *terraform.Options) string {
+	out, err := terraform.RunTerraformCommandE(t, options, "run-all", "validate")
+	saveArtifact(t, "validate.log", out)
+	require.NoError(t, err)
+	return out
+}
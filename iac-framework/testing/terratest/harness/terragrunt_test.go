@@ -0,0 +1,30 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerragruntStack(t *testing.T) {
+	// The fixtures live beside the suite, one directory up from here
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(".."))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	options := TerragruntStack(t, "vpc-ec2", "dev")
+
+	assert.Equal(t, TerragruntBinary, options.TerraformBinary)
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "env.hcl"))
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "..", "root.hcl"), "Root config should be copied with the stack")
+	assert.True(t, filepath.IsAbs(options.EnvVars[TerragruntModulesRootEnvVar]), "Modules root should be absolute")
+	assert.DirExists(t, filepath.Join(options.EnvVars[TerragruntModulesRootEnvVar], "aws", "vpc"))
+
+	unit := TerragruntUnit(options, "vpc")
+	assert.Equal(t, filepath.Join(options.TerraformDir, "vpc"), unit.TerraformDir)
+	assert.Equal(t, options.EnvVars, unit.EnvVars)
+}
@@ -0,0 +1,96 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/upgrade"
+)
+
+// crossedReleases maps directories from ReleasedModuleDir to the releases
+// of the module's breaking changes manifest the upgrade crosses
+var crossedReleases sync.Map
+
+// ReleasedModuleDir is ModuleDir for the module as of its last release, or
+// the ref in TEST_UPGRADE_FROM. The test is skipped when the module has no
+// release to upgrade from yet, or when its breaking changes manifest says a
+// release in between cannot be upgraded to in place.
+func ReleasedModuleDir(t *testing.T, module string) string {
+	ref := os.Getenv(upgrade.FromRefEnvVar)
+	if ref == "" {
+		tags, err := upgrade.Tags(ModulesRoot)
+		require.NoError(t, err)
+
+		var ok bool
+		if ref, ok = upgrade.LatestRelease(tags, module); !ok {
+			t.Skipf("%s has no release to upgrade from", module)
+		}
+	}
+
+	manifest, err := upgrade.LoadManifest(filepath.Join(ModulesRoot, filepath.FromSlash(module)))
+	require.NoError(t, err)
+	releases := manifest.Since(ref)
+	if release, ok := upgrade.NotInPlace(releases); ok {
+		t.Skipf("%s %s cannot be upgraded to in place from %s: %s", module, release.Version, ref, release.Reason)
+	}
+
+	root := t.TempDir()
+	require.NoError(t, upgrade.Export(ModulesRoot, ref, root))
+	dir := filepath.Join(root, filepath.FromSlash(module))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		t.Skipf("%s did not exist at %s", module, ref)
+	}
+
+	t.Logf("Upgrading %s from %s", module, ref)
+	moduleDirs.Store(dir, module)
+	crossedReleases.Store(dir, releases)
+	if backend.IsLocalStack() {
+		WriteProviders(t, dir, ProviderConfig{Name: "aws"})
+	}
+	return dir
+}
+
+// UpgradeToWorkingTree swaps the released module tree under a directory
+// from ReleasedModuleDir for the working tree, keeping the module's state
+// and .terraform directory, as a consumer bumping the version would
+func UpgradeToWorkingTree(t *testing.T, options *terraform.Options) {
+	module, ok := moduleDirs.Load(options.TerraformDir)
+	require.True(t, ok, "%s did not come from ReleasedModuleDir", options.TerraformDir)
+
+	root := strings.TrimSuffix(options.TerraformDir, filepath.FromSlash(module.(string)))
+	require.NoError(t, upgrade.Swap(ModulesRoot, root, module.(string)))
+	if backend.IsLocalStack() {
+		WriteProviders(t, options.TerraformDir, ProviderConfig{Name: "aws"})
+	}
+}
+
+// AssertNoDestructiveChanges plans the options and fails the test for every
+// resource the plan would delete or replace that the module's breaking
+// changes manifest does not declare for the releases the upgrade crosses
+func AssertNoDestructiveChanges(t *testing.T, options *terraform.Options) {
+	planOptions := *options
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "upgrade.tfplan")
+
+	var releases []upgrade.Release
+	if crossed, ok := crossedReleases.Load(options.TerraformDir); ok {
+		releases = crossed.([]upgrade.Release)
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStruct(t, &planOptions)
+	undeclared, declared := upgrade.Undeclared(upgrade.Destructive(plan), releases)
+	for _, address := range declared {
+		t.Logf("%s is deleted or replaced, as the breaking changes manifest declares", address)
+	}
+	for _, address := range undeclared {
+		assert.Failf(t, "Upgrade destroys a resource",
+			"%s would be deleted or replaced; if intended, declare it under %q in the module's %s", address, upgrade.Unreleased, upgrade.ManifestFileName)
+	}
+}
@@ -0,0 +1,32 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/upgrade"
+)
+
+func TestReleasedModuleDir(t *testing.T) {
+	// ModulesRoot is relative to the suite, one directory up from here
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(".."))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv(upgrade.FromRefEnvVar, "HEAD")
+
+	options := &terraform.Options{TerraformDir: ReleasedModuleDir(t, "aws/vpc")}
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "main.tf"))
+	assert.Equal(t, "aws/vpc", moduleName(options.TerraformDir))
+
+	state := filepath.Join(options.TerraformDir, "terraform.tfstate")
+	require.NoError(t, os.WriteFile(state, []byte("{}"), 0o644))
+	UpgradeToWorkingTree(t, options)
+	assert.FileExists(t, filepath.Join(options.TerraformDir, "main.tf"))
+	assert.FileExists(t, state, "State should survive the upgrade")
+}
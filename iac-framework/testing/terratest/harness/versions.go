@@ -0,0 +1,49 @@
+package harness
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/versions"
+)
+
+var installs sync.Map
+
+// ForEachVersion runs fn as a subtest per entry of the version matrix
+// (TEST_TF_MATRIX), passing the binary to set as TerraformBinary. Each
+// release is downloaded once per run however many tests use it, and results
+// show up per version in the run report through the subtest names.
+func ForEachVersion(t *testing.T, fn func(t *testing.T, binary string)) {
+	matrix, err := versions.Matrix()
+	require.NoError(t, err)
+
+	for _, version := range matrix {
+		version := version
+		t.Run(version.String(), func(t *testing.T) {
+			t.Parallel()
+			fn(t, install(t, version))
+		})
+	}
+}
+
+func install(t *testing.T, version versions.Version) string {
+	once, _ := installs.LoadOrStore(version, &installOnce{})
+	binary, err := once.(*installOnce).do(version)
+	require.NoError(t, err, "installing %s", version)
+	return binary
+}
+
+type installOnce struct {
+	once   sync.Once
+	binary string
+	err    error
+}
+
+func (i *installOnce) do(version versions.Version) (string, error) {
+	i.once.Do(func() {
+		i.binary, i.err = versions.Install(version)
+	})
+	return i.binary, i.err
+}
@@ -0,0 +1,220 @@
+package harness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/company/iac-framework/testing/retrypolicy"
+)
+
+const (
+	// ManifestEnvVar overrides where the workspace manifest is written
+	ManifestEnvVar = "TEST_WORKSPACE_MANIFEST"
+
+	// DefaultManifestPath is relative to the directory the tests run in
+	DefaultManifestPath = "workspaces-manifest.json"
+)
+
+// Workspace is a terraform working directory a test has applied, with just
+// enough of its options to destroy it again from outside the test.
+type Workspace struct {
+	Test            string                 `json:"test"`
+	TerraformDir    string                 `json:"terraform_dir"`
+	TerraformBinary string                 `json:"terraform_binary,omitempty"`
+	Vars            map[string]interface{} `json:"vars,omitempty"`
+	VarFiles        []string               `json:"var_files,omitempty"`
+	EnvVars         map[string]string      `json:"env_vars,omitempty"`
+	StartedAt       time.Time              `json:"started_at"`
+}
+
+// Options rebuilds terraform options for destroying the workspace
+func (w Workspace) Options() *terraform.Options {
+	return retrypolicy.Default().Apply(&terraform.Options{
+		TerraformDir:    w.TerraformDir,
+		TerraformBinary: w.TerraformBinary,
+		Vars:            w.Vars,
+		VarFiles:        w.VarFiles,
+		EnvVars:         w.EnvVars,
+		NoColor:         true,
+	})
+}
+
+func (w Workspace) key() string {
+	return w.Test + "|" + w.TerraformDir
+}
+
+// Manifest is the on-disk list of workspaces that may still hold resources
+type Manifest struct {
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// ManifestPath returns the manifest location for this run
+func ManifestPath() string {
+	if path := os.Getenv(ManifestEnvVar); path != "" {
+		return path
+	}
+	return DefaultManifestPath
+}
+
+// ReadManifest loads a manifest; a missing file is an empty manifest
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// WriteManifest replaces the manifest atomically, or removes it when there
+// is nothing left to clean up.
+func WriteManifest(path string, manifest *Manifest) error {
+	if len(manifest.Workspaces) == 0 {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// DestroyWorkspaces runs a best-effort destroy of each workspace and returns
+// the ones that could not be destroyed.
+func DestroyWorkspaces(workspaces []Workspace) []Workspace {
+	var remaining []Workspace
+	for _, workspace := range workspaces {
+		log.Printf("destroying %s (%s)", workspace.TerraformDir, workspace.Test)
+		destroy := terraform.DestroyE
+		if workspace.TerraformBinary == TerragruntBinary {
+			// Terragrunt stacks are tracked at the environment directory
+			destroy = terraform.TgDestroyAllE
+		}
+		if _, err := destroy(HeadlessT(workspace.Test), workspace.Options()); err != nil {
+			log.Printf("destroy of %s failed: %v", workspace.TerraformDir, err)
+			remaining = append(remaining, workspace)
+		}
+	}
+	return remaining
+}
+
+// active tracks the workspaces of the tests currently running
+var active = struct {
+	sync.Mutex
+	workspaces map[string]Workspace
+
+	// interrupted hands the manifest over to the interrupt handler
+	interrupted bool
+}{workspaces: map[string]Workspace{}}
+
+// Track records a test's workspace in the manifest until the test and its
+// deferred destroy have finished. Call it before deferring harness.Destroy
+// so an interrupted run knows what to clean up. The test also gets an entry
+// in the run report and, with TEST_ARTIFACT_BUCKET set, its logs, plan and
+// state are uploaded if it fails. The suite's retry policy is added to
+// options, and the run tag to the module's tags so the sweeper can find
+// whatever the test leaks. AWS workspaces also get a TerratestId tag, and
+// Destroy fails the test for anything still carrying it afterwards.
+func Track(t *testing.T, options *terraform.Options) {
+	retrypolicy.Default().Apply(options)
+	tagResources(t, options)
+	recordTest(t, options)
+	captureArtifacts(t, options)
+
+	dir, err := filepath.Abs(options.TerraformDir)
+	if err != nil {
+		dir = options.TerraformDir
+	}
+
+	workspace := Workspace{
+		Test:            t.Name(),
+		TerraformDir:    dir,
+		TerraformBinary: options.TerraformBinary,
+		Vars:            options.Vars,
+		VarFiles:        options.VarFiles,
+		EnvVars:         withoutLogPath(options.EnvVars),
+		StartedAt:       time.Now().UTC(),
+	}
+
+	active.Lock()
+	active.workspaces[workspace.key()] = workspace
+	saveActiveLocked()
+	active.Unlock()
+
+	t.Cleanup(func() {
+		active.Lock()
+		delete(active.workspaces, workspace.key())
+		saveActiveLocked()
+		active.Unlock()
+	})
+}
+
+// ActiveWorkspaces returns the workspaces of running tests, oldest first
+func ActiveWorkspaces() []Workspace {
+	active.Lock()
+	defer active.Unlock()
+	return activeLocked()
+}
+
+func activeLocked() []Workspace {
+	workspaces := make([]Workspace, 0, len(active.workspaces))
+	for _, workspace := range active.workspaces {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].StartedAt.Before(workspaces[j].StartedAt)
+	})
+	return workspaces
+}
+
+func saveActiveLocked() {
+	if active.interrupted {
+		return
+	}
+	if err := WriteManifest(ManifestPath(), &Manifest{Workspaces: activeLocked()}); err != nil {
+		log.Printf("writing workspace manifest: %v", err)
+	}
+}
+
+// withoutLogPath copies env vars minus TF_LOG_PATH, which points into the
+// test's temporary directory and would be gone by the time the manifest is
+// used to destroy the workspace
+func withoutLogPath(envVars map[string]string) map[string]string {
+	if _, ok := envVars["TF_LOG_PATH"]; !ok {
+		return envVars
+	}
+	out := map[string]string{}
+	for key, value := range envVars {
+		if key != "TF_LOG_PATH" {
+			out[key] = value
+		}
+	}
+	return out
+}
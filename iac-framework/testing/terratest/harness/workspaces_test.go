@@ -0,0 +1,73 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	manifest, err := ReadManifest(path)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Workspaces, "Missing manifest should read as empty")
+
+	written := &Manifest{Workspaces: []Workspace{{
+		Test:         "TestVPCModule",
+		TerraformDir: "/modules/aws/vpc",
+		Vars:         map[string]interface{}{"vpc_cidr": "10.0.0.0/16"},
+		EnvVars:      map[string]string{"AWS_DEFAULT_REGION": "us-west-2"},
+	}}}
+	require.NoError(t, WriteManifest(path, written))
+
+	read, err := ReadManifest(path)
+	require.NoError(t, err)
+	assert.Equal(t, written.Workspaces[0].TerraformDir, read.Workspaces[0].TerraformDir)
+	assert.Equal(t, "10.0.0.0/16", read.Workspaces[0].Vars["vpc_cidr"])
+
+	require.NoError(t, WriteManifest(path, &Manifest{}))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "Empty manifest should be removed")
+}
+
+func TestTrackRecordsWorkspaceUntilCleanup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	t.Setenv(ManifestEnvVar, path)
+
+	t.Run("tracked", func(t *testing.T) {
+		Track(t, &terraform.Options{TerraformDir: "../../modules/aws/vpc"})
+
+		manifest, err := ReadManifest(path)
+		require.NoError(t, err)
+		require.Len(t, manifest.Workspaces, 1)
+		assert.Equal(t, t.Name(), manifest.Workspaces[0].Test)
+		assert.True(t, filepath.IsAbs(manifest.Workspaces[0].TerraformDir), "Workspace dir should be absolute")
+	})
+
+	manifest, err := ReadManifest(path)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Workspaces, "Workspace should be dropped once the test finishes")
+	assert.Empty(t, ActiveWorkspaces())
+}
+
+func TestTrackCapturesTerraformLogs(t *testing.T) {
+	t.Setenv(ManifestEnvVar, filepath.Join(t.TempDir(), "manifest.json"))
+	t.Setenv(ArtifactBucketEnvVar, "terratest-artifacts")
+
+	t.Run("captured", func(t *testing.T) {
+		options := &terraform.Options{TerraformDir: "../../modules/aws/vpc"}
+		Track(t, options)
+
+		assert.Equal(t, "DEBUG", options.EnvVars["TF_LOG"])
+		assert.NotEmpty(t, options.EnvVars["TF_LOG_PATH"], "Debug logs should go to the staging directory")
+
+		workspaces := ActiveWorkspaces()
+		require.Len(t, workspaces, 1)
+		assert.NotContains(t, workspaces[0].EnvVars, "TF_LOG_PATH", "Manifest should not point at the temporary log file")
+	})
+}
@@ -0,0 +1,249 @@
+// Package iamcheck asserts that the IAM roles a module creates grant what
+// they are meant to and nothing more. Allowed and denied actions are checked
+// with the policy simulator; the policy documents themselves go through
+// IAM Access Analyzer validation and a check for wildcard grants.
+package iamcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/accessanalyzer"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// awsManagedPrefix marks policies owned by AWS. They are the caller's choice
+// to attach and change under us, so only their effect is simulated.
+const awsManagedPrefix = "arn:aws:iam::aws:policy/"
+
+// Policy is a policy document attached to a role
+type Policy struct {
+	Name     string
+	Document string
+}
+
+// Decisions simulates actions against the role's policies and returns the
+// decision for each, e.g. "allowed" or "implicitDeny". resources defaults
+// to "*".
+func Decisions(t testing.TestingT, region string, roleArn string, actions []string, resources ...string) map[string]string {
+	if len(resources) == 0 {
+		resources = []string{"*"}
+	}
+	client := newIamClient(t, region)
+
+	decisions := map[string]string{}
+	err := client.SimulatePrincipalPolicyPages(&iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: awssdk.String(roleArn),
+		ActionNames:     awssdk.StringSlice(actions),
+		ResourceArns:    awssdk.StringSlice(resources),
+	}, func(page *iam.SimulatePolicyResponse, lastPage bool) bool {
+		for _, result := range page.EvaluationResults {
+			decisions[awssdk.StringValue(result.EvalActionName)] = awssdk.StringValue(result.EvalDecision)
+		}
+		return true
+	})
+	require.NoError(t, err)
+	return decisions
+}
+
+// AssertActions asserts the role is allowed every action in allowed and
+// denied every action in denied
+func AssertActions(t testing.TestingT, region string, roleArn string, allowed []string, denied []string) {
+	decisions := Decisions(t, region, roleArn, append(append([]string{}, allowed...), denied...))
+	for _, action := range allowed {
+		assert.Equal(t, iam.PolicyEvaluationDecisionTypeAllowed, decisions[action], "%s should be allowed %s", roleArn, action)
+	}
+	for _, action := range denied {
+		assert.NotEqual(t, iam.PolicyEvaluationDecisionTypeAllowed, decisions[action], "%s should not be allowed %s", roleArn, action)
+	}
+}
+
+// RolePolicies returns the inline and customer managed policies of a role.
+// AWS managed policies are left out.
+func RolePolicies(t testing.TestingT, region string, roleName string) []Policy {
+	client := newIamClient(t, region)
+	var policies []Policy
+
+	var inline []*string
+	err := client.ListRolePoliciesPages(&iam.ListRolePoliciesInput{RoleName: awssdk.String(roleName)},
+		func(page *iam.ListRolePoliciesOutput, lastPage bool) bool {
+			inline = append(inline, page.PolicyNames...)
+			return true
+		})
+	require.NoError(t, err)
+	for _, name := range inline {
+		out, err := client.GetRolePolicy(&iam.GetRolePolicyInput{RoleName: awssdk.String(roleName), PolicyName: name})
+		require.NoError(t, err)
+		policies = append(policies, Policy{Name: awssdk.StringValue(name), Document: decode(t, out.PolicyDocument)})
+	}
+
+	var attached []*iam.AttachedPolicy
+	err = client.ListAttachedRolePoliciesPages(&iam.ListAttachedRolePoliciesInput{RoleName: awssdk.String(roleName)},
+		func(page *iam.ListAttachedRolePoliciesOutput, lastPage bool) bool {
+			attached = append(attached, page.AttachedPolicies...)
+			return true
+		})
+	require.NoError(t, err)
+	for _, policy := range attached {
+		if strings.HasPrefix(awssdk.StringValue(policy.PolicyArn), awsManagedPrefix) {
+			continue
+		}
+		meta, err := client.GetPolicy(&iam.GetPolicyInput{PolicyArn: policy.PolicyArn})
+		require.NoError(t, err)
+		version, err := client.GetPolicyVersion(&iam.GetPolicyVersionInput{
+			PolicyArn: policy.PolicyArn,
+			VersionId: meta.Policy.DefaultVersionId,
+		})
+		require.NoError(t, err)
+		policies = append(policies, Policy{Name: awssdk.StringValue(policy.PolicyName), Document: decode(t, version.PolicyVersion.Document)})
+	}
+	return policies
+}
+
+// TrustPolicy returns the role's assume role policy document
+func TrustPolicy(t testing.TestingT, region string, roleName string) string {
+	out, err := newIamClient(t, region).GetRole(&iam.GetRoleInput{RoleName: awssdk.String(roleName)})
+	require.NoError(t, err)
+	return decode(t, out.Role.AssumeRolePolicyDocument)
+}
+
+// AssertLeastPrivilege validates the role's trust policy and its inline and
+// customer managed policies with Access Analyzer, failing on errors and
+// security warnings, and fails on any wildcard grant
+func AssertLeastPrivilege(t testing.TestingT, region string, roleName string) {
+	sess, err := backend.NewSessionE(region)
+	require.NoError(t, err)
+	analyzer := accessanalyzer.New(sess)
+
+	trust := TrustPolicy(t, region, roleName)
+	assertValid(t, analyzer, roleName+" trust policy", &accessanalyzer.ValidatePolicyInput{
+		PolicyDocument:             awssdk.String(trust),
+		PolicyType:                 awssdk.String(accessanalyzer.PolicyTypeResourcePolicy),
+		ValidatePolicyResourceType: awssdk.String(accessanalyzer.ValidatePolicyResourceTypeAwsIamAssumeRolePolicyDocument),
+	})
+
+	for _, policy := range RolePolicies(t, region, roleName) {
+		assertValid(t, analyzer, policy.Name, &accessanalyzer.ValidatePolicyInput{
+			PolicyDocument: awssdk.String(policy.Document),
+			PolicyType:     awssdk.String(accessanalyzer.PolicyTypeIdentityPolicy),
+		})
+
+		wildcards, err := Wildcards(policy.Document)
+		require.NoError(t, err)
+		for _, wildcard := range wildcards {
+			assert.Fail(t, "Policy grants a wildcard", "%s: %s", policy.Name, wildcard)
+		}
+	}
+}
+
+func assertValid(t testing.TestingT, client *accessanalyzer.AccessAnalyzer, name string, input *accessanalyzer.ValidatePolicyInput) {
+	err := client.ValidatePolicyPages(input, func(page *accessanalyzer.ValidatePolicyOutput, lastPage bool) bool {
+		for _, finding := range page.Findings {
+			switch awssdk.StringValue(finding.FindingType) {
+			case accessanalyzer.ValidatePolicyFindingTypeError, accessanalyzer.ValidatePolicyFindingTypeSecurityWarning:
+				assert.Fail(t, "Access Analyzer finding", "%s: %s %s: %s",
+					name, awssdk.StringValue(finding.FindingType), awssdk.StringValue(finding.IssueCode), awssdk.StringValue(finding.FindingDetails))
+			}
+		}
+		return true
+	})
+	require.NoError(t, err)
+}
+
+// statement is the part of a policy statement wildcards are looked for in.
+// Action and Resource may each be a string or a list.
+type statement struct {
+	Effect   string          `json:"Effect"`
+	Action   json.RawMessage `json:"Action"`
+	Resource json.RawMessage `json:"Resource"`
+}
+
+// Wildcards describes every Allow statement in a policy document granting
+// all actions, all actions of a service, or an action on every resource.
+// Resource "*" alone is not flagged for actions that only work that way,
+// such as ec2:Describe*, unless the action is a wildcard too.
+func Wildcards(document string) ([]string, error) {
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return nil, fmt.Errorf("parsing policy: %w", err)
+	}
+	var statements []statement
+	if err := unmarshalOneOrMany(policy.Statement, &statements); err != nil {
+		return nil, fmt.Errorf("parsing policy statements: %w", err)
+	}
+
+	var found []string
+	for _, s := range statements {
+		if s.Effect != "Allow" {
+			continue
+		}
+		var actions, resources []string
+		if err := unmarshalOneOrMany(s.Action, &actions); err != nil {
+			return nil, fmt.Errorf("parsing Action: %w", err)
+		}
+		if err := unmarshalOneOrMany(s.Resource, &resources); err != nil {
+			return nil, fmt.Errorf("parsing Resource: %w", err)
+		}
+		allResources := contains(resources, "*")
+		for _, action := range actions {
+			switch {
+			case action == "*":
+				found = append(found, "allows every action")
+			case strings.HasSuffix(action, ":*") && allResources:
+				found = append(found, fmt.Sprintf("allows %s on every resource", action))
+			}
+		}
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
+// unmarshalOneOrMany decodes a JSON value that is either one T or a list
+// of them, as policy grammar allows for statements, actions and resources
+func unmarshalOneOrMany[T any](raw json.RawMessage, out *[]T) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if raw[0] == '[' {
+		return json.Unmarshal(raw, out)
+	}
+	var one T
+	if err := json.Unmarshal(raw, &one); err != nil {
+		return err
+	}
+	*out = []T{one}
+	return nil
+}
+
+func contains(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
+
+// decode undoes the URL encoding IAM returns policy documents in
+func decode(t testing.TestingT, document *string) string {
+	decoded, err := url.QueryUnescape(awssdk.StringValue(document))
+	require.NoError(t, err)
+	return decoded
+}
+
+func newIamClient(t testing.TestingT, region string) *iam.IAM {
+	sess, err := backend.NewSessionE(region)
+	require.NoError(t, err)
+	return iam.New(sess)
+}
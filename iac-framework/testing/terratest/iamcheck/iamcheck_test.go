@@ -0,0 +1,57 @@
+package iamcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWildcards(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{
+			name:     "scoped actions",
+			document: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:GetObject","ec2:Describe*"],"Resource":"*"}]}`,
+		},
+		{
+			name:     "every action",
+			document: `{"Version":"2012-10-17","Statement":{"Effect":"Allow","Action":"*","Resource":"arn:aws:s3:::bucket"}}`,
+			expected: []string{"allows every action"},
+		},
+		{
+			name:     "service wildcard on every resource",
+			document: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:*","logs:PutLogEvents"],"Resource":["*"]}]}`,
+			expected: []string{"allows s3:* on every resource"},
+		},
+		{
+			name:     "service wildcard on one resource",
+			document: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::bucket/*"}]}`,
+		},
+		{
+			name:     "deny is not a grant",
+			document: `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"*","Resource":"*"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			found, err := Wildcards(tc.document)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, found)
+		})
+	}
+}
+
+func TestWildcardsRejectsMalformedPolicy(t *testing.T) {
+	_, err := Wildcards(`{"Statement":[{"Effect":"Allow","Action":7}]}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+// Package imds checks that instances only answer IMDSv2, session token
+// based, metadata requests. IMDSv1 lets anything that can make the instance
+// issue a GET, such as an SSRF bug, read its role credentials.
+package imds
+
+import (
+	"fmt"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/ssmexec"
+)
+
+const metadataUrl = "http://169.254.169.254/latest/meta-data/"
+
+// v1Request prints only the status code of a token-less metadata request
+const v1Request = "curl --silent --output /dev/null --write-out '%{http_code}' " + metadataUrl
+
+// v2Request fetches a session token first and prints the status code of the
+// metadata request made with it
+const v2Request = `TOKEN=$(curl --silent --request PUT http://169.254.169.254/latest/api/token --header "X-aws-ec2-metadata-token-ttl-seconds: 60") && ` +
+	`curl --silent --output /dev/null --write-out '%{http_code}' --header "X-aws-ec2-metadata-token: $TOKEN" ` + metadataUrl
+
+// AssertRequired asserts the instance requires session tokens and uses the
+// given PUT response hop limit
+func AssertRequired(t testing.TestingT, region string, instanceId string, hopLimit int64) {
+	client := backend.NewEc2Client(t, region)
+	out, err := client.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: awssdk.StringSlice([]string{instanceId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Reservations, 1)
+	require.Len(t, out.Reservations[0].Instances, 1)
+
+	for _, problem := range checkOptions(out.Reservations[0].Instances[0].MetadataOptions, hopLimit) {
+		assert.Fail(t, problem, "instance %s", instanceId)
+	}
+}
+
+func checkOptions(options *ec2.InstanceMetadataOptionsResponse, hopLimit int64) []string {
+	if options == nil {
+		return []string{"instance has no metadata options"}
+	}
+
+	var problems []string
+	if endpoint := awssdk.StringValue(options.HttpEndpoint); endpoint != ec2.InstanceMetadataEndpointStateEnabled {
+		problems = append(problems, fmt.Sprintf("metadata endpoint is %q, expected enabled", endpoint))
+	}
+	if tokens := awssdk.StringValue(options.HttpTokens); tokens != ec2.HttpTokensStateRequired {
+		problems = append(problems, fmt.Sprintf("http tokens are %q, expected required", tokens))
+	}
+	if limit := awssdk.Int64Value(options.HttpPutResponseHopLimit); limit != hopLimit {
+		problems = append(problems, fmt.Sprintf("put response hop limit is %d, expected %d", limit, hopLimit))
+	}
+	return problems
+}
+
+// AssertV1Rejected makes metadata requests from inside the instance over SSM
+// and asserts a token-less request gets 401 while a token request succeeds
+func AssertV1Rejected(t testing.TestingT, region string, instanceId string) {
+	v1 := ssmexec.AssertSucceeds(t, region, instanceId, v1Request)
+	assert.Equal(t, "401", v1, "IMDSv1 request should be rejected")
+
+	v2 := ssmexec.AssertSucceeds(t, region, instanceId, v2Request)
+	assert.Equal(t, "200", v2, "IMDSv2 request should succeed")
+}
@@ -0,0 +1,53 @@
+package imds
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOptions(t *testing.T) {
+	options := func(endpoint, tokens string, hopLimit int64) *ec2.InstanceMetadataOptionsResponse {
+		return &ec2.InstanceMetadataOptionsResponse{
+			HttpEndpoint:            awssdk.String(endpoint),
+			HttpTokens:              awssdk.String(tokens),
+			HttpPutResponseHopLimit: awssdk.Int64(hopLimit),
+		}
+	}
+
+	cases := []struct {
+		name     string
+		options  *ec2.InstanceMetadataOptionsResponse
+		problems []string
+	}{
+		{
+			name:    "required",
+			options: options("enabled", "required", 2),
+		},
+		{
+			name:     "optional tokens",
+			options:  options("enabled", "optional", 2),
+			problems: []string{`http tokens are "optional", expected required`},
+		},
+		{
+			name:    "disabled endpoint and wrong hop limit",
+			options: options("disabled", "required", 1),
+			problems: []string{
+				`metadata endpoint is "disabled", expected enabled`,
+				"put response hop limit is 1, expected 2",
+			},
+		},
+		{
+			name:     "missing options",
+			problems: []string{"instance has no metadata options"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problems, checkOptions(tc.options, 2))
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// Package k8scleanup removes what Kubernetes created in AWS on a cluster's
+// behalf before terraform destroys the cluster. LoadBalancer services and
+// ingresses make the cloud controller create load balancers and security
+// groups that are not in the state; destroying the cluster first leaves
+// them orphaned and holding on to the VPC, so its destroy fails too.
+//
+// Run it deferred after harness.Destroy so it runs first:
+//
+//	defer harness.Destroy(t, terraformOptions)
+//	harness.InitAndApply(t, terraformOptions)
+//	...
+//	defer k8scleanup.Run(t, kubectl, region, clusterName)
+package k8scleanup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+const (
+	// Timeout bounds how long the cloud controller may take to release
+	// everything
+	Timeout = 10 * time.Minute
+
+	pollInterval = 15 * time.Second
+
+	// describeTagsLimit is the most load balancers DescribeTags accepts
+	describeTagsLimit = 20
+
+	// lbControllerClusterTag is set by the AWS Load Balancer Controller;
+	// the in-tree cloud provider sets kubernetes.io/cluster/<name>
+	lbControllerClusterTag = "elbv2.k8s.aws/cluster"
+)
+
+// Object is a Kubernetes resource that owns cloud resources
+type Object struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o Object) String() string {
+	return fmt.Sprintf("%s %s/%s", o.Kind, o.Namespace, o.Name)
+}
+
+func loadBalancerServices(services []corev1.Service) []Object {
+	var objects []Object
+	for _, service := range services {
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			objects = append(objects, Object{Kind: "Service", Namespace: service.Namespace, Name: service.Name})
+		}
+	}
+	return objects
+}
+
+func ingresses(items []networkingv1.Ingress) []Object {
+	var objects []Object
+	for _, ingress := range items {
+		objects = append(objects, Object{Kind: "Ingress", Namespace: ingress.Namespace, Name: ingress.Name})
+	}
+	return objects
+}
+
+// FindE returns the LoadBalancer services and ingresses in every namespace
+func FindE(t testing.TestingT, kubectl *k8s.KubectlOptions) ([]Object, error) {
+	client, err := k8s.GetKubernetesClientFromOptionsE(t, kubectl)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	services, err := client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ingressList, err := client.NetworkingV1().Ingresses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return append(loadBalancerServices(services.Items), ingresses(ingressList.Items)...), nil
+}
+
+// DeleteE deletes the LoadBalancer services and ingresses and waits for
+// them to go away. Their finalizers hold them until the controller that
+// created the load balancers has deleted them.
+func DeleteE(t testing.TestingT, kubectl *k8s.KubectlOptions) error {
+	objects, err := FindE(t, kubectl)
+	if err != nil || len(objects) == 0 {
+		return err
+	}
+	client, err := k8s.GetKubernetesClientFromOptionsE(t, kubectl)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, object := range objects {
+		logger.Logf(t, "Deleting %s", object)
+		switch object.Kind {
+		case "Service":
+			err = client.CoreV1().Services(object.Namespace).Delete(ctx, object.Name, metav1.DeleteOptions{})
+		case "Ingress":
+			err = client.NetworkingV1().Ingresses(object.Namespace).Delete(ctx, object.Name, metav1.DeleteOptions{})
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting %s: %w", object, err)
+		}
+	}
+
+	_, err = retry.DoWithRetryE(t, "waiting for load balancer services and ingresses to be deleted", int(Timeout/pollInterval), pollInterval,
+		func() (string, error) {
+			remaining, err := FindE(t, kubectl)
+			if err != nil {
+				return "", err
+			}
+			if len(remaining) > 0 {
+				return "", fmt.Errorf("still present: %s", describe(remaining))
+			}
+			return "", nil
+		})
+	return err
+}
+
+func describe(objects []Object) string {
+	names := make([]string, len(objects))
+	for i, object := range objects {
+		names[i] = object.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// ownedBy reports whether a Kubernetes controller created the resource for
+// the cluster
+func ownedBy(tags map[string]string, clusterName string) bool {
+	if tags[lbControllerClusterTag] == clusterName {
+		return true
+	}
+	_, ok := tags["kubernetes.io/cluster/"+clusterName]
+	return ok
+}
+
+// controllerSecurityGroup tells the security groups the controllers create
+// for load balancers apart from the cluster and node groups EKS tags the
+// same way
+func controllerSecurityGroup(name string) bool {
+	return strings.HasPrefix(name, "k8s-")
+}
+
+func batches(names []string, size int) [][]string {
+	var out [][]string
+	for len(names) > size {
+		out = append(out, names[:size])
+		names = names[size:]
+	}
+	if len(names) > 0 {
+		out = append(out, names)
+	}
+	return out
+}
+
+// CloudResourcesE returns the load balancers and security groups
+// Kubernetes still holds in AWS for the cluster
+func CloudResourcesE(region string, clusterName string) ([]string, error) {
+	sess, err := backend.NewSessionE(region)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining []string
+
+	classic := elb.New(sess)
+	var classicNames []string
+	err = classic.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{},
+		func(page *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range page.LoadBalancerDescriptions {
+				classicNames = append(classicNames, awssdk.StringValue(lb.LoadBalancerName))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	for _, batch := range batches(classicNames, describeTagsLimit) {
+		out, err := classic.DescribeTags(&elb.DescribeTagsInput{LoadBalancerNames: awssdk.StringSlice(batch)})
+		if err != nil {
+			return nil, err
+		}
+		for _, description := range out.TagDescriptions {
+			tags := map[string]string{}
+			for _, tag := range description.Tags {
+				tags[awssdk.StringValue(tag.Key)] = awssdk.StringValue(tag.Value)
+			}
+			if ownedBy(tags, clusterName) {
+				remaining = append(remaining, "classic load balancer "+awssdk.StringValue(description.LoadBalancerName))
+			}
+		}
+	}
+
+	v2 := elbv2.New(sess)
+	var arns []string
+	err = v2.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{},
+		func(page *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range page.LoadBalancers {
+				arns = append(arns, awssdk.StringValue(lb.LoadBalancerArn))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	for _, batch := range batches(arns, describeTagsLimit) {
+		out, err := v2.DescribeTags(&elbv2.DescribeTagsInput{ResourceArns: awssdk.StringSlice(batch)})
+		if err != nil {
+			return nil, err
+		}
+		for _, description := range out.TagDescriptions {
+			tags := map[string]string{}
+			for _, tag := range description.Tags {
+				tags[awssdk.StringValue(tag.Key)] = awssdk.StringValue(tag.Value)
+			}
+			if ownedBy(tags, clusterName) {
+				remaining = append(remaining, "load balancer "+awssdk.StringValue(description.ResourceArn))
+			}
+		}
+	}
+
+	groups, err := ec2.New(sess).DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{{
+			Name:   awssdk.String("tag-key"),
+			Values: awssdk.StringSlice([]string{"kubernetes.io/cluster/" + clusterName, lbControllerClusterTag}),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups.SecurityGroups {
+		tags := map[string]string{}
+		for _, tag := range group.Tags {
+			tags[awssdk.StringValue(tag.Key)] = awssdk.StringValue(tag.Value)
+		}
+		if ownedBy(tags, clusterName) && controllerSecurityGroup(awssdk.StringValue(group.GroupName)) {
+			remaining = append(remaining, fmt.Sprintf("security group %s (%s)", awssdk.StringValue(group.GroupId), awssdk.StringValue(group.GroupName)))
+		}
+	}
+
+	sort.Strings(remaining)
+	return remaining, nil
+}
+
+// WaitForReleaseE waits until Kubernetes holds nothing in AWS for the
+// cluster
+func WaitForReleaseE(t testing.TestingT, region string, clusterName string) error {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("waiting for %s to release its AWS resources", clusterName), int(Timeout/pollInterval), pollInterval,
+		func() (string, error) {
+			remaining, err := CloudResourcesE(region, clusterName)
+			if err != nil {
+				return "", err
+			}
+			if len(remaining) > 0 {
+				return "", fmt.Errorf("still present: %s", strings.Join(remaining, ", "))
+			}
+			return "", nil
+		})
+	return err
+}
+
+// Run deletes the LoadBalancer services and ingresses and waits until the
+// load balancers and security groups behind them are gone. Failures are
+// reported without stopping the test, so the destroy after it still runs.
+func Run(t testing.TestingT, kubectl *k8s.KubectlOptions, region string, clusterName string) {
+	if err := DeleteE(t, kubectl); err != nil {
+		assert.NoError(t, err, "Cleaning up Kubernetes load balancers in %s", clusterName)
+	}
+	if err := WaitForReleaseE(t, region, clusterName); err != nil {
+		assert.NoError(t, err, "Waiting for %s to release its AWS resources", clusterName)
+	}
+}
@@ -0,0 +1,64 @@
+package k8scleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadBalancerServices(t *testing.T) {
+	service := func(namespace, name string, serviceType corev1.ServiceType) corev1.Service {
+		return corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       corev1.ServiceSpec{Type: serviceType},
+		}
+	}
+
+	objects := loadBalancerServices([]corev1.Service{
+		service("default", "kubernetes", corev1.ServiceTypeClusterIP),
+		service("smoke-abc", "smoke-lb", corev1.ServiceTypeLoadBalancer),
+		service("smoke-abc", "smoke", corev1.ServiceTypeNodePort),
+	})
+	assert.Equal(t, []Object{{Kind: "Service", Namespace: "smoke-abc", Name: "smoke-lb"}}, objects)
+
+	objects = append(objects, ingresses([]networkingv1.Ingress{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "public"}},
+	})...)
+	assert.Equal(t, "Service smoke-abc/smoke-lb, Ingress web/public", describe(objects))
+}
+
+func TestOwnedBy(t *testing.T) {
+	cases := []struct {
+		name  string
+		tags  map[string]string
+		owned bool
+	}{
+		{name: "in-tree cloud provider", tags: map[string]string{"kubernetes.io/cluster/tt-eks-test": "owned"}, owned: true},
+		{name: "load balancer controller", tags: map[string]string{"elbv2.k8s.aws/cluster": "tt-eks-test"}, owned: true},
+		{name: "other cluster", tags: map[string]string{"kubernetes.io/cluster/prod": "owned", "elbv2.k8s.aws/cluster": "prod"}},
+		{name: "untagged", tags: map[string]string{}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.owned, ownedBy(tc.tags, "tt-eks-test"))
+		})
+	}
+}
+
+func TestControllerSecurityGroup(t *testing.T) {
+	assert.True(t, controllerSecurityGroup("k8s-elb-a1b2c3"))
+	assert.True(t, controllerSecurityGroup("k8s-traffic-tteks-0a1b2c"))
+	assert.False(t, controllerSecurityGroup("eks-cluster-sg-tt-eks-test-123"))
+	assert.False(t, controllerSecurityGroup("tt-eks-test-node-2024"))
+}
+
+func TestBatches(t *testing.T) {
+	assert.Empty(t, batches(nil, 20))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, batches([]string{"a", "b", "c", "d", "e"}, 2))
+	assert.Equal(t, [][]string{{"a", "b"}}, batches([]string{"a", "b"}, 2))
+}
@@ -0,0 +1,257 @@
+// Package labels checks the metadata every resource needs, whichever cloud
+// it lives in. Required keys are named once, as Keys, and spelled the way
+// each cloud expects: AWS and Azure tags in PascalCase, GCP labels in lower
+// snake case with the stricter value rules GCP enforces. One compliance
+// test can then plan modules for all three clouds and hold them to the
+// same standard.
+package labels
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/tfshow"
+)
+
+// Key is a piece of required metadata, independent of how a cloud spells it
+type Key string
+
+const (
+	Environment Key = "environment"
+	Owner       Key = "owner"
+	CostCenter  Key = "cost-center"
+	Project     Key = "project"
+)
+
+// Required is the metadata every resource in every cloud needs
+var Required = []Key{Environment, Owner, CostCenter}
+
+// Cloud is how one provider stores metadata on resources
+type Cloud struct {
+	Name string
+
+	// prefix identifies the provider's resource types
+	prefix string
+
+	// attributes hold the metadata, most complete first. The first one
+	// present on a resource wins.
+	attributes []string
+
+	// spell turns a key into the cloud's spelling of it
+	spell func(Key) string
+
+	// validValue reports whether the cloud accepts a value; nil accepts
+	// anything
+	validValue func(string) bool
+}
+
+// gcpValue is what GCP accepts as a label value
+var gcpValue = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+
+var (
+	// AWS tags, including the provider's default_tags through tags_all
+	AWS = Cloud{
+		Name:       "aws",
+		prefix:     "aws_",
+		attributes: []string{"tags_all", "tags"},
+		spell:      pascalCase,
+	}
+
+	// Azure tags
+	Azure = Cloud{
+		Name:       "azure",
+		prefix:     "azurerm_",
+		attributes: []string{"tags"},
+		spell:      pascalCase,
+	}
+
+	// GCP labels. effective_labels includes the provider's default labels;
+	// GKE clusters keep theirs in resource_labels.
+	GCP = Cloud{
+		Name:       "gcp",
+		prefix:     "google_",
+		attributes: []string{"effective_labels", "labels", "resource_labels"},
+		spell:      snakeCase,
+		validValue: gcpValue.MatchString,
+	}
+
+	clouds = []Cloud{AWS, Azure, GCP}
+)
+
+// Spell returns the cloud's name for a key, e.g. "CostCenter" on AWS and
+// "cost_center" on GCP
+func (c Cloud) Spell(key Key) string {
+	return c.spell(key)
+}
+
+// Map spells out metadata for a module's tags or labels variable
+func (c Cloud) Map(values map[Key]string) map[string]string {
+	out := make(map[string]string, len(values))
+	for key, value := range values {
+		out[c.Spell(key)] = value
+	}
+	return out
+}
+
+func pascalCase(key Key) string {
+	var b strings.Builder
+	for _, part := range strings.Split(string(key), "-") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+func snakeCase(key Key) string {
+	return strings.ReplaceAll(string(key), "-", "_")
+}
+
+// CloudOf returns the cloud a resource type belongs to
+func CloudOf(resourceType string) (Cloud, bool) {
+	for _, cloud := range clouds {
+		if strings.HasPrefix(resourceType, cloud.prefix) {
+			return cloud, true
+		}
+	}
+	return Cloud{}, false
+}
+
+// Resource is a managed resource and its metadata. Labelable is false for
+// resource types that take no tags or labels, and for providers outside
+// the three clouds.
+type Resource struct {
+	Address   string
+	Cloud     Cloud
+	Labelable bool
+	Labels    map[string]string
+}
+
+// Violation is a resource missing metadata or carrying values its cloud
+// rejects
+type Violation struct {
+	Address string
+	Cloud   string
+	Missing []string
+	Invalid []string
+}
+
+func (v Violation) String() string {
+	var problems []string
+	if len(v.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(v.Missing, ", "))
+	}
+	if len(v.Invalid) > 0 {
+		problems = append(problems, "invalid values for "+strings.Join(v.Invalid, ", "))
+	}
+	return fmt.Sprintf("%s (%s): %s", v.Address, v.Cloud, strings.Join(problems, "; "))
+}
+
+// Parse reads the managed resources out of terraform show -json output,
+// for either state or a saved plan
+func Parse(data []byte) ([]Resource, error) {
+	shown, err := tfshow.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]Resource, 0, len(shown))
+	for _, resource := range shown {
+		out := Resource{Address: resource.Address}
+		if cloud, ok := CloudOf(resource.Type); ok {
+			out.Cloud = cloud
+			out.Labels, out.Labelable = metadata(cloud, resource.Values)
+		}
+		resources = append(resources, out)
+	}
+	return resources, nil
+}
+
+// metadata reads the first of the cloud's attributes with a value. An
+// attribute present but unknown until apply is skipped in favour of the
+// next.
+func metadata(cloud Cloud, values map[string]interface{}) (map[string]string, bool) {
+	labelable := false
+	for _, attribute := range cloud.attributes {
+		value, ok := values[attribute]
+		if !ok {
+			continue
+		}
+		labelable = true
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		out := map[string]string{}
+		for key, value := range m {
+			out[key] = fmt.Sprint(value)
+		}
+		return out, true
+	}
+	if labelable {
+		return map[string]string{}, true
+	}
+	return nil, false
+}
+
+// Check returns the labelable resources missing any required key or
+// carrying a required value their cloud would reject. An empty value
+// counts as missing.
+func Check(resources []Resource, required []Key) []Violation {
+	var violations []Violation
+	for _, resource := range resources {
+		if !resource.Labelable {
+			continue
+		}
+		violation := Violation{Address: resource.Address, Cloud: resource.Cloud.Name}
+		for _, key := range required {
+			name := resource.Cloud.Spell(key)
+			value := resource.Labels[name]
+			switch {
+			case value == "":
+				violation.Missing = append(violation.Missing, name)
+			case resource.Cloud.validValue != nil && !resource.Cloud.validValue(value):
+				violation.Invalid = append(violation.Invalid, name)
+			}
+		}
+		if len(violation.Missing) > 0 || len(violation.Invalid) > 0 {
+			violations = append(violations, violation)
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Address < violations[j].Address
+	})
+	return violations
+}
+
+// AssertState checks the applied state of options. With no keys given,
+// Required is used.
+func AssertState(t *testing.T, options *terraform.Options, required ...Key) {
+	assertShow(t, terraform.Show(t, options), required)
+}
+
+// AssertPlan plans options and checks what would be created
+func AssertPlan(t *testing.T, options *terraform.Options, required ...Key) {
+	planOptions := *options
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "labels.tfplan")
+	assertShow(t, terraform.InitAndPlanAndShow(t, &planOptions), required)
+}
+
+func assertShow(t *testing.T, show string, required []Key) {
+	if len(required) == 0 {
+		required = Required
+	}
+	resources, err := Parse([]byte(show))
+	require.NoError(t, err)
+	for _, violation := range Check(resources, required) {
+		assert.Fail(t, "Resource is missing required metadata", violation.String())
+	}
+}
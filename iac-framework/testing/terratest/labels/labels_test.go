@@ -0,0 +1,80 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpell(t *testing.T) {
+	assert.Equal(t, "CostCenter", AWS.Spell(CostCenter))
+	assert.Equal(t, "CostCenter", Azure.Spell(CostCenter))
+	assert.Equal(t, "cost_center", GCP.Spell(CostCenter))
+	assert.Equal(t, "Environment", AWS.Spell(Environment))
+	assert.Equal(t, "environment", GCP.Spell(Environment))
+
+	assert.Equal(t, map[string]string{"owner": "platform", "cost_center": "cc-42"},
+		GCP.Map(map[Key]string{Owner: "platform", CostCenter: "cc-42"}))
+}
+
+func TestCloudOf(t *testing.T) {
+	for resourceType, expected := range map[string]string{
+		"aws_vpc":                    "aws",
+		"azurerm_kubernetes_cluster": "azure",
+		"google_container_cluster":   "gcp",
+	} {
+		cloud, ok := CloudOf(resourceType)
+		require.True(t, ok, resourceType)
+		assert.Equal(t, expected, cloud.Name)
+	}
+	_, ok := CloudOf("helm_release")
+	assert.False(t, ok)
+}
+
+const samplePlan = `{
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.this", "mode": "managed", "type": "aws_vpc",
+         "values": {"tags": {"Environment": "test", "Owner": "platform", "CostCenter": "cc-42"}, "tags_all": null}},
+        {"address": "azurerm_resource_group.main", "mode": "managed", "type": "azurerm_resource_group",
+         "values": {"tags": {"Environment": "test", "Owner": "platform"}}},
+        {"address": "azurerm_subnet.internal", "mode": "managed", "type": "azurerm_subnet",
+         "values": {"name": "internal"}},
+        {"address": "google_container_cluster.primary", "mode": "managed", "type": "google_container_cluster",
+         "values": {"resource_labels": {"environment": "test", "owner": "Platform Team", "cost_center": "cc-42"}}},
+        {"address": "google_service_account.kubernetes", "mode": "managed", "type": "google_service_account",
+         "values": {"account_id": "sa"}},
+        {"address": "helm_release.istio", "mode": "managed", "type": "helm_release",
+         "values": {"name": "istio"}}
+      ]
+    }
+  }
+}`
+
+func TestCheck(t *testing.T) {
+	resources, err := Parse([]byte(samplePlan))
+	require.NoError(t, err)
+
+	violations := Check(resources, Required)
+
+	assert.Equal(t, []Violation{
+		{Address: "azurerm_resource_group.main", Cloud: "azure", Missing: []string{"CostCenter"}},
+		{Address: "google_container_cluster.primary", Cloud: "gcp", Invalid: []string{"owner"}},
+	}, violations, "Unlabelable resources and other providers should be skipped; tags should stand in for unknown tags_all")
+	assert.Equal(t, "google_container_cluster.primary (gcp): invalid values for owner", violations[1].String())
+}
+
+func TestMetadataPrefersMostCompleteAttribute(t *testing.T) {
+	labels, ok := metadata(AWS, map[string]interface{}{
+		"tags":     map[string]interface{}{"Owner": "team"},
+		"tags_all": map[string]interface{}{"Owner": "team", "Environment": "test"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"Owner": "team", "Environment": "test"}, labels)
+
+	labels, ok = metadata(GCP, map[string]interface{}{"labels": nil})
+	assert.True(t, ok, "A resource with an unset labels argument can still be labelled")
+	assert.Empty(t, labels)
+}
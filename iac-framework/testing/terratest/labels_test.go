@@ -0,0 +1,124 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/labels"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// TestModulesCarryRequiredMetadata plans the AWS modules and the Azure and
+// GCP cluster stacks with the same required metadata, spelled the way each
+// cloud expects, and asserts every resource that takes tags or labels ends
+// up with all of it. Clouds without credentials in the environment are
+// skipped.
+func TestModulesCarryRequiredMetadata(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the Azure and GCP stacks and the EC2 subnet lookup need real clouds")
+
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC())
+	metadata := map[labels.Key]string{
+		labels.Environment: "test",
+		labels.Project:     "terratest",
+		labels.Owner:       "infrastructure-team",
+		labels.CostCenter:  "platform-testing",
+	}
+
+	cases := []struct {
+		name string
+
+		// requires names an environment variable the cloud needs
+		requires string
+
+		options func(t *testing.T, name string) *terraform.Options
+	}{
+		{
+			name: "aws/vpc",
+			options: func(t *testing.T, name string) *terraform.Options {
+				return &terraform.Options{
+					TerraformDir: harness.ModuleDir(t, "aws/vpc"),
+					Vars: map[string]interface{}{
+						"project_name":            name,
+						"environment":             "test",
+						"enable_database_subnets": true,
+						"tags":                    labels.AWS.Map(metadata),
+					},
+					EnvVars: map[string]string{"AWS_DEFAULT_REGION": awsRegion},
+				}
+			},
+		},
+		{
+			name: "aws/ec2",
+			options: func(t *testing.T, name string) *terraform.Options {
+				// The EC2 module looks its subnet up while planning
+				defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+				defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+				require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
+
+				return &terraform.Options{
+					TerraformDir: harness.ModuleDir(t, "aws/ec2"),
+					Vars: map[string]interface{}{
+						"project_name":    name,
+						"environment":     "test",
+						"subnet_id":       defaultSubnets[0],
+						"create_iam_role": true,
+						"tags":            labels.AWS.Map(metadata),
+					},
+					EnvVars: map[string]string{"AWS_DEFAULT_REGION": awsRegion},
+				}
+			},
+		},
+		{
+			name:     "azure",
+			requires: "ARM_SUBSCRIPTION_ID",
+			options: func(t *testing.T, name string) *terraform.Options {
+				return &terraform.Options{
+					TerraformDir: harness.ClusterDir(t, "azure"),
+					Vars: map[string]interface{}{
+						"project_name": name,
+						"environment":  "test",
+						"tags":         labels.Azure.Map(metadata),
+					},
+				}
+			},
+		},
+		{
+			name:     "gcp",
+			requires: "GOOGLE_CLOUD_PROJECT",
+			options: func(t *testing.T, name string) *terraform.Options {
+				return &terraform.Options{
+					TerraformDir: harness.ClusterDir(t, "gcp"),
+					Vars: map[string]interface{}{
+						"gcp_project_id": os.Getenv("GOOGLE_CLOUD_PROJECT"),
+						"project_name":   name,
+						"environment":    "test",
+						"labels":         labels.GCP.Map(metadata),
+					},
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if tc.requires != "" && os.Getenv(tc.requires) == "" {
+				t.Skipf("%s is not set", tc.requires)
+			}
+
+			name := fmt.Sprintf("labels-%s", strings.ToLower(random.UniqueId()))
+			labels.AssertPlan(t, tc.options(t, name))
+		})
+	}
+}
@@ -0,0 +1,280 @@
+// Package lockcheck verifies that every module's .terraform.lock.hcl pins
+// the same provider builds. Modules that drift apart end up tested against
+// one provider release and consumed with another, which is how "works in CI"
+// turns into a production surprise.
+package lockcheck
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// LockFileName is the dependency lock file terraform init writes
+const LockFileName = ".terraform.lock.hcl"
+
+// Provider is one provider entry from a lock file
+type Provider struct {
+	Source      string   `hcl:"source,label"`
+	Version     string   `hcl:"version"`
+	Constraints string   `hcl:"constraints,optional"`
+	Hashes      []string `hcl:"hashes,optional"`
+}
+
+type lockFile struct {
+	Providers []Provider `hcl:"provider,block"`
+}
+
+// Policy describes what a consistent set of lock files looks like
+type Policy struct {
+	// Constraints maps a provider source address to the versions every
+	// module is allowed to lock, e.g. "registry.terraform.io/hashicorp/aws"
+	// to "~> 5.0". Providers without an entry are only checked for drift.
+	Constraints map[string]string
+
+	// AllowVersionDrift permits modules to lock different versions of the
+	// same provider as long as each satisfies Constraints
+	AllowVersionDrift bool
+}
+
+// DefaultPolicy matches the provider requirements of the modules in this repo
+var DefaultPolicy = Policy{
+	Constraints: map[string]string{
+		"registry.terraform.io/hashicorp/aws": "~> 5.0",
+	},
+}
+
+// Finding is a single policy violation
+type Finding struct {
+	Module   string
+	Provider string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Module, f.Provider, f.Message)
+}
+
+// Parse reads the providers out of a lock file's contents
+func Parse(filename string, src []byte) ([]Provider, error) {
+	var lock lockFile
+	if err := hclsimple.Decode(filename, src, nil, &lock); err != nil {
+		return nil, err
+	}
+	return lock.Providers, nil
+}
+
+// Load finds every lock file under root and returns its providers keyed by
+// the module directory relative to root. Directories named .terraform are
+// skipped since they hold downloaded copies of other modules.
+func Load(root string) (map[string][]Provider, error) {
+	locks := map[string][]Provider{}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || entry.Name() != LockFileName {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		providers, err := Parse(path, src)
+		if err != nil {
+			return err
+		}
+
+		module, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		locks[filepath.ToSlash(module)] = providers
+		return nil
+	})
+	return locks, err
+}
+
+// Unlocked returns the module directories under root, relative to it, that
+// have .tf files but no lock file. Load and Check only see the lock files
+// that exist, so a module that never had one would pass them unnoticed.
+func Unlocked(root string) ([]string, error) {
+	var unlocked []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if entry.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+
+		configs, err := filepath.Glob(filepath.Join(path, "*.tf"))
+		if err != nil || len(configs) == 0 {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(path, LockFileName)); err == nil {
+			return nil
+		}
+		module, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		unlocked = append(unlocked, filepath.ToSlash(module))
+		return nil
+	})
+	return unlocked, err
+}
+
+// Check compares the lock files of all modules against the policy. Findings
+// are sorted by module then provider so output is stable between runs.
+func Check(locks map[string][]Provider, policy Policy) []Finding {
+	var findings []Finding
+
+	// bySource groups module locks per provider so drift can be compared
+	bySource := map[string]map[string]Provider{}
+	for module, providers := range locks {
+		for _, provider := range providers {
+			if bySource[provider.Source] == nil {
+				bySource[provider.Source] = map[string]Provider{}
+			}
+			bySource[provider.Source][module] = provider
+
+			if finding, ok := checkConstraint(module, provider, policy); !ok {
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	for source, modules := range bySource {
+		findings = append(findings, checkDrift(source, modules, policy)...)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Module != findings[j].Module {
+			return findings[i].Module < findings[j].Module
+		}
+		if findings[i].Provider != findings[j].Provider {
+			return findings[i].Provider < findings[j].Provider
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings
+}
+
+func checkConstraint(module string, provider Provider, policy Policy) (Finding, bool) {
+	raw, ok := policy.Constraints[provider.Source]
+	if !ok {
+		return Finding{}, true
+	}
+
+	finding := Finding{Module: module, Provider: provider.Source}
+	constraints, err := version.NewConstraint(raw)
+	if err != nil {
+		finding.Message = fmt.Sprintf("policy constraint %q is invalid: %v", raw, err)
+		return finding, false
+	}
+	locked, err := version.NewVersion(provider.Version)
+	if err != nil {
+		finding.Message = fmt.Sprintf("locked version %q is invalid: %v", provider.Version, err)
+		return finding, false
+	}
+	if !constraints.Check(locked) {
+		finding.Message = fmt.Sprintf("locked version %s does not satisfy policy %s", provider.Version, raw)
+		return finding, false
+	}
+	return Finding{}, true
+}
+
+// checkDrift flags modules that disagree with the most common version of a
+// provider, and modules on the same version whose hashes differ
+func checkDrift(source string, modules map[string]Provider, policy Policy) []Finding {
+	var findings []Finding
+
+	counts := map[string]int{}
+	for _, provider := range modules {
+		counts[provider.Version]++
+	}
+	expected := mostCommon(counts)
+
+	if !policy.AllowVersionDrift {
+		for module, provider := range modules {
+			if provider.Version != expected {
+				findings = append(findings, Finding{
+					Module:   module,
+					Provider: source,
+					Message:  fmt.Sprintf("locks version %s but other modules lock %s", provider.Version, expected),
+				})
+			}
+		}
+	}
+
+	// Hashes only mean the same thing within a version
+	byVersion := map[string]map[string]Provider{}
+	for module, provider := range modules {
+		if byVersion[provider.Version] == nil {
+			byVersion[provider.Version] = map[string]Provider{}
+		}
+		byVersion[provider.Version][module] = provider
+	}
+	for providerVersion, group := range byVersion {
+		union := map[string]bool{}
+		for _, provider := range group {
+			for _, hash := range provider.Hashes {
+				union[hash] = true
+			}
+		}
+		for module, provider := range group {
+			if missing := len(union) - countUnique(provider.Hashes); missing > 0 {
+				findings = append(findings, Finding{
+					Module:   module,
+					Provider: source,
+					Message:  fmt.Sprintf("is missing %d hash(es) for %s that other modules record; run terraform providers lock for every platform", missing, providerVersion),
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+// mostCommon picks the version locked by the most modules, preferring the
+// higher version on a tie so the finding points at the module to upgrade
+func mostCommon(counts map[string]int) string {
+	var best string
+	for candidate, count := range counts {
+		if best == "" || count > counts[best] || (count == counts[best] && newer(candidate, best)) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+func newer(a, b string) bool {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b) > 0
+	}
+	return va.GreaterThan(vb)
+}
+
+func countUnique(hashes []string) int {
+	seen := map[string]bool{}
+	for _, hash := range hashes {
+		seen[hash] = true
+	}
+	return len(seen)
+}
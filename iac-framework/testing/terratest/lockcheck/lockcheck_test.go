@@ -0,0 +1,166 @@
+package lockcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const awsSource = "registry.terraform.io/hashicorp/aws"
+
+const sampleLockFile = `
+# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:linux",
+    "zh:darwin",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+}
+`
+
+func TestParse(t *testing.T) {
+	providers, err := Parse(LockFileName, []byte(sampleLockFile))
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+
+	assert.Equal(t, awsSource, providers[0].Source)
+	assert.Equal(t, "5.31.0", providers[0].Version)
+	assert.Equal(t, "~> 5.0", providers[0].Constraints)
+	assert.Equal(t, []string{"h1:linux", "zh:darwin"}, providers[0].Hashes)
+	assert.Empty(t, providers[1].Hashes)
+}
+
+func TestParseRejectsInvalidLockFile(t *testing.T) {
+	_, err := Parse(LockFileName, []byte(`provider "registry.terraform.io/hashicorp/aws" {}`))
+	assert.Error(t, err, "version is required")
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	writeLockFile(t, filepath.Join(root, "aws", "vpc"), sampleLockFile)
+	writeLockFile(t, filepath.Join(root, "aws", "vpc", ".terraform", "modules", "nested"), sampleLockFile)
+
+	locks, err := Load(root)
+	require.NoError(t, err)
+	assert.Len(t, locks, 1, "Lock files inside .terraform should be skipped")
+	assert.Len(t, locks["aws/vpc"], 2)
+}
+
+func TestUnlocked(t *testing.T) {
+	root := t.TempDir()
+	writeLockFile(t, filepath.Join(root, "aws", "vpc"), sampleLockFile)
+	for _, dir := range []string{"aws/vpc", "aws/ec2", "aws/ec2/.terraform/modules/nested"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, dir, "main.tf"), nil, 0o644))
+	}
+
+	unlocked, err := Unlocked(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws/ec2"}, unlocked, "Only modules with .tf files and no lock file should be listed")
+}
+
+func TestCheck(t *testing.T) {
+	aws := func(version string, hashes ...string) Provider {
+		return Provider{Source: awsSource, Version: version, Hashes: hashes}
+	}
+
+	cases := []struct {
+		name     string
+		locks    map[string][]Provider
+		policy   Policy
+		findings []Finding
+	}{
+		{
+			name: "consistent modules",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("5.31.0", "h1:a", "zh:b")},
+				"aws/ec2": {aws("5.31.0", "h1:a", "zh:b")},
+			},
+			policy: DefaultPolicy,
+		},
+		{
+			name: "version drift",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("5.31.0", "h1:a")},
+				"aws/ec2": {aws("5.31.0", "h1:a")},
+				"aws/rds": {aws("5.20.0", "h1:c")},
+			},
+			policy: DefaultPolicy,
+			findings: []Finding{
+				{Module: "aws/rds", Provider: awsSource, Message: "locks version 5.20.0 but other modules lock 5.31.0"},
+			},
+		},
+		{
+			name: "version drift allowed",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("5.31.0", "h1:a")},
+				"aws/rds": {aws("5.20.0", "h1:c")},
+			},
+			policy: Policy{AllowVersionDrift: true},
+		},
+		{
+			name: "tie prefers newer version",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("5.31.0", "h1:a")},
+				"aws/ec2": {aws("5.30.0", "h1:b")},
+			},
+			policy: Policy{},
+			findings: []Finding{
+				{Module: "aws/ec2", Provider: awsSource, Message: "locks version 5.30.0 but other modules lock 5.31.0"},
+			},
+		},
+		{
+			name: "missing platform hashes",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("5.31.0", "h1:a", "zh:b")},
+				"aws/ec2": {aws("5.31.0", "h1:a")},
+			},
+			policy: DefaultPolicy,
+			findings: []Finding{
+				{Module: "aws/ec2", Provider: awsSource, Message: "is missing 1 hash(es) for 5.31.0 that other modules record; run terraform providers lock for every platform"},
+			},
+		},
+		{
+			name: "version outside policy",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("4.67.0", "h1:a")},
+			},
+			policy: DefaultPolicy,
+			findings: []Finding{
+				{Module: "aws/vpc", Provider: awsSource, Message: "locked version 4.67.0 does not satisfy policy ~> 5.0"},
+			},
+		},
+		{
+			name: "invalid policy constraint",
+			locks: map[string][]Provider{
+				"aws/vpc": {aws("5.31.0", "h1:a")},
+			},
+			policy: Policy{Constraints: map[string]string{awsSource: "five"}},
+			findings: []Finding{
+				{Module: "aws/vpc", Provider: awsSource, Message: `policy constraint "five" is invalid: Malformed constraint: five`},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.findings, Check(tc.locks, tc.policy))
+		})
+	}
+}
+
+func writeLockFile(t *testing.T, dir string, contents string) {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, LockFileName), []byte(contents), 0o644))
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/lockcheck"
+)
+
+// TestProviderLockfilesConsistent checks the modules' .terraform.lock.hcl
+// files agree with the lock policy. It needs no cloud access. Modules that
+// have not been locked yet skip the test rather than fail it, since writing
+// the files needs terraform and network access.
+func TestProviderLockfilesConsistent(t *testing.T) {
+	t.Parallel()
+
+	locks, err := lockcheck.Load(harness.ModulesRoot)
+	require.NoError(t, err)
+
+	for _, finding := range lockcheck.Check(locks, lockcheck.DefaultPolicy) {
+		t.Error(finding)
+	}
+
+	unlocked, err := lockcheck.Unlocked(harness.ModulesRoot)
+	require.NoError(t, err)
+	if len(unlocked) > 0 {
+		t.Skipf("%d module(s) have no %s: %s; run make lock-providers and commit the files",
+			len(unlocked), lockcheck.LockFileName, strings.Join(unlocked, ", "))
+	}
+}
@@ -0,0 +1,20 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/company/iac-framework/testing/accountvend"
+	"github.com/company/iac-framework/testing/harness"
+)
+
+// TestMain destroys whatever the running tests have applied if the suite is
+// interrupted, since their deferred destroys never get the chance. With
+// TEST_ACCOUNT_POOL_OU set the whole run happens in a leased account, which
+// is swept and released even when the run is interrupted.
+func TestMain(m *testing.M) {
+	os.Exit(accountvend.Run(func(release func()) int {
+		harness.OnInterrupt(release)
+		return harness.RunWithInterruptHandling(m)
+	}))
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/probe"
+	"github.com/company/iac-framework/testing/workload"
+)
+
+// meshKubeconfigEnvVar points the mesh suite at a cluster to install Istio
+// into. The cluster must not run Istio already; the module owns
+// istio-system.
+const meshKubeconfigEnvVar = "TEST_MESH_KUBECONFIG"
+
+// TestIstioMTLS installs the shared Istio module into an existing cluster
+// and checks mutual TLS end to end: the mesh-wide PeerAuthentication and
+// DestinationRule carry the configured modes, sidecars are issued workload
+// certificates, traffic between meshed pods flows and plaintext from
+// outside the mesh is refused. The module has no virtual services or
+// subsets, so traffic shifting between app versions is not covered.
+func TestIstioMTLS(t *testing.T) {
+	t.Parallel()
+	kubeconfigPath := os.Getenv(meshKubeconfigEnvVar)
+	if kubeconfigPath == "" {
+		t.Skipf("%s is not set", meshKubeconfigEnvVar)
+	}
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		t.Skip("The mesh checks need kubectl")
+	}
+
+	uniqueId := strings.ToLower(random.UniqueId())
+	kubectl := k8s.NewKubectlOptions("", kubeconfigPath, "default")
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: harness.ClusterDir(t, "shared/modules/istio"),
+		Vars: map[string]interface{}{
+			"cluster_name":         fmt.Sprintf("tt-%s", uniqueId),
+			"mtls_mode":            "STRICT",
+			"gateway_service_type": "ClusterIP",
+		},
+		EnvVars: map[string]string{
+			"KUBE_CONFIG_PATH": kubeconfigPath,
+		},
+	}
+
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+
+	// kubernetes_manifest needs the Istio CRDs to plan, so the charts that
+	// install them go first
+	chartsOptions := *terraformOptions
+	chartsOptions.Targets = []string{"helm_release.istiod"}
+	harness.InitAndApply(t, &chartsOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	namespace := terraform.Output(t, terraformOptions, "namespace")
+	system := k8s.NewKubectlOptions("", kubeconfigPath, namespace)
+	mode, err := k8s.RunKubectlAndGetOutputE(t, system, "get", "peerauthentication", "default", "-o", "jsonpath={.spec.mtls.mode}")
+	require.NoError(t, err)
+	assert.Equal(t, "STRICT", mode, "Mesh-wide PeerAuthentication should enforce mTLS")
+	tlsMode, err := k8s.RunKubectlAndGetOutputE(t, system, "get", "destinationrule", "default", "-o", "jsonpath={.spec.trafficPolicy.tls.mode}")
+	require.NoError(t, err)
+	assert.Equal(t, "ISTIO_MUTUAL", tlsMode, "Mesh-wide DestinationRule should originate Istio mTLS")
+
+	// The sample workload, in a namespace whose pods get a sidecar
+	meshed := k8s.NewKubectlOptions("", kubeconfigPath, "mesh-"+uniqueId)
+	k8s.CreateNamespace(t, kubectl, meshed.Namespace)
+	defer k8s.DeleteNamespace(t, kubectl, meshed.Namespace)
+	k8s.RunKubectl(t, kubectl, "label", "namespace", meshed.Namespace, "istio-injection=enabled")
+	k8s.KubectlApplyFromString(t, meshed, workload.Manifest(workload.Image))
+	k8s.WaitUntilDeploymentAvailable(t, meshed, workload.Name, 30, 10*time.Second)
+
+	pods := k8s.ListPods(t, meshed, metav1.ListOptions{LabelSelector: "app=" + workload.Name})
+	require.NotEmpty(t, pods, "The workload should have pods")
+	pod := pods[0].Name
+
+	// istiod signs a certificate for the pod's service account identity
+	certs, err := k8s.RunKubectlAndGetOutputE(t, meshed, "exec", pod, "-c", "istio-proxy", "--", "pilot-agent", "request", "GET", "certs")
+	require.NoError(t, err)
+	assert.Contains(t, certs, fmt.Sprintf("spiffe://cluster.local/ns/%s/sa/default", meshed.Namespace),
+		"The sidecar should hold a workload certificate")
+
+	// Between sidecars the request is upgraded to mTLS and goes through
+	serviceURL := fmt.Sprintf("http://%s.%s.svc.cluster.local", workload.Name, meshed.Namespace)
+	page, err := k8s.RunKubectlAndGetOutputE(t, meshed, "exec", pod, "-c", "web", "--", "wget", "-qO-", serviceURL)
+	require.NoError(t, err)
+	assert.Contains(t, page, "Welcome to nginx", "Meshed pods should reach each other")
+
+	// A pod without a sidecar only speaks plaintext, which STRICT refuses
+	image := probe.Image()
+	if image == "" {
+		t.Logf("%s is not set, skipping the plaintext check", probe.ImageEnvVar)
+		return
+	}
+	plain := k8s.NewKubectlOptions("", kubeconfigPath, "plain-"+uniqueId)
+	k8s.CreateNamespace(t, kubectl, plain.Namespace)
+	defer k8s.DeleteNamespace(t, kubectl, plain.Namespace)
+	result := probe.InCluster(t, plain, image, "http-get", map[string]string{
+		"url":      serviceURL,
+		"contains": "Welcome to nginx",
+	})
+	assert.False(t, result.Passed, "Plaintext from outside the mesh should be refused: %s", result)
+}
@@ -0,0 +1,232 @@
+// Package modvars reads the input variables a module declares so suites can
+// generate variable sets from the module itself instead of hand maintaining
+// one map per module.
+package modvars
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Variable is one variable block from a module
+type Variable struct {
+	Name     string
+	Type     cty.Type
+	Required bool
+	Nullable bool
+}
+
+// variableBlock only decodes what Variable needs; description, validation
+// and sensitive are left in Remain
+type variableBlock struct {
+	Name     string         `hcl:"name,label"`
+	Type     hcl.Expression `hcl:"type,optional"`
+	Default  hcl.Expression `hcl:"default,optional"`
+	Nullable *bool          `hcl:"nullable,optional"`
+	Remain   hcl.Body       `hcl:",remain"`
+}
+
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "variable", LabelNames: []string{"name"}}},
+}
+
+// Load parses every .tf file in dir and returns its variables sorted by name
+func Load(dir string) ([]Variable, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	var variables []Variable
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := parser.ParseHCL(src, path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		content, _, diags := file.Body.PartialContent(moduleSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for _, block := range content.Blocks {
+			variable, err := decodeVariable(block)
+			if err != nil {
+				return nil, err
+			}
+			variables = append(variables, variable)
+		}
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables, nil
+}
+
+func decodeVariable(block *hcl.Block) (Variable, error) {
+	var decoded variableBlock
+	if diags := gohcl.DecodeBody(block.Body, nil, &decoded); diags.HasErrors() {
+		return Variable{}, diags
+	}
+
+	variable := Variable{
+		Name:     block.Labels[0],
+		Type:     cty.DynamicPseudoType,
+		Required: isUnset(decoded.Default),
+		Nullable: decoded.Nullable == nil || *decoded.Nullable,
+	}
+	if !isUnset(decoded.Type) {
+		ty, diags := typeexpr.TypeConstraint(decoded.Type)
+		if diags.HasErrors() {
+			return Variable{}, fmt.Errorf("variable %q: %w", variable.Name, diags)
+		}
+		variable.Type = ty
+	}
+	return variable, nil
+}
+
+// isUnset reports whether an optional attribute was left out. gohcl fills
+// missing expression attributes with a synthetic null expression that has
+// no source range.
+func isUnset(expr hcl.Expression) bool {
+	if expr == nil {
+		return true
+	}
+	return expr.Range().Empty()
+}
+
+// Case is a named variable set to apply a module with
+type Case struct {
+	Name string
+	Vars map[string]interface{}
+}
+
+// EdgeCases builds the variable sets every module should survive: only the
+// required variables, every optional collection empty, and every nullable
+// optional variable explicitly null. required supplies values for the
+// variables without defaults.
+func EdgeCases(variables []Variable, required map[string]interface{}) ([]Case, error) {
+	for _, variable := range variables {
+		if _, ok := required[variable.Name]; variable.Required && !ok {
+			return nil, fmt.Errorf("no value supplied for required variable %q", variable.Name)
+		}
+	}
+
+	minimal := Case{Name: "minimal", Vars: copyVars(required)}
+
+	empty := Case{Name: "empty-collections", Vars: copyVars(required)}
+	for _, variable := range optional(variables, required) {
+		if value, ok := emptyValue(variable.Type); ok {
+			empty.Vars[variable.Name] = value
+		}
+	}
+
+	nulls := Case{Name: "explicit-nulls", Vars: copyVars(required)}
+	for _, variable := range optional(variables, required) {
+		if variable.Nullable {
+			nulls.Vars[variable.Name] = nil
+		}
+	}
+
+	return []Case{minimal, empty, nulls}, nil
+}
+
+// Malformed builds one case per variable and bad value on top of required:
+// empty and overlong strings, negative and huge numbers, empty lists, and
+// invalid CIDRs for variables whose name mentions cidr. A module should
+// turn each of these away with a validation error or accept it, never fail
+// somewhere inside the provider.
+func Malformed(variables []Variable, required map[string]interface{}) ([]Case, error) {
+	for _, variable := range variables {
+		if _, ok := required[variable.Name]; variable.Required && !ok {
+			return nil, fmt.Errorf("no value supplied for required variable %q", variable.Name)
+		}
+	}
+
+	var cases []Case
+	for _, variable := range variables {
+		values := malformedValues(variable)
+		kinds := make([]string, 0, len(values))
+		for kind := range values {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			vars := copyVars(required)
+			vars[variable.Name] = values[kind]
+			cases = append(cases, Case{Name: variable.Name + "/" + kind, Vars: vars})
+		}
+	}
+	return cases, nil
+}
+
+// overlongLength is past every name limit AWS has for the resources in
+// these modules
+const overlongLength = 256
+
+func malformedValues(variable Variable) map[string]interface{} {
+	cidr := strings.Contains(variable.Name, "cidr")
+	values := map[string]interface{}{}
+	switch {
+	case variable.Type == cty.String:
+		values["empty"] = ""
+		values["overlong"] = strings.Repeat("a", overlongLength)
+		if cidr {
+			values["bad-prefix"] = "10.0.0.0/33"
+			values["not-a-cidr"] = "not-a-cidr"
+		}
+	case variable.Type == cty.Number:
+		values["negative"] = -1
+		values["huge"] = 1000000000
+	case variable.Type.IsListType() || variable.Type.IsSetType():
+		values["empty"] = []interface{}{}
+		if cidr && variable.Type.ElementType() == cty.String {
+			values["not-a-cidr"] = []interface{}{"not-a-cidr"}
+		}
+	}
+	return values
+}
+
+func optional(variables []Variable, required map[string]interface{}) []Variable {
+	var out []Variable
+	for _, variable := range variables {
+		if _, ok := required[variable.Name]; !ok && !variable.Required {
+			out = append(out, variable)
+		}
+	}
+	return out
+}
+
+// emptyValue is the empty literal for collection types. Objects and tuples
+// are skipped since an empty value rarely conforms to their attributes.
+func emptyValue(ty cty.Type) (interface{}, bool) {
+	switch {
+	case ty.IsListType(), ty.IsSetType():
+		return []interface{}{}, true
+	case ty.IsMapType():
+		return map[string]interface{}{}, true
+	}
+	return nil, false
+}
+
+func copyVars(vars map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(vars))
+	for key, value := range vars {
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package modvars
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const sampleVariables = `
+variable "environment" {
+  description = "Environment name"
+  type        = string
+
+  validation {
+    condition     = contains(["dev", "prod"], var.environment)
+    error_message = "Environment must be dev or prod."
+  }
+}
+
+variable "subnet_ids" {
+  type    = list(string)
+  default = ["subnet-1"]
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "enabled" {
+  type     = bool
+  default  = true
+  nullable = false
+}
+
+variable "anything" {
+  default = null
+}
+`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(sampleVariables), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "null_resource" "this" {}`), 0o644))
+
+	variables, err := Load(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Variable{
+		{Name: "anything", Type: cty.DynamicPseudoType, Nullable: true},
+		{Name: "enabled", Type: cty.Bool},
+		{Name: "environment", Type: cty.String, Required: true, Nullable: true},
+		{Name: "subnet_ids", Type: cty.List(cty.String), Nullable: true},
+		{Name: "tags", Type: cty.Map(cty.String), Nullable: true},
+	}, variables)
+}
+
+func TestLoadModules(t *testing.T) {
+	for _, module := range []string{"aws/vpc", "aws/ec2"} {
+		variables, err := Load(filepath.Join("../../../modules", module))
+		require.NoError(t, err, module)
+		assert.NotEmpty(t, variables, module)
+	}
+}
+
+func TestEdgeCases(t *testing.T) {
+	variables := []Variable{
+		{Name: "enabled", Type: cty.Bool},
+		{Name: "environment", Type: cty.String, Required: true, Nullable: true},
+		{Name: "subnet_ids", Type: cty.List(cty.String), Nullable: true},
+		{Name: "tags", Type: cty.Map(cty.String), Nullable: true},
+	}
+
+	cases, err := EdgeCases(variables, map[string]interface{}{"environment": "dev"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Case{
+		{Name: "minimal", Vars: map[string]interface{}{"environment": "dev"}},
+		{Name: "empty-collections", Vars: map[string]interface{}{
+			"environment": "dev",
+			"subnet_ids":  []interface{}{},
+			"tags":        map[string]interface{}{},
+		}},
+		{Name: "explicit-nulls", Vars: map[string]interface{}{
+			"environment": "dev",
+			"subnet_ids":  nil,
+			"tags":        nil,
+		}},
+	}, cases)
+}
+
+func TestEdgeCasesRequiresValues(t *testing.T) {
+	_, err := EdgeCases([]Variable{{Name: "environment", Type: cty.String, Required: true}}, nil)
+	assert.EqualError(t, err, `no value supplied for required variable "environment"`)
+}
+
+func TestMalformed(t *testing.T) {
+	variables := []Variable{
+		{Name: "enabled", Type: cty.Bool},
+		{Name: "environment", Type: cty.String, Required: true, Nullable: true},
+		{Name: "instance_count", Type: cty.Number},
+		{Name: "ssh_cidr_blocks", Type: cty.List(cty.String), Nullable: true},
+	}
+
+	cases, err := Malformed(variables, map[string]interface{}{"environment": "dev"})
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(cases))
+	for _, c := range cases {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{
+		"environment/empty",
+		"environment/overlong",
+		"instance_count/huge",
+		"instance_count/negative",
+		"ssh_cidr_blocks/empty",
+		"ssh_cidr_blocks/not-a-cidr",
+	}, names)
+
+	assert.Equal(t, map[string]interface{}{"environment": "dev", "instance_count": -1}, cases[3].Vars,
+		"Each case should break one variable on top of the required values")
+	assert.Len(t, cases[1].Vars["environment"], overlongLength)
+}
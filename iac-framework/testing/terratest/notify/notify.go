@@ -0,0 +1,177 @@
+// Package notify posts a summary of a finished run to a Slack or Microsoft
+// Teams incoming webhook, so whoever is on call for infrastructure hears
+// about a failing nightly run without opening CI.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/company/iac-framework/testing/report"
+)
+
+const (
+	// WebhookURLEnvVar enables notifications when set
+	WebhookURLEnvVar = "TEST_NOTIFY_WEBHOOK_URL"
+
+	// WebhookKindEnvVar selects the payload format: slack (default) or teams
+	WebhookKindEnvVar = "TEST_NOTIFY_WEBHOOK_KIND"
+
+	// NotifyOnEnvVar is "failure" (default) to only post failed runs, or
+	// "always"
+	NotifyOnEnvVar = "TEST_NOTIFY_ON"
+
+	// RunURLEnvVar optionally links the message to the CI run
+	RunURLEnvVar = "TEST_NOTIFY_RUN_URL"
+)
+
+// Webhook kinds
+const (
+	Slack = "slack"
+	Teams = "teams"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Config says where and when to post
+type Config struct {
+	URL    string
+	Kind   string
+	Always bool
+	RunURL string
+}
+
+// ConfigFromEnv reads the configuration from the environment. ok is false
+// when notifications are not configured.
+func ConfigFromEnv() (config Config, ok bool, err error) {
+	config = Config{
+		URL:    os.Getenv(WebhookURLEnvVar),
+		Kind:   strings.ToLower(os.Getenv(WebhookKindEnvVar)),
+		RunURL: os.Getenv(RunURLEnvVar),
+	}
+	if config.URL == "" {
+		return config, false, nil
+	}
+	if config.Kind == "" {
+		config.Kind = Slack
+	}
+	if config.Kind != Slack && config.Kind != Teams {
+		return config, false, fmt.Errorf("%s must be %s or %s, got %q", WebhookKindEnvVar, Slack, Teams, config.Kind)
+	}
+	switch on := strings.ToLower(os.Getenv(NotifyOnEnvVar)); on {
+	case "", "failure":
+	case "always":
+		config.Always = true
+	default:
+		return config, false, fmt.Errorf("%s must be failure or always, got %q", NotifyOnEnvVar, on)
+	}
+	return config, true, nil
+}
+
+// Summary is what the message reports about a run
+type Summary struct {
+	Tests       int
+	FailedTests []report.TestRecord
+
+	// Leaked lists workspaces still applied when the run ended
+	Leaked []string
+
+	MonthlyCost  float64
+	CostCurrency string
+}
+
+// Summarize builds a Summary from the run's records and leftover workspaces
+func Summarize(records []report.TestRecord, leaked []string) Summary {
+	summary := Summary{Tests: len(records), Leaked: leaked}
+	for _, record := range records {
+		if record.Failed {
+			summary.FailedTests = append(summary.FailedTests, record)
+		}
+		summary.MonthlyCost += record.MonthlyCost
+		if summary.CostCurrency == "" {
+			summary.CostCurrency = record.CostCurrency
+		}
+	}
+	return summary
+}
+
+// Failed reports whether the run needs attention
+func (s Summary) Failed() bool {
+	return len(s.FailedTests) > 0 || len(s.Leaked) > 0
+}
+
+// Text renders the summary as the message body. Both Slack and Teams render
+// the *bold* and bullet markup used here.
+func (s Summary) Text(runURL string) string {
+	var b strings.Builder
+	if s.Failed() {
+		fmt.Fprintf(&b, "*Terratest run failed*: %d of %d test(s) failed", len(s.FailedTests), s.Tests)
+	} else {
+		fmt.Fprintf(&b, "*Terratest run passed*: %d test(s)", s.Tests)
+	}
+	if runURL != "" {
+		fmt.Fprintf(&b, " (%s)", runURL)
+	}
+	b.WriteString("\n")
+
+	for _, test := range s.FailedTests {
+		fmt.Fprintf(&b, "\n• %s", test.Name)
+		if test.FailureReason != "" {
+			fmt.Fprintf(&b, ": %s", firstLine(test.FailureReason))
+		}
+	}
+	if len(s.Leaked) > 0 {
+		fmt.Fprintf(&b, "\n\n*%d workspace(s) may have leaked resources*; run the sweeper with -manifest:", len(s.Leaked))
+		for _, workspace := range s.Leaked {
+			fmt.Fprintf(&b, "\n• %s", workspace)
+		}
+	}
+	if s.CostCurrency != "" {
+		fmt.Fprintf(&b, "\n\nEstimated monthly cost of the tested infrastructure: %.2f %s", s.MonthlyCost, s.CostCurrency)
+	}
+	return b.String()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// payload is the JSON body for the webhook kind. Both accept a plain text
+// field; Teams also wants a summary for the notification preview.
+func payload(kind string, text string) ([]byte, error) {
+	body := map[string]string{"text": text}
+	if kind == Teams {
+		body["@type"] = "MessageCard"
+		body["@context"] = "https://schema.org/extensions"
+		body["summary"] = firstLine(text)
+	}
+	return json.Marshal(body)
+}
+
+// Send posts the summary if the configuration asks for it
+func Send(config Config, summary Summary) error {
+	if !config.Always && !summary.Failed() {
+		return nil
+	}
+	body, err := payload(config.Kind, summary.Text(config.RunURL))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Post(config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
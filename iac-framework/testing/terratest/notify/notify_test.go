@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/report"
+)
+
+func failedRun() Summary {
+	return Summarize([]report.TestRecord{
+		{Name: "TestVPCModule", MonthlyCost: 32.4, CostCurrency: "USD"},
+		{Name: "TestEC2Module", Failed: true, FailureReason: "apply: InvalidSubnetID.NotFound\nstatus code: 400", MonthlyCost: 7.6, CostCurrency: "USD"},
+	}, []string{"TestEC2Module: /tmp/ec2"})
+}
+
+func TestSummaryText(t *testing.T) {
+	expected := "*Terratest run failed*: 1 of 2 test(s) failed (https://ci.example.com/runs/1)\n" +
+		"\n• TestEC2Module: apply: InvalidSubnetID.NotFound" +
+		"\n\n*1 workspace(s) may have leaked resources*; run the sweeper with -manifest:" +
+		"\n• TestEC2Module: /tmp/ec2" +
+		"\n\nEstimated monthly cost of the tested infrastructure: 40.00 USD"
+	assert.Equal(t, expected, failedRun().Text("https://ci.example.com/runs/1"))
+
+	passed := Summarize([]report.TestRecord{{Name: "TestVPCModule"}}, nil)
+	assert.Equal(t, "*Terratest run passed*: 1 test(s)\n", passed.Text(""))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(WebhookURLEnvVar, "")
+	_, ok, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, ok, "Notifications are off without a webhook")
+
+	t.Setenv(WebhookURLEnvVar, "https://hooks.example.com/abc")
+	t.Setenv(NotifyOnEnvVar, "always")
+	config, ok, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Config{URL: "https://hooks.example.com/abc", Kind: Slack, Always: true}, config)
+
+	t.Setenv(WebhookKindEnvVar, "discord")
+	_, _, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestSend(t *testing.T) {
+	var bodies []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		src, _ := io.ReadAll(r.Body)
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(src, &body))
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	passed := Summarize([]report.TestRecord{{Name: "TestVPCModule"}}, nil)
+	require.NoError(t, Send(Config{URL: server.URL, Kind: Slack}, passed))
+	assert.Empty(t, bodies, "Passing runs are quiet unless notifying always")
+
+	require.NoError(t, Send(Config{URL: server.URL, Kind: Teams}, failedRun()))
+	require.Len(t, bodies, 1)
+	assert.Equal(t, "MessageCard", bodies[0]["@type"])
+	assert.Equal(t, "*Terratest run failed*: 1 of 2 test(s) failed", bodies[0]["summary"])
+}
+
+func TestSendReportsWebhookErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := Send(Config{URL: server.URL, Kind: Slack}, failedRun())
+	assert.EqualError(t, err, "webhook returned 403 Forbidden")
+}
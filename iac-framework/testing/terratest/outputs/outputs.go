@@ -0,0 +1,193 @@
+// Package outputs holds the output contract of each module: the outputs
+// downstream configurations read, with their types. Renaming an output or
+// changing its type applies cleanly and only breaks the consumers, so the
+// suites check the contract after every apply.
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Kind is the shape of an output value as terraform output -json prints it
+type Kind string
+
+// Kinds. List covers lists, sets and tuples; Map covers maps and objects.
+const (
+	String Kind = "string"
+	Number Kind = "number"
+	Bool   Kind = "bool"
+	List   Kind = "list"
+	Map    Kind = "map"
+)
+
+// Output is one output a consumer may depend on. Required outputs must be
+// non-empty after an apply with default variables; the rest may be empty
+// when their feature is disabled but must still exist with the right type.
+type Output struct {
+	Name     string
+	Kind     Kind
+	Required bool
+}
+
+// Contract is the set of outputs a module promises
+type Contract []Output
+
+// VPC is the contract of aws/vpc
+var VPC = Contract{
+	{Name: "vpc_id", Kind: String, Required: true},
+	{Name: "vpc_arn", Kind: String, Required: true},
+	{Name: "vpc_cidr_block", Kind: String, Required: true},
+	{Name: "default_security_group_id", Kind: String, Required: true},
+	{Name: "igw_id", Kind: String, Required: true},
+	{Name: "azs", Kind: List, Required: true},
+	{Name: "public_subnets", Kind: List, Required: true},
+	{Name: "private_subnets", Kind: List, Required: true},
+	{Name: "public_route_table_ids", Kind: List, Required: true},
+	{Name: "private_route_table_ids", Kind: List, Required: true},
+	{Name: "database_subnets", Kind: List},
+	{Name: "nat_public_ips", Kind: List},
+	{Name: "natgw_ids", Kind: List},
+	{Name: "vpc_flow_log_id", Kind: String},
+	{Name: "vpc_endpoint_s3_id", Kind: String},
+}
+
+// EC2 is the contract of aws/ec2
+var EC2 = Contract{
+	{Name: "instance_ids", Kind: List, Required: true},
+	{Name: "instance_arns", Kind: List, Required: true},
+	{Name: "instance_private_ips", Kind: List, Required: true},
+	{Name: "instance_availability_zones", Kind: List, Required: true},
+	{Name: "instance_subnet_ids", Kind: List, Required: true},
+	{Name: "instance_public_ips", Kind: List},
+	{Name: "security_group_id", Kind: String},
+	{Name: "iam_role_arn", Kind: String},
+	{Name: "iam_instance_profile_name", Kind: String},
+	{Name: "key_pair_name", Kind: String},
+	{Name: "eip_public_ips", Kind: List},
+	{Name: "instance_tags", Kind: List},
+}
+
+// Contracts maps module paths such as "aws/vpc" to their contract
+var Contracts = map[string]Contract{
+	"aws/vpc": VPC,
+	"aws/ec2": EC2,
+}
+
+// Assert checks the applied outputs of module against its contract
+func Assert(t testing.TestingT, options *terraform.Options, module string) {
+	contract, ok := Contracts[module]
+	require.True(t, ok, "No output contract for %s", module)
+
+	var actual map[string]struct {
+		Value interface{} `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(terraform.OutputJson(t, options, "")), &actual))
+
+	values := make(map[string]interface{}, len(actual))
+	for name, output := range actual {
+		values[name] = output.Value
+	}
+	for _, problem := range Check(contract, values) {
+		assert.Fail(t, problem)
+	}
+}
+
+// Check compares output values, keyed by name, against a contract
+func Check(contract Contract, values map[string]interface{}) []string {
+	var problems []string
+	for _, output := range contract {
+		value, ok := values[output.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("Output %s is missing", output.Name))
+			continue
+		}
+		if value == nil {
+			if output.Required {
+				problems = append(problems, fmt.Sprintf("Output %s is null", output.Name))
+			}
+			continue
+		}
+		if kind := kindOf(value); kind != output.Kind {
+			problems = append(problems, fmt.Sprintf("Output %s is a %s, expected a %s", output.Name, kind, output.Kind))
+			continue
+		}
+		if output.Required && isEmpty(value) {
+			problems = append(problems, fmt.Sprintf("Output %s is empty", output.Name))
+		}
+	}
+	return problems
+}
+
+func kindOf(value interface{}) Kind {
+	switch value.(type) {
+	case string:
+		return String
+	case float64:
+		return Number
+	case bool:
+		return Bool
+	case []interface{}:
+		return List
+	case map[string]interface{}:
+		return Map
+	}
+	return Kind(fmt.Sprintf("%T", value))
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "output", LabelNames: []string{"name"}}},
+}
+
+// Declared returns the names of the outputs declared by the .tf files in
+// dir, sorted, so contracts can be checked without applying anything
+func Declared(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	var names []string
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := parser.ParseHCL(src, path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		content, _, diags := file.Body.PartialContent(moduleSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for _, block := range content.Blocks {
+			names = append(names, block.Labels[0])
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
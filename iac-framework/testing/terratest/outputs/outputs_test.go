@@ -0,0 +1,77 @@
+package outputs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	contract := Contract{
+		{Name: "vpc_id", Kind: String, Required: true},
+		{Name: "public_subnets", Kind: List, Required: true},
+		{Name: "nat_public_ips", Kind: List},
+		{Name: "vpc_flow_log_id", Kind: String},
+	}
+
+	cases := []struct {
+		name     string
+		values   map[string]interface{}
+		problems []string
+	}{
+		{
+			name: "satisfied",
+			values: map[string]interface{}{
+				"vpc_id":          "vpc-123",
+				"public_subnets":  []interface{}{"subnet-1"},
+				"nat_public_ips":  []interface{}{},
+				"vpc_flow_log_id": "",
+			},
+		},
+		{
+			name: "renamed output",
+			values: map[string]interface{}{
+				"id":              "vpc-123",
+				"public_subnets":  []interface{}{"subnet-1"},
+				"nat_public_ips":  []interface{}{},
+				"vpc_flow_log_id": "",
+			},
+			problems: []string{"Output vpc_id is missing"},
+		},
+		{
+			name: "wrong types and empty required",
+			values: map[string]interface{}{
+				"vpc_id":          "",
+				"public_subnets":  "subnet-1,subnet-2",
+				"nat_public_ips":  nil,
+				"vpc_flow_log_id": map[string]interface{}{"id": "fl-1"},
+			},
+			problems: []string{
+				"Output vpc_id is empty",
+				"Output public_subnets is a string, expected a list",
+				"Output vpc_flow_log_id is a map, expected a string",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problems, Check(contract, tc.values))
+		})
+	}
+}
+
+// TestContractsMatchModules catches a renamed output without applying
+// anything
+func TestContractsMatchModules(t *testing.T) {
+	for module, contract := range Contracts {
+		declared, err := Declared(filepath.Join("../../../modules", module))
+		require.NoError(t, err, module)
+
+		for _, output := range contract {
+			assert.Contains(t, declared, output.Name, "%s should declare output %s", module, output.Name)
+		}
+	}
+}
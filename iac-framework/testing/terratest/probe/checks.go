@@ -0,0 +1,131 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The built-in checks. Suites needing more register their own in an init
+// function of a package cmd/probe imports.
+func init() {
+	Register("http-get", httpGet)
+	Register("tcp-connect", tcpConnect)
+	Register("tcp-blocked", tcpBlocked)
+	Register("dns-resolve", dnsResolve)
+}
+
+func required(args map[string]string, name string) (string, error) {
+	value := args[name]
+	if value == "" {
+		return "", fmt.Errorf("missing argument %q", name)
+	}
+	return value, nil
+}
+
+// httpGet fetches url and expects status, 200 by default, and a body
+// containing contains, when given
+func httpGet(ctx context.Context, args map[string]string) (string, error) {
+	url, err := required(args, "url")
+	if err != nil {
+		return "", err
+	}
+	expected := http.StatusOK
+	if value, ok := args["status"]; ok {
+		if expected, err = strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("invalid status %q", value)
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode != expected {
+		return "", fmt.Errorf("GET %s returned %d, expected %d", url, response.StatusCode, expected)
+	}
+	if contains := args["contains"]; contains != "" && !strings.Contains(string(body), contains) {
+		return "", fmt.Errorf("GET %s returned a body without %q", url, contains)
+	}
+	return fmt.Sprintf("GET %s returned %d", url, response.StatusCode), nil
+}
+
+func dial(ctx context.Context, args map[string]string) (string, error) {
+	address, err := required(args, "address")
+	if err != nil {
+		return "", err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return address, err
+	}
+	return address, conn.Close()
+}
+
+// tcpConnect expects a TCP connection to address to succeed
+func tcpConnect(ctx context.Context, args map[string]string) (string, error) {
+	address, err := dial(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	return "connected to " + address, nil
+}
+
+// tcpBlocked expects a TCP connection to address to be refused or time
+// out, e.g. across a network policy or security group
+func tcpBlocked(ctx context.Context, args map[string]string) (string, error) {
+	if _, err := required(args, "address"); err != nil {
+		return "", err
+	}
+	address, err := dial(ctx, args)
+	if err == nil {
+		return "", fmt.Errorf("connected to %s, expected it to be blocked", address)
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// A name that does not resolve says nothing about the network path
+		return "", err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timed out connecting to " + address, nil
+	}
+	return fmt.Sprintf("could not connect to %s: %v", address, err), nil
+}
+
+// dnsResolve expects name to resolve, and to resolve to expect when given
+func dnsResolve(ctx context.Context, args map[string]string) (string, error) {
+	name, err := required(args, "name")
+	if err != nil {
+		return "", err
+	}
+	addresses, err := net.DefaultResolver.LookupHost(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if expected := args["expect"]; expected != "" {
+		found := false
+		for _, address := range addresses {
+			found = found || address == expected
+		}
+		if !found {
+			return "", fmt.Errorf("%s resolved to %s, expected %s", name, strings.Join(addresses, ", "), expected)
+		}
+	}
+	return fmt.Sprintf("%s resolved to %s", name, strings.Join(addresses, ", ")), nil
+}
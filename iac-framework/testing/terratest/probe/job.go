@@ -0,0 +1,135 @@
+package probe
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ImageEnvVar names the probe image clusters pull, built from
+	// cmd/probe/Dockerfile
+	ImageEnvVar = "TEST_PROBE_IMAGE"
+
+	// JobTimeout bounds a probe Job from creation to completion,
+	// including pulling the image
+	JobTimeout = 5 * time.Minute
+
+	pollInterval = 5 * time.Second
+)
+
+// Image returns the probe image from TEST_PROBE_IMAGE, empty when unset
+func Image() string {
+	return os.Getenv(ImageEnvVar)
+}
+
+// commandArgs turns a check and its arguments into the probe's command
+// line, arguments sorted so the Job spec is stable
+func commandArgs(check string, args map[string]string) []string {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	command := []string{"-check", check}
+	for _, key := range keys {
+		command = append(command, "-arg", key+"="+args[key])
+	}
+	return command
+}
+
+// jobManifest is a single attempt of the probe that cleans up after itself
+// should the test not get to
+func jobManifest(name string, image string, check string, args map[string]string) (string, error) {
+	backoffLimit := int32(0)
+	deadline := int64(JobTimeout / time.Second)
+	ttl := int32(600)
+	nonRoot := true
+	noEscalation := false
+
+	job := batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": "probe"}},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "probe"}},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:  "probe",
+						Image: image,
+						Args:  commandArgs(check, args),
+						SecurityContext: &corev1.SecurityContext{
+							RunAsNonRoot:             &nonRoot,
+							AllowPrivilegeEscalation: &noEscalation,
+						},
+					}},
+				},
+			},
+		},
+	}
+	data, err := yaml.Marshal(job)
+	return string(data), err
+}
+
+// finished reports whether the Job's single attempt is over
+func finished(job *batchv1.Job) bool {
+	return job.Status.Succeeded > 0 || job.Status.Failed > 0
+}
+
+// InCluster runs a check as a Job in the namespace of kubectl, waits for it
+// and returns its result. A failed check is a result, not an error; the
+// test only fails when the probe could not run or report.
+func InCluster(t *testing.T, kubectl *k8s.KubectlOptions, image string, check string, args map[string]string) Result {
+	require.NotEmpty(t, image, "No probe image: set %s", ImageEnvVar)
+
+	name := "probe-" + strings.ToLower(random.UniqueId())
+	manifest, err := jobManifest(name, image, check, args)
+	require.NoError(t, err)
+	k8s.KubectlApplyFromString(t, kubectl, manifest)
+	defer k8s.KubectlDeleteFromString(t, kubectl, manifest)
+
+	_, err = retry.DoWithRetryE(t, fmt.Sprintf("waiting for probe %s (%s)", name, check), int(JobTimeout/pollInterval), pollInterval,
+		func() (string, error) {
+			job, err := k8s.GetJobE(t, kubectl, name)
+			if err != nil {
+				return "", err
+			}
+			if !finished(job) {
+				return "", fmt.Errorf("probe %s still running", name)
+			}
+			return "", nil
+		})
+	require.NoError(t, err)
+
+	pods := k8s.ListPods(t, kubectl, metav1.ListOptions{LabelSelector: "job-name=" + name})
+	require.Len(t, pods, 1, "Probe %s should have run one pod", name)
+	logs := k8s.GetPodLogs(t, kubectl, &pods[0], "probe")
+	result, err := Parse(logs)
+	require.NoError(t, err, "Probe %s did not report a result", name)
+	return result
+}
+
+// AssertInCluster runs a check as a Job and asserts it passed
+func AssertInCluster(t *testing.T, kubectl *k8s.KubectlOptions, image string, check string, args map[string]string) Result {
+	result := InCluster(t, kubectl, image, check, args)
+	assert.True(t, result.Passed, "In-cluster probe: %s", result)
+	return result
+}
@@ -0,0 +1,142 @@
+// Package probe runs assertions from inside the environment under test, for
+// what the machine running the suite cannot reach: private endpoints,
+// cluster DNS, network policies seen from a pod. Assertions are registered
+// by name in a catalog compiled into the probe binary (cmd/probe), which
+// runs one check per invocation and prints its Result as a marked JSON
+// line. InCluster runs the binary as a Kubernetes Job and collects that
+// line back into the test.
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ResultPrefix marks the line carrying the result in the probe's
+	// output, so it can be picked out of pod logs
+	ResultPrefix = "PROBE_RESULT "
+
+	// DefaultTimeout bounds a check that sets no timeout argument
+	DefaultTimeout = 30 * time.Second
+)
+
+// Check is an assertion run by the probe. It returns a short description
+// of what it saw when it passes and an error when it does not.
+type Check func(ctx context.Context, args map[string]string) (string, error)
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[string]Check{}
+)
+
+// Register adds a check to the catalog. Registering a name twice is a
+// programming error and panics.
+func Register(name string, check Check) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if _, ok := catalog[name]; ok {
+		panic(fmt.Sprintf("probe: check %q registered twice", name))
+	}
+	catalog[name] = check
+}
+
+// Names lists the registered checks
+func Names() []string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (Check, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	check, ok := catalog[name]
+	return check, ok
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Check    string            `json:"check"`
+	Args     map[string]string `json:"args,omitempty"`
+	Passed   bool              `json:"passed"`
+	Message  string            `json:"message,omitempty"`
+	Duration time.Duration     `json:"duration_ns"`
+}
+
+func (r Result) String() string {
+	outcome := "failed"
+	if r.Passed {
+		outcome = "passed"
+	}
+	return fmt.Sprintf("%s %v %s: %s", r.Check, r.Args, outcome, r.Message)
+}
+
+// Run runs a check from the catalog. A "timeout" argument, as a Go
+// duration, replaces DefaultTimeout.
+func Run(name string, args map[string]string) Result {
+	result := Result{Check: name, Args: args}
+	check, ok := lookup(name)
+	if !ok {
+		result.Message = fmt.Sprintf("unknown check, expected one of %s", strings.Join(Names(), ", "))
+		return result
+	}
+
+	timeout := DefaultTimeout
+	if value, ok := args["timeout"]; ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			result.Message = fmt.Sprintf("invalid timeout %q: %v", value, err)
+			return result
+		}
+		timeout = parsed
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	started := time.Now()
+	message, err := check(ctx, args)
+	result.Duration = time.Since(started)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Passed = true
+	result.Message = message
+	return result
+}
+
+// Format renders the result line the probe prints
+func Format(result Result) (string, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return ResultPrefix + string(data), nil
+}
+
+// Parse finds the result line in the probe's output
+func Parse(output string) (Result, error) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, ResultPrefix) {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, ResultPrefix)), &result); err != nil {
+			return Result{}, fmt.Errorf("parsing probe result: %w", err)
+		}
+		return result, nil
+	}
+	return Result{}, fmt.Errorf("no probe result in output:\n%s", output)
+}
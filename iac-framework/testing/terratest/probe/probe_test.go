@@ -0,0 +1,124 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRun(t *testing.T) {
+	Register("test-echo", func(ctx context.Context, args map[string]string) (string, error) {
+		if args["fail"] != "" {
+			return "", errors.New(args["fail"])
+		}
+		return "echo " + args["say"], nil
+	})
+	assert.Contains(t, Names(), "test-echo")
+	assert.Panics(t, func() { Register("test-echo", nil) })
+
+	passed := Run("test-echo", map[string]string{"say": "hi"})
+	assert.True(t, passed.Passed)
+	assert.Equal(t, "echo hi", passed.Message)
+
+	failed := Run("test-echo", map[string]string{"fail": "boom"})
+	assert.False(t, failed.Passed)
+	assert.Equal(t, "boom", failed.Message)
+
+	unknown := Run("no-such-check", nil)
+	assert.False(t, unknown.Passed)
+	assert.Contains(t, unknown.Message, "http-get")
+
+	badTimeout := Run("test-echo", map[string]string{"timeout": "soon"})
+	assert.False(t, badTimeout.Passed)
+	assert.Contains(t, badTimeout.Message, "invalid timeout")
+}
+
+func TestFormatAndParse(t *testing.T) {
+	result := Result{Check: "tcp-connect", Args: map[string]string{"address": "db:5432"}, Passed: true, Message: "connected to db:5432"}
+	line, err := Format(result)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, ResultPrefix))
+
+	parsed, err := Parse("starting\n" + line + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, result, parsed)
+
+	_, err = Parse("panic: something else\n")
+	assert.Error(t, err)
+	_, err = Parse(ResultPrefix + "{")
+	assert.Error(t, err)
+}
+
+func TestHTTPGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "Welcome to nginx")
+	}))
+	defer server.Close()
+
+	assert.True(t, Run("http-get", map[string]string{"url": server.URL, "contains": "nginx"}).Passed)
+	assert.True(t, Run("http-get", map[string]string{"url": server.URL + "/missing", "status": "404"}).Passed)
+	assert.False(t, Run("http-get", map[string]string{"url": server.URL + "/missing"}).Passed)
+	assert.False(t, Run("http-get", map[string]string{"url": server.URL, "contains": "apache"}).Passed)
+	assert.False(t, Run("http-get", map[string]string{}).Passed)
+}
+
+func TestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	open := listener.Addr().String()
+
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := closedListener.Addr().String()
+	require.NoError(t, closedListener.Close())
+	defer listener.Close()
+
+	assert.True(t, Run("tcp-connect", map[string]string{"address": open}).Passed)
+	assert.False(t, Run("tcp-connect", map[string]string{"address": closed}).Passed)
+	assert.True(t, Run("tcp-blocked", map[string]string{"address": closed}).Passed)
+	assert.False(t, Run("tcp-blocked", map[string]string{"address": open}).Passed)
+	assert.False(t, Run("tcp-blocked", map[string]string{}).Passed)
+}
+
+func TestDNSResolve(t *testing.T) {
+	assert.True(t, Run("dns-resolve", map[string]string{"name": "localhost"}).Passed)
+	assert.False(t, Run("dns-resolve", map[string]string{"name": "localhost", "expect": "192.0.2.1"}).Passed)
+}
+
+func TestJobManifest(t *testing.T) {
+	manifest, err := jobManifest("probe-abc", "registry.example.com/probe:1", "http-get",
+		map[string]string{"url": "http://smoke", "contains": "nginx"})
+	require.NoError(t, err)
+
+	var job batchv1.Job
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &job))
+	assert.Equal(t, "Job", job.Kind)
+	assert.Equal(t, "probe-abc", job.Name)
+	assert.EqualValues(t, 0, *job.Spec.BackoffLimit, "A failed check should not be retried")
+
+	require.Len(t, job.Spec.Template.Spec.Containers, 1)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "registry.example.com/probe:1", container.Image)
+	assert.Equal(t, []string{"-check", "http-get", "-arg", "contains=nginx", "-arg", "url=http://smoke"}, container.Args)
+	assert.True(t, *container.SecurityContext.RunAsNonRoot)
+}
+
+func TestFinished(t *testing.T) {
+	assert.False(t, finished(&batchv1.Job{}))
+	assert.True(t, finished(&batchv1.Job{Status: batchv1.JobStatus{Succeeded: 1}}))
+	assert.True(t, finished(&batchv1.Job{Status: batchv1.JobStatus{Failed: 1}}))
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// TestVPCAliasedProvider calls the VPC module the way a consumer deploying
+// to a second region does, with its aws provider mapped to an aliased
+// configuration, and asserts everything lands in the alias's region. A
+// module that configures its own provider, or a data source that slips past
+// the mapping, shows up as resources in the default provider's region.
+func TestVPCAliasedProvider(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "LocalStack answers for every region from one endpoint")
+
+	primaryRegion := regions.PickRegion(t)
+	secondaryRegion := regions.PickRegion(t,
+		regions.Except(primaryRegion),
+		quotas.Requirement(quotas.VPCs(1), quotas.InternetGateways(1)))
+	uniqueId := random.UniqueId()
+
+	vars := map[string]interface{}{
+		"project_name":             fmt.Sprintf("alias-%s", uniqueId),
+		"environment":              "test",
+		"vpc_cidr":                 "10.5.0.0/16",
+		"availability_zones_count": 2,
+		"enable_nat_gateway":       false,
+		"tags": map[string]string{
+			"Environment": "test",
+			"TestType":    "provider-alias",
+		},
+	}
+	variables := make([]string, 0, len(vars))
+	for name := range vars {
+		variables = append(variables, name)
+	}
+
+	dir := harness.ModuleCallerDir(t, "aws/vpc", map[string]string{"aws": "aws.secondary"},
+		variables, []string{"vpc_id", "vpc_arn", "azs", "public_subnets"})
+	harness.WriteProviders(t, dir,
+		harness.ProviderConfig{Name: "aws", Region: primaryRegion},
+		harness.AwsAlias("secondary", secondaryRegion))
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: dir,
+		Vars:         vars,
+		EnvVars: map[string]string{
+			// Where the resources should end up, for the report and the
+			// orphan check. Both providers set their region explicitly.
+			"AWS_DEFAULT_REGION": secondaryRegion,
+		},
+	}
+
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
+	vpc := aws.GetVpcById(t, vpcId, secondaryRegion)
+	_, err := aws.GetVpcByIdE(t, vpcId, primaryRegion)
+	assert.Error(t, err, "The VPC should not be in the default provider's region")
+
+	assert.Contains(t, terraform.Output(t, terraformOptions, "vpc_arn"), ":"+secondaryRegion+":")
+
+	azs := terraform.OutputList(t, terraformOptions, "azs")
+	require.Len(t, azs, 2)
+	for _, az := range azs {
+		assert.True(t, strings.HasPrefix(az, secondaryRegion), "AZ %s should come from the aliased provider's region", az)
+	}
+
+	subnetIds := map[string]bool{}
+	for _, subnet := range vpc.Subnets {
+		subnetIds[subnet.Id] = true
+		assert.True(t, strings.HasPrefix(subnet.AvailabilityZone, secondaryRegion), "Subnet %s is in %s", subnet.Id, subnet.AvailabilityZone)
+	}
+	for _, subnetId := range terraform.OutputList(t, terraformOptions, "public_subnets") {
+		assert.True(t, subnetIds[subnetId], "Subnet %s should be in the VPC in %s", subnetId, secondaryRegion)
+	}
+}
@@ -0,0 +1,312 @@
+// Package quotas checks Service Quotas before a test applies something
+// quota-bound, like NAT gateways, Elastic IPs or instance vCPUs. A test that
+// would exceed a quota is skipped, or routed to another region through
+// regions.PickRegion, instead of failing halfway through an apply and
+// leaving partial infrastructure behind.
+package quotas
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// Need is an amount of one quota a test is about to consume
+type Need struct {
+	Name        string
+	ServiceCode string
+	QuotaCode   string
+	Amount      float64
+
+	// usage returns how much of the quota the region already uses
+	usage func(sess *session.Session) (float64, error)
+}
+
+// VPCs needs n VPCs in the region
+func VPCs(n int) Need {
+	return Need{Name: "VPCs per Region", ServiceCode: "vpc", QuotaCode: "L-F678F1CE", Amount: float64(n), usage: vpcUsage}
+}
+
+// InternetGateways needs n internet gateways in the region
+func InternetGateways(n int) Need {
+	return Need{Name: "Internet gateways per Region", ServiceCode: "vpc", QuotaCode: "L-A4707A72", Amount: float64(n), usage: internetGatewayUsage}
+}
+
+// NATGatewaysPerAZ needs n NAT gateways in each availability zone used.
+// Usage is that of the busiest zone, since the test may land in it.
+func NATGatewaysPerAZ(n int) Need {
+	return Need{Name: "NAT gateways per Availability Zone", ServiceCode: "vpc", QuotaCode: "L-FE5A380F", Amount: float64(n), usage: natGatewayUsage}
+}
+
+// ElasticIPs needs n Elastic IPs in the region, e.g. one per NAT gateway
+func ElasticIPs(n int) Need {
+	return Need{Name: "EC2-VPC Elastic IPs", ServiceCode: "ec2", QuotaCode: "L-0263D0A3", Amount: float64(n), usage: elasticIPUsage}
+}
+
+// OnDemandVCPUs needs n vCPUs of running On-Demand standard instances (A, C,
+// D, H, I, M, R, T and Z families)
+func OnDemandVCPUs(n int) Need {
+	return Need{Name: "Running On-Demand Standard instances vCPUs", ServiceCode: "ec2", QuotaCode: "L-1216C47A", Amount: float64(n), usage: onDemandVCPUUsage}
+}
+
+// Shortfall is a quota a need would exceed
+type Shortfall struct {
+	Need  Need
+	Limit float64
+	Usage float64
+}
+
+func (s Shortfall) String() string {
+	return fmt.Sprintf("%s: need %g more, %g of %g in use", s.Need.Name, s.Need.Amount, s.Usage, s.Limit)
+}
+
+// Check returns the needs that do not fit in the region's quotas
+func Check(region string, needs ...Need) ([]Shortfall, error) {
+	sess, err := backend.NewSessionE(region)
+	if err != nil {
+		return nil, err
+	}
+	client := servicequotas.New(sess)
+
+	var shortfalls []Shortfall
+	for _, need := range needs {
+		limit, err := quotaValue(client, need)
+		if err != nil {
+			return nil, fmt.Errorf("reading quota %s: %w", need.Name, err)
+		}
+		usage, err := need.usage(sess)
+		if err != nil {
+			return nil, fmt.Errorf("counting usage of %s: %w", need.Name, err)
+		}
+		if exceeds(limit, usage, need.Amount) {
+			shortfalls = append(shortfalls, Shortfall{Need: need, Limit: limit, Usage: usage})
+		}
+	}
+	return shortfalls, nil
+}
+
+func exceeds(limit float64, usage float64, amount float64) bool {
+	return usage+amount > limit
+}
+
+// Preflight skips the test if the region cannot fit what it needs. Quotas
+// that cannot be read are logged and the test goes ahead, so a role without
+// Service Quotas access does not turn into skipped tests.
+func Preflight(t *testing.T, region string, needs ...Need) {
+	if backend.IsLocalStack() {
+		return
+	}
+	shortfalls, err := Check(region, needs...)
+	if err != nil {
+		logger.Logf(t, "Skipping quota pre-flight in %s: %v", region, err)
+		return
+	}
+	if len(shortfalls) > 0 {
+		t.Skipf("Not enough quota in %s: %s", region, describe(shortfalls))
+	}
+}
+
+// Requirement lets regions.PickRegion route a test to a region with room
+// for what it needs
+func Requirement(needs ...Need) regions.Requirement {
+	names := make([]string, len(needs))
+	for i, need := range needs {
+		names[i] = need.Name
+	}
+	return regions.Requirement{
+		Name: "quota for " + strings.Join(names, ", "),
+		Check: func(t terratesting.TestingT, region string) (bool, error) {
+			shortfalls, err := Check(region, needs...)
+			if err != nil {
+				return false, err
+			}
+			if len(shortfalls) > 0 {
+				logger.Logf(t, "Not enough quota in %s: %s", region, describe(shortfalls))
+			}
+			return len(shortfalls) == 0, nil
+		},
+	}
+}
+
+func describe(shortfalls []Shortfall) string {
+	descriptions := make([]string, len(shortfalls))
+	for i, shortfall := range shortfalls {
+		descriptions[i] = shortfall.String()
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// quotaValue returns the applied quota, falling back to the AWS default for
+// quotas the account has never had changed
+func quotaValue(client *servicequotas.ServiceQuotas, need Need) (float64, error) {
+	out, err := client.GetServiceQuota(&servicequotas.GetServiceQuotaInput{
+		ServiceCode: awssdk.String(need.ServiceCode),
+		QuotaCode:   awssdk.String(need.QuotaCode),
+	})
+	if err == nil {
+		return awssdk.Float64Value(out.Quota.Value), nil
+	}
+	if _, ok := err.(*servicequotas.NoSuchResourceException); !ok {
+		return 0, err
+	}
+	defaults, err := client.GetAWSDefaultServiceQuota(&servicequotas.GetAWSDefaultServiceQuotaInput{
+		ServiceCode: awssdk.String(need.ServiceCode),
+		QuotaCode:   awssdk.String(need.QuotaCode),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return awssdk.Float64Value(defaults.Quota.Value), nil
+}
+
+func vpcUsage(sess *session.Session) (float64, error) {
+	count := 0
+	err := ec2.New(sess).DescribeVpcsPages(&ec2.DescribeVpcsInput{}, func(page *ec2.DescribeVpcsOutput, lastPage bool) bool {
+		count += len(page.Vpcs)
+		return true
+	})
+	return float64(count), err
+}
+
+func internetGatewayUsage(sess *session.Session) (float64, error) {
+	count := 0
+	err := ec2.New(sess).DescribeInternetGatewaysPages(&ec2.DescribeInternetGatewaysInput{}, func(page *ec2.DescribeInternetGatewaysOutput, lastPage bool) bool {
+		count += len(page.InternetGateways)
+		return true
+	})
+	return float64(count), err
+}
+
+func elasticIPUsage(sess *session.Session) (float64, error) {
+	out, err := ec2.New(sess).DescribeAddresses(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{{Name: awssdk.String("domain"), Values: awssdk.StringSlice([]string{"vpc"})}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return float64(len(out.Addresses)), nil
+}
+
+func natGatewayUsage(sess *session.Session) (float64, error) {
+	client := ec2.New(sess)
+
+	var subnetIds []string
+	err := client.DescribeNatGatewaysPages(&ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{{
+			Name:   awssdk.String("state"),
+			Values: awssdk.StringSlice([]string{ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable}),
+		}},
+	}, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, gateway := range page.NatGateways {
+			subnetIds = append(subnetIds, awssdk.StringValue(gateway.SubnetId))
+		}
+		return true
+	})
+	if err != nil || len(subnetIds) == 0 {
+		return 0, err
+	}
+
+	zoneOf := map[string]string{}
+	err = client.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{SubnetIds: awssdk.StringSlice(unique(subnetIds))},
+		func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+			for _, subnet := range page.Subnets {
+				zoneOf[awssdk.StringValue(subnet.SubnetId)] = awssdk.StringValue(subnet.AvailabilityZone)
+			}
+			return true
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	zones := make([]string, len(subnetIds))
+	for i, subnetId := range subnetIds {
+		zones[i] = zoneOf[subnetId]
+	}
+	return float64(busiest(zones)), nil
+}
+
+// busiest returns how often the most frequent zone occurs
+func busiest(zones []string) int {
+	counts := map[string]int{}
+	max := 0
+	for _, zone := range zones {
+		counts[zone]++
+		if counts[zone] > max {
+			max = counts[zone]
+		}
+	}
+	return max
+}
+
+func onDemandVCPUUsage(sess *session.Session) (float64, error) {
+	client := ec2.New(sess)
+
+	// instance type -> running count
+	running := map[string]int{}
+	err := client.DescribeInstancesPages(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{
+			Name:   awssdk.String("instance-state-name"),
+			Values: awssdk.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning}),
+		}},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instanceType := awssdk.StringValue(instance.InstanceType)
+				if instance.InstanceLifecycle != nil || !standardFamily(instanceType) {
+					// Spot and scheduled instances count against other quotas
+					continue
+				}
+				running[instanceType]++
+			}
+		}
+		return true
+	})
+	if err != nil || len(running) == 0 {
+		return 0, err
+	}
+
+	types := make([]string, 0, len(running))
+	for instanceType := range running {
+		types = append(types, instanceType)
+	}
+	vcpus := 0
+	err = client.DescribeInstanceTypesPages(&ec2.DescribeInstanceTypesInput{InstanceTypes: awssdk.StringSlice(types)},
+		func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+			for _, info := range page.InstanceTypes {
+				vcpus += int(awssdk.Int64Value(info.VCpuInfo.DefaultVCpus)) * running[awssdk.StringValue(info.InstanceType)]
+			}
+			return true
+		})
+	return float64(vcpus), err
+}
+
+// standardFamily reports whether an instance type counts against the
+// standard On-Demand vCPU quota
+func standardFamily(instanceType string) bool {
+	if instanceType == "" {
+		return false
+	}
+	return strings.ContainsRune("acdhimrtz", rune(instanceType[0]))
+}
+
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	return out
+}
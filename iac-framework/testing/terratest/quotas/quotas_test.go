@@ -0,0 +1,37 @@
+package quotas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExceeds(t *testing.T) {
+	assert.False(t, exceeds(5, 3, 2), "Filling a quota exactly should fit")
+	assert.True(t, exceeds(5, 4, 2))
+	assert.False(t, exceeds(5, 0, 0))
+}
+
+func TestBusiest(t *testing.T) {
+	assert.Equal(t, 0, busiest(nil))
+	assert.Equal(t, 2, busiest([]string{"us-west-2a", "us-west-2b", "us-west-2a"}))
+}
+
+func TestStandardFamily(t *testing.T) {
+	for _, instanceType := range []string{"t3.micro", "m5.large", "c7g.xlarge", "r6i.2xlarge", "z1d.large"} {
+		assert.True(t, standardFamily(instanceType), instanceType)
+	}
+	for _, instanceType := range []string{"p4d.24xlarge", "g5.xlarge", "x2idn.16xlarge", ""} {
+		assert.False(t, standardFamily(instanceType), instanceType)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	shortfalls := []Shortfall{
+		{Need: ElasticIPs(2), Limit: 5, Usage: 4},
+		{Need: NATGatewaysPerAZ(1), Limit: 5, Usage: 5},
+	}
+	assert.Equal(t,
+		"EC2-VPC Elastic IPs: need 2 more, 4 of 5 in use; NAT gateways per Availability Zone: need 1 more, 5 of 5 in use",
+		describe(shortfalls))
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// mlflowDB is the ML platform's MLflow backend database
+const mlflowDB = "aws_db_instance.mlflow"
+
+// TestMLflowDBStorageAutoscaling plans the ML platform stack and checks the
+// MLflow database renders with storage autoscaling: max_allocated_storage
+// set from mlflow_db_max_storage and above the allocated storage. This is
+// the fast mode; filling a real database until RDS grows its storage takes
+// hours and is not implemented. Nothing is applied.
+func TestMLflowDBStorageAutoscaling(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the platform stack configures its own AWS provider")
+
+	awsRegion := regions.PickRegion(t, regions.Service("rds"))
+
+	cases := []struct {
+		name                string
+		vars                map[string]interface{}
+		allocatedStorage    float64
+		maxAllocatedStorage float64
+	}{
+		{
+			name:                "defaults",
+			allocatedStorage:    20,
+			maxAllocatedStorage: 100,
+		},
+		{
+			name: "custom-limit",
+			vars: map[string]interface{}{
+				"mlflow_db_storage":     50,
+				"mlflow_db_max_storage": 500,
+			},
+			allocatedStorage:    50,
+			maxAllocatedStorage: 500,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vars := map[string]interface{}{
+				"aws_region":   awsRegion,
+				"project_name": fmt.Sprintf("rds-%s", strings.ToLower(random.UniqueId())),
+				"environment":  "test",
+			}
+			for name, value := range tc.vars {
+				vars[name] = value
+			}
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: harness.PlatformDir(t, "aws"),
+				Vars:         vars,
+				// Only the database and what it depends on, so the EKS
+				// cluster and the providers configured from it stay out
+				Targets:      []string{mlflowDB},
+				PlanFilePath: filepath.Join(t.TempDir(), "mlflow.tfplan"),
+				EnvVars: map[string]string{
+					"AWS_DEFAULT_REGION": awsRegion,
+				},
+			}
+
+			plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+			terraform.AssertPlannedValuesMapKeyExists(t, plan, mlflowDB)
+			db := plan.ResourcePlannedValuesMap[mlflowDB].AttributeValues
+
+			require.Contains(t, db, "max_allocated_storage", "Storage autoscaling should be configured")
+			assert.Equal(t, tc.allocatedStorage, db["allocated_storage"], "Allocated storage should match")
+			assert.Equal(t, tc.maxAllocatedStorage, db["max_allocated_storage"], "Max allocated storage should match")
+			assert.Greater(t, db["max_allocated_storage"], db["allocated_storage"], "Autoscaling needs room above the allocated storage")
+			assert.Equal(t, "gp3", db["storage_type"], "Storage type should be gp3")
+		})
+	}
+}
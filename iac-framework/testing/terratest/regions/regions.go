@@ -0,0 +1,228 @@
+// Package regions spreads tests across AWS regions. Pinning every suite to
+// us-west-2 hides region-specific module bugs (missing instance types, fewer
+// availability zones, services that have not launched yet) and concentrates
+// quota pressure on one region.
+package regions
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// OverrideEnvVar forces a region, matching the variable terratest's own
+// region helpers read. Handy when iterating locally.
+const OverrideEnvVar = "TERRATEST_REGION"
+
+// DenyListEnvVar adds comma separated regions to DenyList for one run
+const DenyListEnvVar = "TEST_REGION_DENYLIST"
+
+// DenyList holds regions tests must never be scheduled in
+var DenyList []string
+
+// Requirement is something a region has to offer for a test to run there
+type Requirement struct {
+	Name  string
+	Check func(t testing.TestingT, region string) (bool, error)
+}
+
+// Service requires an AWS service, named as in the global infrastructure
+// public parameters, e.g. "eks" or "elasticache"
+func Service(name string) Requirement {
+	return Requirement{
+		Name: "service " + name,
+		Check: func(t testing.TestingT, region string) (bool, error) {
+			return serviceAvailable(t, region, name)
+		},
+	}
+}
+
+// InstanceType requires an EC2 instance type to be offered in the region
+func InstanceType(instanceType string) Requirement {
+	return Requirement{
+		Name: "instance type " + instanceType,
+		Check: func(t testing.TestingT, region string) (bool, error) {
+			return instanceTypeOffered(t, region, instanceType)
+		},
+	}
+}
+
+// DefaultVPC requires the account to have a default VPC in the region, for
+// tests that plan or launch into its subnets
+func DefaultVPC() Requirement {
+	return Requirement{
+		Name: "default VPC",
+		Check: func(t testing.TestingT, region string) (bool, error) {
+			return hasDefaultVpc(t, region)
+		},
+	}
+}
+
+// Except rules out the given regions, e.g. the one a test already uses
+// when it needs a second
+func Except(excluded ...string) Requirement {
+	return Requirement{
+		Name: "a region other than " + strings.Join(excluded, ", "),
+		Check: func(_ testing.TestingT, region string) (bool, error) {
+			return !collections.ListContains(excluded, region), nil
+		},
+	}
+}
+
+// PickRegion returns a random enabled region, outside the deny list, that
+// satisfies every requirement. Against LocalStack, which answers for any
+// region from one endpoint and cannot be asked what a real region offers,
+// it returns TERRATEST_REGION or AWS_REGION without checking anything.
+func PickRegion(t testing.TestingT, required ...Requirement) string {
+	region, err := PickRegionE(t, required...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return region
+}
+
+// PickRegionE is PickRegion returning an error instead of failing the test
+func PickRegionE(t testing.TestingT, required ...Requirement) (string, error) {
+	if backend.IsLocalStack() {
+		return localStackRegion(), nil
+	}
+
+	candidates := []string{}
+	if override := os.Getenv(OverrideEnvVar); override != "" {
+		logger.Logf(t, "Using AWS region %s from environment variable %s", override, OverrideEnvVar)
+		candidates = append(candidates, override)
+	} else {
+		enabled, err := aws.GetAllAwsRegionsE(t)
+		if err != nil {
+			return "", err
+		}
+		candidates = enabled
+	}
+	return pick(t, candidates, denyList(), required, rand.Shuffle)
+}
+
+// pick tries the candidates in shuffled order and returns the first one that
+// meets all requirements. Regions whose checks error are skipped rather than
+// failing the test, since an unreachable region is as good as unavailable.
+func pick(t testing.TestingT, candidates []string, deny []string, required []Requirement, shuffle func(int, func(int, int))) (string, error) {
+	candidates = collections.ListSubtract(candidates, deny)
+	shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	for _, region := range candidates {
+		if satisfies(t, region, required) {
+			logger.Logf(t, "Picked AWS region %s", region)
+			return region, nil
+		}
+	}
+	return "", fmt.Errorf("no enabled region outside the deny list satisfies %s", describe(required))
+}
+
+func satisfies(t testing.TestingT, region string, required []Requirement) bool {
+	for _, requirement := range required {
+		ok, err := requirement.Check(t, region)
+		if err != nil {
+			logger.Logf(t, "Skipping region %s: checking %s: %v", region, requirement.Name, err)
+			return false
+		}
+		if !ok {
+			logger.Logf(t, "Skipping region %s: %s is not available", region, requirement.Name)
+			return false
+		}
+	}
+	return true
+}
+
+func describe(required []Requirement) string {
+	if len(required) == 0 {
+		return "no requirements"
+	}
+	names := make([]string, len(required))
+	for i, requirement := range required {
+		names[i] = requirement.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func localStackRegion() string {
+	for _, name := range []string{OverrideEnvVar, "AWS_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+	return "us-east-1"
+}
+
+func denyList() []string {
+	deny := append([]string{}, DenyList...)
+	for _, region := range strings.Split(os.Getenv(DenyListEnvVar), ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			deny = append(deny, region)
+		}
+	}
+	return deny
+}
+
+// serviceAvailable reads the global infrastructure public parameters, which
+// are published in every region so us-east-1 answers for all of them
+func serviceAvailable(t testing.TestingT, region string, service string) (bool, error) {
+	client, err := aws.NewSsmClientE(t, "us-east-1")
+	if err != nil {
+		return false, err
+	}
+	_, err = client.GetParameter(&ssm.GetParameterInput{
+		Name: awssdk.String(fmt.Sprintf("/aws/service/global-infrastructure/regions/%s/services/%s", region, service)),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func instanceTypeOffered(t testing.TestingT, region string, instanceType string) (bool, error) {
+	client, err := aws.NewEc2ClientE(t, region)
+	if err != nil {
+		return false, err
+	}
+	out, err := client.DescribeInstanceTypeOfferings(&ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: awssdk.String(ec2.LocationTypeRegion),
+		Filters: []*ec2.Filter{{
+			Name:   awssdk.String("instance-type"),
+			Values: awssdk.StringSlice([]string{instanceType}),
+		}},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.InstanceTypeOfferings) > 0, nil
+}
+
+func hasDefaultVpc(t testing.TestingT, region string) (bool, error) {
+	client, err := aws.NewEc2ClientE(t, region)
+	if err != nil {
+		return false, err
+	}
+	out, err := client.DescribeVpcs(&ec2.DescribeVpcsInput{
+		Filters: []*ec2.Filter{{
+			Name:   awssdk.String("is-default"),
+			Values: awssdk.StringSlice([]string{"true"}),
+		}},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.Vpcs) > 0, nil
+}
@@ -0,0 +1,108 @@
+package regions
+
+import (
+	"errors"
+	"testing"
+
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+func TestPick(t *testing.T) {
+	noShuffle := func(int, func(int, int)) {}
+	only := func(allowed ...string) Requirement {
+		return Requirement{
+			Name: "fake",
+			Check: func(_ terratesting.TestingT, region string) (bool, error) {
+				for _, candidate := range allowed {
+					if candidate == region {
+						return true, nil
+					}
+				}
+				return false, nil
+			},
+		}
+	}
+	failing := Requirement{
+		Name: "broken",
+		Check: func(_ terratesting.TestingT, region string) (bool, error) {
+			if region == "us-west-2" {
+				return false, errors.New("throttled")
+			}
+			return true, nil
+		},
+	}
+
+	cases := []struct {
+		name       string
+		candidates []string
+		deny       []string
+		required   []Requirement
+		expected   string
+	}{
+		{
+			name:       "first candidate without requirements",
+			candidates: []string{"us-west-2", "eu-west-1"},
+			expected:   "us-west-2",
+		},
+		{
+			name:       "deny list is excluded",
+			candidates: []string{"us-west-2", "eu-west-1"},
+			deny:       []string{"us-west-2"},
+			expected:   "eu-west-1",
+		},
+		{
+			name:       "all requirements must hold",
+			candidates: []string{"us-west-2", "eu-west-1", "ap-south-1"},
+			required:   []Requirement{only("eu-west-1", "ap-south-1"), only("ap-south-1")},
+			expected:   "ap-south-1",
+		},
+		{
+			name:       "regions whose checks error are skipped",
+			candidates: []string{"us-west-2", "eu-west-1"},
+			required:   []Requirement{failing},
+			expected:   "eu-west-1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			region, err := pick(t, tc.candidates, tc.deny, tc.required, noShuffle)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, region)
+		})
+	}
+}
+
+func TestPickFailsWhenNothingQualifies(t *testing.T) {
+	never := Requirement{
+		Name:  "service nothing",
+		Check: func(terratesting.TestingT, string) (bool, error) { return false, nil },
+	}
+	_, err := pick(t, []string{"us-west-2"}, nil, []Requirement{never}, func(int, func(int, int)) {})
+	assert.EqualError(t, err, "no enabled region outside the deny list satisfies service nothing")
+}
+
+func TestDenyListReadsEnvironment(t *testing.T) {
+	t.Setenv(DenyListEnvVar, " ap-east-1, ,me-south-1")
+	assert.Equal(t, []string{"ap-east-1", "me-south-1"}, denyList())
+}
+
+func TestExcept(t *testing.T) {
+	region, err := pick(t, []string{"us-west-2", "us-east-2"}, nil, []Requirement{Except("us-west-2")}, func(int, func(int, int)) {})
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-2", region)
+}
+
+func TestPickRegionOnLocalStack(t *testing.T) {
+	t.Setenv(backend.EnvVar, backend.LocalStack)
+	t.Setenv(OverrideEnvVar, "")
+	t.Setenv("AWS_REGION", "eu-central-1")
+	assert.Equal(t, "eu-central-1", PickRegion(t, Service("eks")), "Requirements cannot be checked against LocalStack")
+
+	t.Setenv("AWS_REGION", "")
+	assert.Equal(t, "us-east-1", PickRegion(t))
+}
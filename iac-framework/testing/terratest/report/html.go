@@ -0,0 +1,131 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"cost":     formatCost,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Terratest run report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+td.number { text-align: right; }
+tr.failed { background: #fdd; }
+tr.skipped { color: #888; }
+</style>
+</head>
+<body>
+<h1>Terratest run report</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<tr><th>Tests</th><th>Failed</th><th>Apply time</th><th>Destroy time</th><th>Estimated run cost</th><th>Retries</th></tr>
+<tr><td class="number">{{len .Tests}}</td><td class="number">{{.Failed}}</td><td class="number">{{duration .ApplyTime}}</td><td class="number">{{duration .DestroyTime}}</td><td class="number">{{cost .RunCost .Currency}}</td><td class="number">{{.Retries}}</td></tr>
+</table>
+<h2>Tests by apply and destroy time</h2>
+<table>
+<tr><th>Test</th><th>Module</th><th>Region</th><th>Status</th><th>Apply</th><th>Destroy</th><th>Total</th><th>Estimated run cost</th><th>Retries</th><th>Artifacts</th></tr>
+{{range .Tests}}<tr class="{{.Status}}">
+<td>{{.Name}}</td><td>{{.Module}}</td><td>{{.Region}}</td>
+<td>{{.Status}}{{if .FailureReason}}: {{.FailureReason}}{{end}}</td>
+<td class="number">{{duration .ApplyDuration}}</td><td class="number">{{duration .DestroyDuration}}</td><td class="number">{{duration .Duration}}</td>
+<td class="number">{{cost .RunCost .CostCurrency}}</td><td class="number">{{.Retries}}</td>
+<td>{{range .Links}}<a href="{{.URL}}">{{.Name}}</a> {{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type htmlTest struct {
+	TestRecord
+	Status string
+	Links  []htmlLink
+}
+
+type htmlLink struct {
+	Name string
+	URL  template.URL
+}
+
+type htmlReport struct {
+	GeneratedAt time.Time
+	Tests       []htmlTest
+	Failed      int
+	ApplyTime   time.Duration
+	DestroyTime time.Duration
+	RunCost     float64
+	Currency    string
+	Retries     int
+}
+
+// WriteHTML writes a single page summarising the run, with the slowest
+// tests first
+func WriteHTML(w io.Writer, records []TestRecord) error {
+	page := htmlReport{GeneratedAt: time.Now().UTC()}
+	for _, record := range records {
+		test := htmlTest{TestRecord: record, Status: status(record)}
+		for name, path := range record.Artifacts {
+			test.Links = append(test.Links, htmlLink{Name: name, URL: template.URL(path)})
+		}
+		sort.Slice(test.Links, func(i, j int) bool {
+			return test.Links[i].Name < test.Links[j].Name
+		})
+
+		if record.Failed {
+			page.Failed++
+		}
+		page.ApplyTime += record.ApplyDuration
+		page.DestroyTime += record.DestroyDuration
+		page.RunCost += record.RunCost()
+		page.Retries += record.Retries
+		if page.Currency == "" {
+			page.Currency = record.CostCurrency
+		}
+		page.Tests = append(page.Tests, test)
+	}
+
+	sort.SliceStable(page.Tests, func(i, j int) bool {
+		return infraTime(page.Tests[i].TestRecord) > infraTime(page.Tests[j].TestRecord)
+	})
+	return htmlTemplate.Execute(w, page)
+}
+
+func infraTime(record TestRecord) time.Duration {
+	return record.ApplyDuration + record.DestroyDuration
+}
+
+func status(record TestRecord) string {
+	switch {
+	case record.Failed:
+		return "failed"
+	case record.Skipped:
+		return "skipped"
+	}
+	return "passed"
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+func formatCost(cost float64, currency string) string {
+	if currency == "" {
+		return "-"
+	}
+	if cost > 0 && cost < 0.01 {
+		// Short tests on cheap resources round to nothing
+		return "< 0.01 " + currency
+	}
+	return fmt.Sprintf("%.2f %s", cost, currency)
+}
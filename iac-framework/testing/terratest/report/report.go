@@ -0,0 +1,233 @@
+// Package report records what each test did to real infrastructure and
+// writes it out as JUnit XML, for CI test tabs, as JSON, for dashboards, and
+// as an HTML page showing where a run's time and money went.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// JSONFileName is the machine readable report written by WriteFiles
+	JSONFileName = "report.json"
+
+	// JUnitFileName is the JUnit XML report written by WriteFiles
+	JUnitFileName = "junit.xml"
+
+	// HTMLFileName is the human readable report written by WriteFiles
+	HTMLFileName = "report.html"
+
+	// SuiteName names the JUnit test suite
+	SuiteName = "terratest"
+
+	// HoursPerMonth is the month Infracost's monthly figures are priced over
+	HoursPerMonth = 730
+)
+
+// TestRecord is what one test applied and how it went
+type TestRecord struct {
+	Name            string        `json:"name"`
+	Module          string        `json:"module,omitempty"`
+	TerraformBinary string        `json:"terraform_binary,omitempty"`
+	Region          string        `json:"region,omitempty"`
+	Resources       []string      `json:"resources,omitempty"`
+	StartedAt       time.Time     `json:"started_at"`
+	Duration        time.Duration `json:"duration_ns"`
+	ApplyDuration   time.Duration `json:"apply_duration_ns"`
+	DestroyDuration time.Duration `json:"destroy_duration_ns"`
+	Failed          bool          `json:"failed"`
+	Skipped         bool          `json:"skipped,omitempty"`
+	FailureReason   string        `json:"failure_reason,omitempty"`
+	Retries         int           `json:"retries,omitempty"`
+
+	// MonthlyCost is Infracost's estimate for what the test applied, when
+	// Infracost is configured
+	MonthlyCost  float64 `json:"monthly_cost,omitempty"`
+	CostCurrency string  `json:"cost_currency,omitempty"`
+
+	// Artifacts maps captured logs and plans to their path relative to the
+	// report directory
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+}
+
+// RunCost prorates the monthly estimate to how long the test ran, roughly
+// what keeping its infrastructure up for the test cost
+func (r TestRecord) RunCost() float64 {
+	return r.MonthlyCost * r.Duration.Hours() / HoursPerMonth
+}
+
+// Recorder collects records from parallel tests
+type Recorder struct {
+	mu      sync.Mutex
+	records map[string]*TestRecord
+}
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{records: map[string]*TestRecord{}}
+}
+
+// Update applies fn to the record for the named test, creating it first if
+// needed
+func (r *Recorder) Update(name string, fn func(*TestRecord)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[name]
+	if !ok {
+		record = &TestRecord{Name: name}
+		r.records[name] = record
+	}
+	fn(record)
+}
+
+// Records returns a copy of every record ordered by start time
+func (r *Recorder) Records() []TestRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := make([]TestRecord, 0, len(r.records))
+	for _, record := range r.records {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].StartedAt.Equal(records[j].StartedAt) {
+			return records[i].StartedAt.Before(records[j].StartedAt)
+		}
+		return records[i].Name < records[j].Name
+	})
+	return records
+}
+
+// Report is the JSON document
+type Report struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Tests       []TestRecord `json:"tests"`
+}
+
+// WriteJSON writes the records as an indented JSON Report
+func WriteJSON(w io.Writer, records []TestRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Report{GeneratedAt: time.Now().UTC(), Tests: records})
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes the records as a single JUnit test suite. Infrastructure
+// details go in testcase properties, which most CI systems display.
+func WriteJUnit(w io.Writer, records []TestRecord) error {
+	suite := junitSuite{Name: SuiteName, Tests: len(records)}
+	var total time.Duration
+	for _, record := range records {
+		total += record.Duration
+		testCase := junitCase{
+			Name:      record.Name,
+			Classname: SuiteName,
+			Time:      seconds(record.Duration),
+			Properties: []junitProperty{
+				{Name: "module", Value: record.Module},
+				{Name: "region", Value: record.Region},
+				{Name: "apply_seconds", Value: seconds(record.ApplyDuration)},
+				{Name: "destroy_seconds", Value: seconds(record.DestroyDuration)},
+				{Name: "resources", Value: strconv.Itoa(len(record.Resources))},
+			},
+		}
+		for _, resource := range record.Resources {
+			testCase.SystemOut += resource + "\n"
+		}
+		switch {
+		case record.Failed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: record.FailureReason}
+		case record.Skipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// WriteFiles writes every report format into dir, creating it if needed
+func WriteFiles(dir string, records []TestRecord) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	writers := map[string]func(io.Writer, []TestRecord) error{
+		JSONFileName:  WriteJSON,
+		JUnitFileName: WriteJUnit,
+		HTMLFileName:  WriteHTML,
+	}
+	for name, write := range writers {
+		if err := writeFile(filepath.Join(dir, name), records, write); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, records []TestRecord, write func(io.Writer, []TestRecord) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, records); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,126 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var started = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func sampleRecords() []TestRecord {
+	return []TestRecord{
+		{
+			Name:            "TestVPCModule",
+			Module:          "aws/vpc",
+			Region:          "us-west-2",
+			Resources:       []string{"aws_vpc.this", "aws_subnet.public[0]"},
+			StartedAt:       started,
+			Duration:        5 * time.Minute,
+			ApplyDuration:   2 * time.Minute,
+			DestroyDuration: time.Minute,
+		},
+		{
+			Name:          "TestEC2Module",
+			Module:        "aws/ec2",
+			Region:        "us-west-2",
+			StartedAt:     started.Add(time.Second),
+			Duration:      90 * time.Second,
+			Failed:        true,
+			FailureReason: "apply: InvalidSubnetID.NotFound",
+		},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Update("TestB", func(r *TestRecord) { r.StartedAt = started.Add(time.Minute) })
+	recorder.Update("TestA", func(r *TestRecord) { r.StartedAt = started })
+	recorder.Update("TestA", func(r *TestRecord) { r.ApplyDuration = time.Second })
+
+	records := recorder.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "TestA", records[0].Name, "Records should be ordered by start time")
+	assert.Equal(t, time.Second, records[0].ApplyDuration, "Updates should accumulate on one record")
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteJUnit(&out, sampleRecords()))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="terratest" tests="2" failures="1" skipped="0" time="390.000">
+    <testcase name="TestVPCModule" classname="terratest" time="300.000">
+      <properties>
+        <property name="module" value="aws/vpc"></property>
+        <property name="region" value="us-west-2"></property>
+        <property name="apply_seconds" value="120.000"></property>
+        <property name="destroy_seconds" value="60.000"></property>
+        <property name="resources" value="2"></property>
+      </properties>
+      <system-out>aws_vpc.this&#xA;aws_subnet.public[0]&#xA;</system-out>
+    </testcase>
+    <testcase name="TestEC2Module" classname="terratest" time="90.000">
+      <properties>
+        <property name="module" value="aws/ec2"></property>
+        <property name="region" value="us-west-2"></property>
+        <property name="apply_seconds" value="0.000"></property>
+        <property name="destroy_seconds" value="0.000"></property>
+        <property name="resources" value="0"></property>
+      </properties>
+      <failure message="apply: InvalidSubnetID.NotFound"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, WriteFiles(dir, sampleRecords()))
+
+	src, err := os.ReadFile(filepath.Join(dir, JSONFileName))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(src, &report))
+	assert.Equal(t, sampleRecords(), report.Tests)
+
+	_, err = os.Stat(filepath.Join(dir, JUnitFileName))
+	assert.NoError(t, err)
+}
+
+func TestWriteHTML(t *testing.T) {
+	records := sampleRecords()
+	// Five minutes of a month priced at 730 hours
+	records[0].MonthlyCost = 12 * HoursPerMonth
+	records[0].CostCurrency = "USD"
+	records[1].MonthlyCost = 7.6
+	records[1].CostCurrency = "USD"
+	records[0].Retries = 1
+	records[0].Artifacts = map[string]string{
+		"plan.json": "artifacts/TestVPCModule/plan.json",
+		"apply.log": "artifacts/TestVPCModule/apply.log",
+	}
+	records[1].FailureReason = "apply: <InvalidSubnetID.NotFound>"
+
+	var out bytes.Buffer
+	require.NoError(t, WriteHTML(&out, records))
+	html := out.String()
+
+	assert.Contains(t, html, `<td class="number">2m0s</td><td class="number">1m0s</td><td class="number">5m0s</td>`)
+	assert.Contains(t, html, `<td class="number">1.00 USD</td><td class="number">1</td>`, "Costs should be prorated to the test's duration")
+	assert.Contains(t, html, `<td class="number">&lt; 0.01 USD</td>`)
+	assert.NotContains(t, html, "monthly", "The report shows what the run cost, not monthly estimates")
+	assert.Contains(t, html, `<a href="artifacts/TestVPCModule/apply.log">apply.log</a> <a href="artifacts/TestVPCModule/plan.json">plan.json</a>`)
+	assert.Contains(t, html, `failed: apply: &lt;InvalidSubnetID.NotFound&gt;`, "Failure reasons should be escaped")
+	assert.Less(t, bytes.Index(out.Bytes(), []byte("TestVPCModule</td>")), bytes.Index(out.Bytes(), []byte("TestEC2Module</td>")),
+		"Tests with the most infrastructure time should come first")
+}
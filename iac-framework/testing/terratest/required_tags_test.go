@@ -0,0 +1,86 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/regions"
+	"github.com/company/iac-framework/testing/tagcheck"
+)
+
+// TestModulesPropagateRequiredTags plans each module with the required tag
+// set in its tags variable and asserts every taggable resource in the plan
+// carries all of them, so a resource that forgets to merge var.tags is
+// caught without applying anything
+func TestModulesPropagateRequiredTags(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the EC2 module needs a real subnet to plan against")
+
+	awsRegion := regions.PickRegion(t, regions.DefaultVPC())
+	tags := map[string]string{
+		"Environment": "test",
+		"Project":     "terratest",
+		"Owner":       "infrastructure-team",
+		"CostCenter":  "platform-testing",
+	}
+
+	// The EC2 module looks its subnet up while planning, so it needs a real one
+	defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+	defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+	require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
+
+	cases := []struct {
+		module string
+		vars   map[string]interface{}
+	}{
+		{
+			module: "aws/vpc",
+			vars: map[string]interface{}{
+				"enable_database_subnets":  true,
+				"enable_s3_endpoint":       true,
+				"enable_dynamodb_endpoint": true,
+			},
+		},
+		{
+			module: "aws/ec2",
+			vars: map[string]interface{}{
+				"subnet_id":       defaultSubnets[0],
+				"create_eip":      true,
+				"create_iam_role": true,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.module, func(t *testing.T) {
+			t.Parallel()
+
+			vars := map[string]interface{}{
+				"project_name": fmt.Sprintf("tags-%s", random.UniqueId()),
+				"environment":  "test",
+				"tags":         tags,
+			}
+			for name, value := range tc.vars {
+				vars[name] = value
+			}
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: harness.ModuleDir(t, tc.module),
+				Vars:         vars,
+				EnvVars: map[string]string{
+					"AWS_DEFAULT_REGION": awsRegion,
+				},
+			}
+
+			tagcheck.AssertPlan(t, terraformOptions, tagcheck.DefaultRequired...)
+		})
+	}
+}
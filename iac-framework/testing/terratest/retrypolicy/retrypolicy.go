@@ -0,0 +1,154 @@
+// Package retrypolicy is the one place the suite decides which terraform
+// failures are worth retrying and how often. The harness applies it to the
+// options of every tracked test, so tests do not carry their own lists of
+// retryable errors.
+package retrypolicy
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+const (
+	// MaxRetriesEnvVar overrides DefaultMaxRetries for one run
+	MaxRetriesEnvVar = "TEST_RETRY_MAX"
+
+	// TimeBetweenRetriesEnvVar overrides DefaultTimeBetweenRetries for one
+	// run, e.g. "30s"
+	TimeBetweenRetriesEnvVar = "TEST_RETRY_SLEEP"
+
+	// DefaultMaxRetries is how often a retryable failure is retried
+	DefaultMaxRetries = 3
+
+	// DefaultTimeBetweenRetries gives eventually consistent APIs time to
+	// catch up. Terratest waits the same time before every retry.
+	DefaultTimeBetweenRetries = 15 * time.Second
+)
+
+// Throttling errors from AWS APIs under the load of parallel tests
+var Throttling = map[string]string{
+	".*Throttling: Rate exceeded.*":    "AWS API throttling.",
+	".*ThrottlingException.*":          "AWS API throttling.",
+	".*RequestLimitExceeded.*":         "AWS EC2 API throttling.",
+	".*TooManyRequestsException.*":     "AWS API throttling.",
+	".*SlowDown: Please reduce your.*": "S3 request rate throttling.",
+}
+
+// EventualConsistency errors are resources referenced before every AWS
+// endpoint knows they exist
+var EventualConsistency = map[string]string{
+	".*InvalidGroup.NotFound.*":                                        "Security group not visible yet.",
+	".*InvalidParameterValue: .*security group.*does not exist.*":      "Security group not visible yet.",
+	".*InvalidInstanceID.NotFound.*":                                   "Instance not visible yet.",
+	".*InvalidSubnetID.NotFound.*":                                     "Subnet not visible yet.",
+	".*InvalidRouteTableID.NotFound.*":                                 "Route table not visible yet.",
+	".*InvalidAllocationID.NotFound.*":                                 "Elastic IP not visible yet.",
+	".*Invalid IAM Instance Profile name.*":                            "IAM instance profile not propagated yet.",
+	".*Value .* for parameter iamInstanceProfile.name is invalid.*":    "IAM instance profile not propagated yet.",
+	".*The role defined for the function cannot be assumed.*":          "IAM role not propagated yet.",
+	".*DependencyViolation.*has.*dependencies and cannot be deleted.*": "Dependent resource not released yet.",
+}
+
+// Policy is a set of retryable errors and how to retry them
+type Policy struct {
+	// Errors maps regular expressions matched against terraform output to a
+	// description of why the failure is retryable
+	Errors             map[string]string
+	MaxRetries         int
+	TimeBetweenRetries time.Duration
+}
+
+// Default is the suite's policy: terratest's own retryable errors,
+// throttling and eventual consistency, with limits from the environment
+func Default() Policy {
+	errors := map[string]string{}
+	for _, set := range []map[string]string{terraform.DefaultRetryableTerraformErrors, Throttling, EventualConsistency} {
+		for pattern, reason := range set {
+			errors[pattern] = reason
+		}
+	}
+	return Policy{
+		Errors:             errors,
+		MaxRetries:         envInt(MaxRetriesEnvVar, DefaultMaxRetries),
+		TimeBetweenRetries: envDuration(TimeBetweenRetriesEnvVar, DefaultTimeBetweenRetries),
+	}
+}
+
+// Apply adds the policy to options. Errors a test lists itself are kept
+// alongside the policy's, and retry limits a test sets itself win.
+func (p Policy) Apply(options *terraform.Options) *terraform.Options {
+	if options.RetryableTerraformErrors == nil {
+		options.RetryableTerraformErrors = map[string]string{}
+	}
+	for pattern, reason := range p.Errors {
+		if _, ok := options.RetryableTerraformErrors[pattern]; !ok {
+			options.RetryableTerraformErrors[pattern] = reason
+		}
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = p.MaxRetries
+	}
+	if options.TimeBetweenRetries == 0 {
+		options.TimeBetweenRetries = p.TimeBetweenRetries
+	}
+	return options
+}
+
+// Retryable returns why output is worth retrying, or "" if it is not
+func (p Policy) Retryable(output string) string {
+	for pattern, reason := range p.Errors {
+		if matched, _ := regexp.MatchString(pattern, output); matched {
+			return reason
+		}
+	}
+	return ""
+}
+
+// Do runs action with the retries options were given by Apply, but in the
+// open: terratest retries inside its own calls without telling anyone, so
+// action gets a copy of options with that turned off. Action is run again
+// while its output or error matches one of the options' retryable errors,
+// up to MaxRetries more times, and onRetry hears the reason for each retry.
+func Do(options *terraform.Options, action func(*terraform.Options) (string, error), onRetry func(reason string)) (string, error) {
+	policy := Policy{
+		Errors:             options.RetryableTerraformErrors,
+		MaxRetries:         options.MaxRetries,
+		TimeBetweenRetries: options.TimeBetweenRetries,
+	}
+	once := *options
+	once.RetryableTerraformErrors = nil
+	once.MaxRetries = 0
+
+	for attempt := 0; ; attempt++ {
+		out, err := action(&once)
+		if err == nil || attempt >= policy.MaxRetries {
+			return out, err
+		}
+		reason := policy.Retryable(out + "\n" + err.Error())
+		if reason == "" {
+			return out, err
+		}
+		onRetry(reason)
+		time.Sleep(policy.TimeBetweenRetries)
+	}
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package retrypolicy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	t.Setenv(MaxRetriesEnvVar, "")
+	t.Setenv(TimeBetweenRetriesEnvVar, "")
+
+	policy := Default()
+	assert.Equal(t, DefaultMaxRetries, policy.MaxRetries)
+	assert.Equal(t, DefaultTimeBetweenRetries, policy.TimeBetweenRetries)
+	for pattern := range terraform.DefaultRetryableTerraformErrors {
+		assert.Contains(t, policy.Errors, pattern, "Terratest's own retryable errors should be kept")
+	}
+
+	t.Setenv(MaxRetriesEnvVar, "6")
+	t.Setenv(TimeBetweenRetriesEnvVar, "1m")
+	policy = Default()
+	assert.Equal(t, 6, policy.MaxRetries)
+	assert.Equal(t, time.Minute, policy.TimeBetweenRetries)
+
+	t.Setenv(MaxRetriesEnvVar, "-1")
+	t.Setenv(TimeBetweenRetriesEnvVar, "soon")
+	policy = Default()
+	assert.Equal(t, DefaultMaxRetries, policy.MaxRetries, "Nonsensical overrides should fall back")
+	assert.Equal(t, DefaultTimeBetweenRetries, policy.TimeBetweenRetries)
+}
+
+func TestRetryable(t *testing.T) {
+	policy := Default()
+
+	testCases := []struct {
+		output    string
+		retryable bool
+	}{
+		{"Error: creating EC2 Instance: InvalidParameterValue: The security group 'sg-0abc' does not exist in VPC 'vpc-1'", true},
+		{"Error: InvalidGroup.NotFound: The security group ID 'sg-0abc' does not exist", true},
+		{"Error: reading EC2 Instance: RequestLimitExceeded: Request limit exceeded.", true},
+		{"Error: creating IAM Role: ThrottlingException: Rate exceeded", true},
+		{"Error: creating EC2 Instance: InvalidParameterValue: Value (test-profile) for parameter iamInstanceProfile.name is invalid. Invalid IAM Instance Profile name", true},
+		{"Error: deleting EC2 Subnet: DependencyViolation: The subnet 'subnet-1' has dependencies and cannot be deleted.", true},
+		{"Error: Invalid value for variable", false},
+		{"Error: creating EC2 VPC: VpcLimitExceeded: The maximum number of VPCs has been reached.", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.retryable, policy.Retryable(tc.output) != "", tc.output)
+	}
+}
+
+func TestApply(t *testing.T) {
+	policy := Policy{
+		Errors:             map[string]string{".*Throttling.*": "throttled", ".*flaky.*": "policy reason"},
+		MaxRetries:         3,
+		TimeBetweenRetries: 15 * time.Second,
+	}
+
+	options := policy.Apply(&terraform.Options{})
+	assert.Equal(t, policy.Errors, options.RetryableTerraformErrors)
+	assert.Equal(t, 3, options.MaxRetries)
+	assert.Equal(t, 15*time.Second, options.TimeBetweenRetries)
+
+	options = policy.Apply(&terraform.Options{
+		RetryableTerraformErrors: map[string]string{".*flaky.*": "test reason", ".*custom.*": "custom"},
+		MaxRetries:               1,
+		TimeBetweenRetries:       time.Second,
+	})
+	assert.Equal(t, map[string]string{
+		".*Throttling.*": "throttled",
+		".*flaky.*":      "test reason",
+		".*custom.*":     "custom",
+	}, options.RetryableTerraformErrors, "A test's own errors should be kept")
+	assert.Equal(t, 1, options.MaxRetries, "A test's own limits should win")
+	assert.Equal(t, time.Second, options.TimeBetweenRetries)
+}
+
+func TestDo(t *testing.T) {
+	options := &terraform.Options{
+		RetryableTerraformErrors: map[string]string{".*RequestLimitExceeded.*": "throttled"},
+		MaxRetries:               2,
+		TimeBetweenRetries:       time.Millisecond,
+	}
+
+	run := func(outputs ...string) (attempts int, reasons []string, err error) {
+		_, err = Do(options, func(once *terraform.Options) (string, error) {
+			assert.Zero(t, once.MaxRetries, "Terratest should not retry on its own")
+			assert.Nil(t, once.RetryableTerraformErrors)
+			output := outputs[attempts]
+			attempts++
+			if output == "" {
+				return "applied", nil
+			}
+			return output, errors.New("exit status 1")
+		}, func(reason string) {
+			reasons = append(reasons, reason)
+		})
+		return attempts, reasons, err
+	}
+
+	attempts, reasons, err := run("Error: RequestLimitExceeded", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"throttled"}, reasons)
+
+	attempts, reasons, err = run("Error: Invalid value for variable")
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts, "Errors outside the policy should not be retried")
+	assert.Empty(t, reasons)
+
+	attempts, reasons, err = run("Error: RequestLimitExceeded", "Error: RequestLimitExceeded", "Error: RequestLimitExceeded")
+	assert.Error(t, err)
+	assert.Equal(t, 3, attempts, "MaxRetries should bound the retries")
+	assert.Len(t, reasons, 2)
+	assert.Equal(t, 2, options.MaxRetries, "The caller's options should be left alone")
+}
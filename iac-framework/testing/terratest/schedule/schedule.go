@@ -0,0 +1,345 @@
+// Package schedule runs the suite on a cadence without a CI pipeline behind
+// it. A config names each scheduled suite, the mode it runs in and how
+// often; every run of the suite is appended to a history file, which is also
+// what decides when a suite is next due, so a scheduler that restarts picks
+// up where it left off.
+package schedule
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/report"
+	"github.com/company/iac-framework/testing/upgrade"
+)
+
+// Mode is how a scheduled suite runs
+type Mode string
+
+const (
+	// ModeConformance runs the selected tests as they are, against the
+	// modules in the working tree
+	ModeConformance Mode = "conformance"
+
+	// ModeUpgradeCanary runs the upgrade tests, applying each module's last
+	// release and upgrading it to the working tree
+	ModeUpgradeCanary Mode = "upgrade-canary"
+
+	// ModeChaos is reserved for fault injection runs, which the suite has no
+	// support for yet. Configs using it are rejected.
+	ModeChaos Mode = "chaos"
+)
+
+// upgradeTests selects the upgrade tests for ModeUpgradeCanary
+const upgradeTests = "^TestModuleUpgrades$"
+
+// DefaultTimeout bounds a single suite run when the config sets none
+const DefaultTimeout = 90 * time.Minute
+
+// Duration is a time.Duration written as a string like "6h" in the config
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"6h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Suite is one scheduled run of the suite
+type Suite struct {
+	Name string `json:"name"`
+	Mode Mode   `json:"mode"`
+
+	// Tests is the -run pattern. ModeUpgradeCanary defaults it to the
+	// upgrade tests.
+	Tests string `json:"tests,omitempty"`
+
+	// Every is how long after the last run started the suite is due again
+	Every Duration `json:"every"`
+
+	// Timeout bounds the run, defaulting to the config's
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// Env is added to the environment of the run, e.g. AWS_REGION or
+	// TEST_UPGRADE_FROM
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// Config is the schedule
+type Config struct {
+	Suites  []Suite  `json:"suites"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// Load reads and validates a config, filling in defaults
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Suites) == 0 {
+		return errors.New("no suites scheduled")
+	}
+	if c.Timeout.Duration == 0 {
+		c.Timeout.Duration = DefaultTimeout
+	}
+
+	seen := map[string]bool{}
+	for i := range c.Suites {
+		suite := &c.Suites[i]
+		if suite.Name == "" {
+			return fmt.Errorf("suite %d has no name", i)
+		}
+		if seen[suite.Name] {
+			return fmt.Errorf("suite %s is scheduled twice", suite.Name)
+		}
+		seen[suite.Name] = true
+
+		switch suite.Mode {
+		case ModeConformance:
+			if suite.Tests == "" {
+				return fmt.Errorf("suite %s: conformance runs need tests to select", suite.Name)
+			}
+		case ModeUpgradeCanary:
+			if suite.Tests == "" {
+				suite.Tests = upgradeTests
+			}
+		case ModeChaos:
+			return fmt.Errorf("suite %s: chaos mode is not supported yet", suite.Name)
+		default:
+			return fmt.Errorf("suite %s: unknown mode %q", suite.Name, suite.Mode)
+		}
+
+		if _, err := regexp.Compile(suite.Tests); err != nil {
+			return fmt.Errorf("suite %s: invalid tests pattern: %w", suite.Name, err)
+		}
+		if suite.Every.Duration <= 0 {
+			return fmt.Errorf("suite %s: every must be positive", suite.Name)
+		}
+		if suite.Timeout.Duration == 0 {
+			suite.Timeout = c.Timeout
+		}
+	}
+	return nil
+}
+
+// Run is one finished run of a scheduled suite, as kept in the history
+type Run struct {
+	Suite     string        `json:"suite"`
+	Mode      Mode          `json:"mode"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Passed    bool          `json:"passed"`
+
+	// Failed names the failed tests, from the run's report
+	Failed []string `json:"failed,omitempty"`
+
+	// Error is set when the run could not start or produced no report
+	Error string `json:"error,omitempty"`
+
+	// ReportDir holds the run's JUnit, JSON and HTML reports
+	ReportDir string `json:"report_dir,omitempty"`
+}
+
+// ReadHistory returns every run in a history file, oldest first. A missing
+// file is an empty history.
+func ReadHistory(path string) ([]Run, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// AppendHistory adds a run to the history file, one JSON document per line
+func AppendHistory(path string, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Due returns the suites that have never run or whose last run started at
+// least Every before now, in config order
+func Due(config *Config, history []Run, now time.Time) []Suite {
+	last := map[string]time.Time{}
+	for _, run := range history {
+		if run.StartedAt.After(last[run.Suite]) {
+			last[run.Suite] = run.StartedAt
+		}
+	}
+
+	var due []Suite
+	for _, suite := range config.Suites {
+		started, ok := last[suite.Name]
+		if !ok || !now.Before(started.Add(suite.Every.Duration)) {
+			due = append(due, suite)
+		}
+	}
+	return due
+}
+
+// NextDue returns when the next suite comes due after now
+func NextDue(config *Config, history []Run, now time.Time) time.Time {
+	last := map[string]time.Time{}
+	for _, run := range history {
+		if run.StartedAt.After(last[run.Suite]) {
+			last[run.Suite] = run.StartedAt
+		}
+	}
+
+	var next time.Time
+	for _, suite := range config.Suites {
+		started, ok := last[suite.Name]
+		if !ok {
+			return now
+		}
+		at := started.Add(suite.Every.Duration)
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	return next
+}
+
+// Execute runs a suite with go test from the suite directory, writing its
+// reports to a fresh directory under reportRoot, and returns the run for
+// the history. A failing test is a failed run, not an error.
+func Execute(suiteDir string, reportRoot string, suite Suite) Run {
+	started := time.Now().UTC()
+	run := Run{
+		Suite:     suite.Name,
+		Mode:      suite.Mode,
+		StartedAt: started,
+		ReportDir: filepath.Join(reportRoot, suite.Name, started.Format("20060102T150405Z")),
+	}
+	defer func() { run.Duration = time.Since(started) }()
+
+	if err := os.MkdirAll(run.ReportDir, 0o755); err != nil {
+		run.Error = err.Error()
+		return run
+	}
+
+	cmd := exec.Command("go", "test", "-count=1", "-timeout", suite.Timeout.String(), "-run", suite.Tests, ".")
+	cmd.Dir = suiteDir
+	cmd.Env = append(os.Environ(), harness.ReportDirEnvVar+"="+run.ReportDir)
+	for key, value := range environment(suite) {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	if logErr := os.WriteFile(filepath.Join(run.ReportDir, "go-test.log"), output, 0o644); logErr != nil && err == nil {
+		err = logErr
+	}
+
+	failed, reportErr := failedTests(filepath.Join(run.ReportDir, report.JSONFileName))
+	switch {
+	case reportErr != nil:
+		// Without a report the suite did not get as far as running tests,
+		// e.g. it failed to compile
+		run.Error = fmt.Sprintf("reading report: %v (go test: %v)", reportErr, err)
+	case err != nil && len(failed) == 0:
+		run.Error = fmt.Sprintf("go test: %v", err)
+	default:
+		run.Failed = failed
+		run.Passed = err == nil && len(failed) == 0
+	}
+	return run
+}
+
+// environment is the suite's own variables plus what its mode needs
+func environment(suite Suite) map[string]string {
+	env := map[string]string{}
+	for key, value := range suite.Env {
+		env[key] = value
+	}
+	if suite.Mode == ModeUpgradeCanary {
+		// A pin inherited from the scheduler's own environment would stop
+		// the canary following releases, so it is cleared unless the suite
+		// sets one
+		if _, ok := env[upgrade.FromRefEnvVar]; !ok {
+			env[upgrade.FromRefEnvVar] = ""
+		}
+	}
+	return env
+}
+
+func failedTests(reportPath string) ([]string, error) {
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, err
+	}
+	var parsed report.Report
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	var failed []string
+	for _, record := range parsed.Tests {
+		if record.Failed {
+			failed = append(failed, record.Name)
+		}
+	}
+	sort.Strings(failed)
+	return failed, nil
+}
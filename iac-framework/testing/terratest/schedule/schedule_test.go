@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/report"
+	"github.com/company/iac-framework/testing/upgrade"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	config, err := Load(writeConfig(t, `{
+		"suites": [
+			{"name": "vpc", "mode": "conformance", "tests": "^TestVPC", "every": "6h", "timeout": "30m"},
+			{"name": "canary", "mode": "upgrade-canary", "every": "24h"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	require.Len(t, config.Suites, 2)
+	assert.Equal(t, 6*time.Hour, config.Suites[0].Every.Duration)
+	assert.Equal(t, 30*time.Minute, config.Suites[0].Timeout.Duration)
+	assert.Equal(t, upgradeTests, config.Suites[1].Tests, "Upgrade canaries should default to the upgrade tests")
+	assert.Equal(t, DefaultTimeout, config.Suites[1].Timeout.Duration)
+}
+
+func TestLoadRejectsInvalidConfigs(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"no suites", `{"suites": []}`, "no suites"},
+		{"unknown mode", `{"suites": [{"name": "a", "mode": "soak", "every": "1h"}]}`, "unknown mode"},
+		{"chaos", `{"suites": [{"name": "a", "mode": "chaos", "every": "1h"}]}`, "not supported"},
+		{"no tests", `{"suites": [{"name": "a", "mode": "conformance", "every": "1h"}]}`, "need tests"},
+		{"bad pattern", `{"suites": [{"name": "a", "mode": "conformance", "tests": "(", "every": "1h"}]}`, "invalid tests pattern"},
+		{"no cadence", `{"suites": [{"name": "a", "mode": "upgrade-canary"}]}`, "every must be positive"},
+		{"bad duration", `{"suites": [{"name": "a", "mode": "upgrade-canary", "every": "daily"}]}`, "invalid duration"},
+		{"duplicate", `{"suites": [{"name": "a", "mode": "upgrade-canary", "every": "1h"}, {"name": "a", "mode": "upgrade-canary", "every": "2h"}]}`, "scheduled twice"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tc.config))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	config := &Config{Suites: []Suite{
+		{Name: "vpc", Every: Duration{6 * time.Hour}},
+		{Name: "ec2", Every: Duration{12 * time.Hour}},
+		{Name: "canary", Every: Duration{24 * time.Hour}},
+	}}
+	history := []Run{
+		{Suite: "vpc", StartedAt: now.Add(-7 * time.Hour)},
+		{Suite: "ec2", StartedAt: now.Add(-13 * time.Hour)},
+		{Suite: "ec2", StartedAt: now.Add(-time.Hour)},
+	}
+
+	var names []string
+	for _, suite := range Due(config, history, now) {
+		names = append(names, suite.Name)
+	}
+	assert.Equal(t, []string{"vpc", "canary"}, names)
+
+	history = append(history,
+		Run{Suite: "vpc", StartedAt: now},
+		Run{Suite: "canary", StartedAt: now})
+	assert.Empty(t, Due(config, history, now))
+	assert.Equal(t, now.Add(6*time.Hour), NextDue(config, history, now))
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	runs, err := ReadHistory(path)
+	require.NoError(t, err)
+	assert.Empty(t, runs, "A missing history should be empty")
+
+	first := Run{Suite: "vpc", Mode: ModeConformance, StartedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Passed: true}
+	second := Run{Suite: "vpc", Mode: ModeConformance, StartedAt: time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC), Failed: []string{"TestVPCFlowLogs"}}
+	require.NoError(t, AppendHistory(path, first))
+	require.NoError(t, AppendHistory(path, second))
+
+	runs, err = ReadHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Run{first, second}, runs)
+}
+
+func TestFailedTests(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, report.WriteFiles(dir, []report.TestRecord{
+		{Name: "TestVPCModule"},
+		{Name: "TestVPCFlowLogs", Failed: true},
+		{Name: "TestEC2Module", Failed: true},
+	}))
+
+	failed, err := failedTests(filepath.Join(dir, report.JSONFileName))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TestEC2Module", "TestVPCFlowLogs"}, failed)
+}
+
+func TestEnvironment(t *testing.T) {
+	env := environment(Suite{Mode: ModeUpgradeCanary, Env: map[string]string{"AWS_REGION": "eu-west-1"}})
+	assert.Equal(t, map[string]string{"AWS_REGION": "eu-west-1", upgrade.FromRefEnvVar: ""}, env)
+
+	env = environment(Suite{Mode: ModeUpgradeCanary, Env: map[string]string{upgrade.FromRefEnvVar: "v1.0.0"}})
+	assert.Equal(t, "v1.0.0", env[upgrade.FromRefEnvVar], "A pinned ref should be kept")
+
+	assert.Empty(t, environment(Suite{Mode: ModeConformance}))
+}
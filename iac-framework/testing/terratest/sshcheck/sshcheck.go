@@ -0,0 +1,108 @@
+// Package sshcheck validates instances from the inside over SSH, using the
+// ephemeral key pairs the harness imports for each test.
+package sshcheck
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DefaultUser is the login user on the Amazon Linux images
+const DefaultUser = "ec2-user"
+
+const (
+	maxRetries         = 30
+	sleepBetweenChecks = 10 * time.Second
+)
+
+// Host describes an instance reachable at ip with the given key pair
+func Host(ip string, keyPair *aws.Ec2Keypair) ssh.Host {
+	return ssh.Host{
+		Hostname:    ip,
+		SshUserName: DefaultUser,
+		SshKeyPair:  keyPair.KeyPair,
+	}
+}
+
+// WaitForCloudInit waits until sshd accepts the key and cloud-init has run
+// the user data to completion. A user data script that failed fails the test.
+func WaitForCloudInit(t testing.TestingT, host ssh.Host) {
+	description := fmt.Sprintf("cloud-init to finish on %s", host.Hostname)
+	retry.DoWithRetry(t, description, maxRetries, sleepBetweenChecks, func() (string, error) {
+		output, err := ssh.CheckSshCommandE(t, host, "cloud-init status --wait")
+		if err != nil && output == "" {
+			// Not reachable yet
+			return "", err
+		}
+		if err := cloudInitDone(output); err != nil {
+			return "", retry.FatalError{Underlying: err}
+		}
+		return output, nil
+	})
+}
+
+// cloudInitDone interprets the output of cloud-init status. Anything but done
+// is final since --wait only returns once cloud-init stops running.
+func cloudInitDone(output string) error {
+	for _, line := range strings.Split(output, "\n") {
+		status, found := strings.CutPrefix(strings.TrimSpace(line), "status:")
+		if !found {
+			continue
+		}
+		if status = strings.TrimSpace(status); status != "done" {
+			return fmt.Errorf("cloud-init finished with status %q", status)
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected cloud-init status output: %q", output)
+}
+
+// Curl fetches url from the instance itself and returns the body, retrying
+// while the service comes up
+func Curl(t testing.TestingT, host ssh.Host, url string) string {
+	command := fmt.Sprintf("curl --silent --show-error --fail %s", url)
+	return ssh.CheckSshCommandWithRetry(t, host, command, maxRetries, sleepBetweenChecks)
+}
+
+// RunnerCidrURL answers with the caller's public IP, which is the address
+// the instance sees the test runner's SSH connection come from
+const RunnerCidrURL = "https://checkip.amazonaws.com"
+
+// RunnerCidr returns the test runner's public IP as a /32, for security
+// group rules that let only this run in on port 22
+func RunnerCidr(t testing.TestingT) string {
+	body := retry.DoWithRetry(t, "look up the runner's public IP", 3, 5*time.Second, func() (string, error) {
+		response, err := http.Get(RunnerCidrURL)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("%s answered %s", RunnerCidrURL, response.Status)
+		}
+		body, err := io.ReadAll(response.Body)
+		return string(body), err
+	})
+	cidr, err := toCidr(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cidr
+}
+
+func toCidr(body string) (string, error) {
+	ip := net.ParseIP(strings.TrimSpace(body))
+	if ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("expected an IPv4 address from %s, got %q", RunnerCidrURL, body)
+	}
+	return ip.String() + "/32", nil
+}
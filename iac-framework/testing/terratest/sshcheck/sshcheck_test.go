@@ -0,0 +1,43 @@
+package sshcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudInitDone(t *testing.T) {
+	cases := []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{name: "done", output: "\nstatus: done\n"},
+		{name: "done with progress dots", output: "....\nstatus: done"},
+		{name: "error", output: "status: error", wantErr: `cloud-init finished with status "error"`},
+		{name: "garbage", output: "Permission denied", wantErr: `unexpected cloud-init status output: "Permission denied"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := cloudInitDone(tc.output)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestToCidr(t *testing.T) {
+	cidr, err := toCidr("203.0.113.7\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "203.0.113.7/32", cidr)
+
+	_, err = toCidr("<html>rate limited</html>")
+	assert.Error(t, err)
+
+	_, err = toCidr("2001:db8::1")
+	assert.Error(t, err, "The module's ssh_cidr_blocks only take IPv4")
+}
@@ -0,0 +1,252 @@
+// Package ssmcompliance checks that instances come up managed by Systems
+// Manager the way the account baseline expects: registered in the
+// inventory, with the baseline State Manager associations applied
+// successfully and reported COMPLIANT. The associations belong to the
+// account, not to the modules, so the documents to look for are
+// configurable.
+package ssmcompliance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+const (
+	// BaselineEnvVar overrides the comma separated baseline documents
+	BaselineEnvVar = "TEST_SSM_BASELINE_DOCUMENTS"
+
+	// Timeout bounds how long associations and the first inventory
+	// collection may take on a new instance
+	Timeout = 20 * time.Minute
+
+	pollInterval = 30 * time.Second
+
+	// inventoryType is written by every inventory collection
+	inventoryType = "AWS:InstanceInformation"
+)
+
+// DefaultBaseline is the inventory gathering and CloudWatch agent
+// associations every account is set up with
+var DefaultBaseline = []string{"AWS-GatherSoftwareInventory", "AmazonCloudWatch-ManageAgent"}
+
+// Association is the status of one association on an instance
+type Association struct {
+	Id       string
+	Document string
+	Status   string
+	Detail   string
+}
+
+// Baseline returns the documents from TEST_SSM_BASELINE_DOCUMENTS, or the
+// default baseline when it is unset
+func Baseline() []string {
+	return parseDocuments(os.Getenv(BaselineEnvVar))
+}
+
+func parseDocuments(value string) []string {
+	var documents []string
+	for _, document := range strings.Split(value, ",") {
+		if document = strings.TrimSpace(document); document != "" {
+			documents = append(documents, document)
+		}
+	}
+	if len(documents) == 0 {
+		return DefaultBaseline
+	}
+	return documents
+}
+
+// Associations returns the status of every association on the instance
+func Associations(t testing.TestingT, region string, instanceId string) []Association {
+	associations, err := AssociationsE(region, instanceId)
+	require.NoError(t, err)
+	return associations
+}
+
+// AssociationsE is Associations returning an error instead of failing the
+// test
+func AssociationsE(region string, instanceId string) ([]Association, error) {
+	client, err := newSsmClient(region)
+	if err != nil {
+		return nil, err
+	}
+
+	var associations []Association
+	err = client.DescribeInstanceAssociationsStatusPages(&ssm.DescribeInstanceAssociationsStatusInput{
+		InstanceId: awssdk.String(instanceId),
+	}, func(page *ssm.DescribeInstanceAssociationsStatusOutput, lastPage bool) bool {
+		for _, info := range page.InstanceAssociationStatusInfos {
+			associations = append(associations, Association{
+				Id:       awssdk.StringValue(info.AssociationId),
+				Document: awssdk.StringValue(info.Name),
+				Status:   awssdk.StringValue(info.Status),
+				Detail:   awssdk.StringValue(info.DetailedStatus),
+			})
+		}
+		return true
+	})
+	return associations, err
+}
+
+// progress sorts the documents into those whose associations all
+// succeeded, those still waiting to be associated or to finish, and those
+// with a failed association. A document can be associated more than once,
+// by the baseline and by a team, and every association has to succeed.
+func progress(associations []Association, documents []string) (succeeded []Association, waiting []string, failed []string) {
+	for _, document := range documents {
+		var matching []Association
+		for _, association := range associations {
+			if association.Document == document {
+				matching = append(matching, association)
+			}
+		}
+		if len(matching) == 0 {
+			waiting = append(waiting, fmt.Sprintf("%s: not associated", document))
+			continue
+		}
+
+		done := true
+		for _, association := range matching {
+			switch association.Status {
+			case ssm.AssociationStatusNameSuccess:
+			case ssm.AssociationStatusNameFailed:
+				failed = append(failed, fmt.Sprintf("%s (%s): %s", document, association.Id, association.Detail))
+				done = false
+			default:
+				waiting = append(waiting, fmt.Sprintf("%s (%s): %s", document, association.Id, association.Status))
+				done = false
+			}
+		}
+		if done {
+			succeeded = append(succeeded, matching...)
+		}
+	}
+	return succeeded, waiting, failed
+}
+
+// AssertAssociationsSucceed waits for the associations of each document to
+// reach Success on the instance and returns them. A failed association
+// fails the test straight away.
+func AssertAssociationsSucceed(t testing.TestingT, region string, instanceId string, documents ...string) []Association {
+	var succeeded []Association
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("waiting for associations on %s", instanceId), int(Timeout/pollInterval), pollInterval,
+		func() (string, error) {
+			associations, err := AssociationsE(region, instanceId)
+			if err != nil {
+				return "", err
+			}
+			var waiting, failed []string
+			succeeded, waiting, failed = progress(associations, documents)
+			if len(failed) > 0 {
+				return "", retry.FatalError{Underlying: fmt.Errorf("failed associations: %s", strings.Join(failed, "; "))}
+			}
+			if len(waiting) > 0 {
+				return "", fmt.Errorf("waiting on %s", strings.Join(waiting, "; "))
+			}
+			return "", nil
+		})
+	require.NoError(t, err)
+	return succeeded
+}
+
+// AssertInventoried waits for the instance's first inventory collection to
+// be reported
+func AssertInventoried(t testing.TestingT, region string, instanceId string) {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("waiting for inventory of %s", instanceId), int(Timeout/pollInterval), pollInterval,
+		func() (string, error) {
+			client, err := newSsmClient(region)
+			if err != nil {
+				return "", err
+			}
+			out, err := client.ListInventoryEntries(&ssm.ListInventoryEntriesInput{
+				InstanceId: awssdk.String(instanceId),
+				TypeName:   awssdk.String(inventoryType),
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(out.Entries) == 0 {
+				return "", errors.New("no inventory reported yet")
+			}
+			return "", nil
+		})
+	require.NoError(t, err)
+}
+
+// Compliance returns the association compliance status reported for the
+// instance, by association ID
+func Compliance(t testing.TestingT, region string, instanceId string) map[string]string {
+	client, err := newSsmClient(region)
+	require.NoError(t, err)
+
+	statuses := map[string]string{}
+	err = client.ListComplianceItemsPages(&ssm.ListComplianceItemsInput{
+		ResourceIds:   awssdk.StringSlice([]string{instanceId}),
+		ResourceTypes: awssdk.StringSlice([]string{"ManagedInstance"}),
+		Filters: []*ssm.ComplianceStringFilter{{
+			Key:    awssdk.String("ComplianceType"),
+			Type:   awssdk.String(ssm.ComplianceQueryOperatorTypeEqual),
+			Values: awssdk.StringSlice([]string{"Association"}),
+		}},
+	}, func(page *ssm.ListComplianceItemsOutput, lastPage bool) bool {
+		for _, item := range page.ComplianceItems {
+			statuses[awssdk.StringValue(item.Id)] = awssdk.StringValue(item.Status)
+		}
+		return true
+	})
+	require.NoError(t, err)
+	return statuses
+}
+
+// nonCompliant lists the associations not reported COMPLIANT, including
+// those with no compliance item at all
+func nonCompliant(statuses map[string]string, associations []Association) []string {
+	var problems []string
+	for _, association := range associations {
+		status, ok := statuses[association.Id]
+		if !ok {
+			status = "not reported"
+		}
+		if status != ssm.ComplianceStatusCompliant {
+			problems = append(problems, fmt.Sprintf("%s (%s): %s", association.Document, association.Id, status))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// AssertCompliant asserts each association is reported COMPLIANT
+func AssertCompliant(t testing.TestingT, region string, instanceId string, associations []Association) {
+	for _, problem := range nonCompliant(Compliance(t, region, instanceId), associations) {
+		assert.Fail(t, "association not compliant", "%s on %s", problem, instanceId)
+	}
+}
+
+// AssertBaseline runs every check against the baseline documents. The
+// instance must already be registered with SSM.
+func AssertBaseline(t testing.TestingT, region string, instanceId string) {
+	associations := AssertAssociationsSucceed(t, region, instanceId, Baseline()...)
+	AssertInventoried(t, region, instanceId)
+	AssertCompliant(t, region, instanceId, associations)
+}
+
+func newSsmClient(region string) (*ssm.SSM, error) {
+	sess, err := backend.NewSessionE(region)
+	if err != nil {
+		return nil, err
+	}
+	return ssm.New(sess), nil
+}
@@ -0,0 +1,79 @@
+package ssmcompliance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocuments(t *testing.T) {
+	assert.Equal(t, DefaultBaseline, parseDocuments(""))
+	assert.Equal(t, DefaultBaseline, parseDocuments(" , "))
+	assert.Equal(t, []string{"AWS-GatherSoftwareInventory", "Custom-Hardening"}, parseDocuments("AWS-GatherSoftwareInventory, Custom-Hardening,"))
+}
+
+func TestProgress(t *testing.T) {
+	inventory := Association{Id: "a-1", Document: "AWS-GatherSoftwareInventory", Status: "Success"}
+	agent := Association{Id: "a-2", Document: "AmazonCloudWatch-ManageAgent", Status: "Pending", Detail: "Pending"}
+	agentFailed := Association{Id: "a-3", Document: "AmazonCloudWatch-ManageAgent", Status: "Failed", Detail: "Failed"}
+
+	cases := []struct {
+		name         string
+		associations []Association
+		succeeded    []Association
+		waiting      []string
+		failed       []string
+	}{
+		{
+			name:         "all succeeded",
+			associations: []Association{inventory, {Id: "a-4", Document: "AmazonCloudWatch-ManageAgent", Status: "Success"}},
+			succeeded:    []Association{inventory, {Id: "a-4", Document: "AmazonCloudWatch-ManageAgent", Status: "Success"}},
+		},
+		{
+			name:         "pending",
+			associations: []Association{inventory, agent},
+			succeeded:    []Association{inventory},
+			waiting:      []string{"AmazonCloudWatch-ManageAgent (a-2): Pending"},
+		},
+		{
+			name:         "not associated",
+			associations: []Association{inventory},
+			succeeded:    []Association{inventory},
+			waiting:      []string{"AmazonCloudWatch-ManageAgent: not associated"},
+		},
+		{
+			name:         "one of two associations failed",
+			associations: []Association{inventory, {Id: "a-4", Document: "AmazonCloudWatch-ManageAgent", Status: "Success"}, agentFailed},
+			succeeded:    []Association{inventory},
+			failed:       []string{"AmazonCloudWatch-ManageAgent (a-3): Failed"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			succeeded, waiting, failed := progress(tc.associations, DefaultBaseline)
+			assert.Equal(t, tc.succeeded, succeeded)
+			assert.Equal(t, tc.waiting, waiting)
+			assert.Equal(t, tc.failed, failed)
+		})
+	}
+}
+
+func TestNonCompliant(t *testing.T) {
+	associations := []Association{
+		{Id: "a-1", Document: "AWS-GatherSoftwareInventory"},
+		{Id: "a-2", Document: "AmazonCloudWatch-ManageAgent"},
+		{Id: "a-3", Document: "Custom-Hardening"},
+	}
+	statuses := map[string]string{
+		"a-1": "COMPLIANT",
+		"a-2": "NON_COMPLIANT",
+	}
+
+	assert.Equal(t, []string{
+		"AmazonCloudWatch-ManageAgent (a-2): NON_COMPLIANT",
+		"Custom-Hardening (a-3): not reported",
+	}, nonCompliant(statuses, associations))
+	assert.Empty(t, nonCompliant(statuses, associations[:1]))
+}
@@ -0,0 +1,127 @@
+// Package ssmexec runs shell commands on test instances through SSM Run
+// Command, for environments where SSH is blocked. Instances need the SSM
+// agent and an instance profile with AmazonSSMManagedInstanceCore.
+package ssmexec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// RegistrationTimeout bounds how long a new instance may take to show up
+	// in the SSM inventory
+	RegistrationTimeout = 10 * time.Minute
+
+	// CommandTimeout bounds a single command
+	CommandTimeout = 2 * time.Minute
+)
+
+// Result is the outcome of a command that ran to completion
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int64
+}
+
+// WaitForInstance blocks until the instance has registered with SSM
+func WaitForInstance(t testing.TestingT, region string, instanceId string) {
+	aws.WaitForSsmInstance(t, region, instanceId, RegistrationTimeout)
+}
+
+// Run executes command on the instance and returns its output. A command
+// exiting non-zero is a result, not an error; the test only fails when the
+// command could not be run at all.
+func Run(t testing.TestingT, region string, instanceId string, command string) *Result {
+	result, err := RunE(t, region, instanceId, command)
+	require.NoError(t, err, "running %q on %s", command, instanceId)
+	return result
+}
+
+// RunE is Run returning an error instead of failing the test
+func RunE(t testing.TestingT, region string, instanceId string, command string) (*Result, error) {
+	output, err := aws.CheckSsmCommandE(t, region, instanceId, command, CommandTimeout)
+	return toResult(output, err)
+}
+
+// toResult separates commands that ran and exited non-zero, which SSM
+// reports as a failed invocation, from commands that never ran
+func toResult(output *aws.CommandOutput, err error) (*Result, error) {
+	if output == nil {
+		if err == nil {
+			err = fmt.Errorf("no output returned")
+		}
+		return nil, err
+	}
+	result := &Result{Stdout: output.Stdout, Stderr: output.Stderr, ExitCode: output.ExitCode}
+	if err != nil && result.ExitCode <= 0 {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AssertSucceeds runs command, asserts it exits zero and returns its stdout
+func AssertSucceeds(t testing.TestingT, region string, instanceId string, command string) string {
+	result := Run(t, region, instanceId, command)
+	assert.Equal(t, int64(0), result.ExitCode, "%q should exit 0\n  stdout: %s\n  stderr: %s", command, result.Stdout, result.Stderr)
+	return result.Stdout
+}
+
+// AssertExitCode runs command and asserts its exit code
+func AssertExitCode(t testing.TestingT, region string, instanceId string, command string, exitCode int64) {
+	result := Run(t, region, instanceId, command)
+	assert.Equal(t, exitCode, result.ExitCode, "%q exit code\n  stdout: %s\n  stderr: %s", command, result.Stdout, result.Stderr)
+}
+
+// AssertStdoutContains runs command and asserts its stdout contains expected
+func AssertStdoutContains(t testing.TestingT, region string, instanceId string, command string, expected string) {
+	stdout := AssertSucceeds(t, region, instanceId, command)
+	assert.Contains(t, stdout, expected, "stdout of %q", command)
+}
+
+// AssertPackageInstalled asserts an RPM package is installed, e.g. one the
+// user data installs
+func AssertPackageInstalled(t testing.TestingT, region string, instanceId string, pkg string) {
+	AssertExitCode(t, region, instanceId, fmt.Sprintf("rpm -q %s", pkg), 0)
+}
+
+// Mount is a mounted filesystem as findmnt reports it
+type Mount struct {
+	Source     string
+	FSType     string
+	MountPoint string
+}
+
+// Mounts returns the filesystems mounted on the instance keyed by mount point
+func Mounts(t testing.TestingT, region string, instanceId string) map[string]Mount {
+	stdout := AssertSucceeds(t, region, instanceId, "findmnt --list --noheadings --output SOURCE,FSTYPE,TARGET")
+	return parseMounts(stdout)
+}
+
+func parseMounts(output string) map[string]Mount {
+	mounts := map[string]Mount{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		mounts[fields[2]] = Mount{Source: fields[0], FSType: fields[1], MountPoint: fields[2]}
+	}
+	return mounts
+}
+
+// AssertMounted asserts a filesystem of the given type is mounted at
+// mountPoint and returns it
+func AssertMounted(t testing.TestingT, region string, instanceId string, mountPoint string, fsType string) Mount {
+	mount, ok := Mounts(t, region, instanceId)[mountPoint]
+	if assert.True(t, ok, "%s should be mounted", mountPoint) {
+		assert.Equal(t, fsType, mount.FSType, "filesystem type at %s", mountPoint)
+	}
+	return mount
+}
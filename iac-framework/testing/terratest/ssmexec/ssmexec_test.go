@@ -0,0 +1,36 @@
+package ssmexec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToResult(t *testing.T) {
+	result, err := toResult(&aws.CommandOutput{Stdout: "ok\n", ExitCode: 0}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Stdout: "ok\n"}, result)
+
+	result, err = toResult(&aws.CommandOutput{Stderr: "package httpd is not installed", ExitCode: 1}, errors.New("failed to run commands: exit status 1"))
+	require.NoError(t, err, "A command exiting non-zero still ran")
+	assert.Equal(t, int64(1), result.ExitCode)
+
+	_, err = toResult(&aws.CommandOutput{ExitCode: -1}, errors.New("unexpected error: timed out"))
+	assert.EqualError(t, err, "unexpected error: timed out")
+
+	_, err = toResult(nil, errors.New("InvalidInstanceId"))
+	assert.EqualError(t, err, "InvalidInstanceId")
+}
+
+func TestParseMounts(t *testing.T) {
+	output := `/dev/nvme0n1p1 xfs  /
+/dev/nvme1n1   ext4 /data
+tmpfs          tmpfs /run/user/1000
+`
+	mounts := parseMounts(output)
+	assert.Len(t, mounts, 3)
+	assert.Equal(t, Mount{Source: "/dev/nvme1n1", FSType: "ext4", MountPoint: "/data"}, mounts["/data"])
+}
@@ -0,0 +1,640 @@
+// Package sweeper finds and deletes AWS resources leaked by interrupted test
+// runs. harness.Track tags everything a suite creates with TerratestRun,
+// whatever the module does with its other tags, so anything still carrying
+// that tag well after a run should have finished is garbage.
+package sweeper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/gruntwork-io/terratest/modules/aws"
+)
+
+const (
+	// DefaultTagKey is the tag harness.Track adds to every workspace, valued
+	// with the run's ID. The modules set Project themselves, so it cannot
+	// tell test resources apart.
+	DefaultTagKey = "TerratestRun"
+
+	// DefaultOlderThan is long enough that a healthy run has finished
+	DefaultOlderThan = 6 * time.Hour
+)
+
+// Resource types reported by the sweeper
+const (
+	TypeInstance   = "ec2-instance"
+	TypeNatGateway = "nat-gateway"
+	TypeAddress    = "elastic-ip"
+	TypeVolume     = "ebs-volume"
+	TypeLogGroup   = "log-group"
+	TypeRole       = "iam:role"
+
+	TypeNetworkInterface = "network-interface"
+)
+
+// Options controls what is swept and where
+type Options struct {
+	Regions []string
+	TagKey  string
+
+	// TagValue restricts the sweep to one value of TagKey, such as one
+	// run's ID; empty matches any value
+	TagValue  string
+	OlderThan time.Duration
+	DryRun    bool
+
+	// Logf receives one line per resource found; defaults to discarding
+	Logf func(format string, args ...interface{})
+}
+
+// Resource is a leaked resource found by the sweeper
+type Resource struct {
+	Type      string
+	ID        string
+	Region    string
+	CreatedAt time.Time
+}
+
+func (r Resource) String() string {
+	if r.CreatedAt.IsZero() {
+		return fmt.Sprintf("%s %s (%s)", r.Type, r.ID, r.Region)
+	}
+	return fmt.Sprintf("%s %s (%s, created %s)", r.Type, r.ID, r.Region, r.CreatedAt.UTC().Format(time.RFC3339))
+}
+
+func (o Options) withDefaults() Options {
+	if o.TagKey == "" {
+		o.TagKey = DefaultTagKey
+	}
+	if o.OlderThan == 0 {
+		o.OlderThan = DefaultOlderThan
+	}
+	if o.Logf == nil {
+		o.Logf = func(string, ...interface{}) {}
+	}
+	return o
+}
+
+// Sweep finds leaked resources in every requested region and, unless DryRun
+// is set, deletes them. It returns everything it found; deletion failures
+// are collected into the returned error rather than stopping the sweep.
+func Sweep(opts Options) ([]Resource, error) {
+	opts = opts.withDefaults()
+	if len(opts.Regions) == 0 {
+		return nil, errors.New("sweeper: at least one region is required")
+	}
+
+	cutoff := time.Now().Add(-opts.OlderThan)
+
+	var found []Resource
+	var errs []error
+	for _, region := range opts.Regions {
+		sess, err := aws.NewAuthenticatedSession(region)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", region, err))
+			continue
+		}
+
+		resources, err := sweepRegion(sess, region, opts, cutoff)
+		found = append(found, resources...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", region, err))
+		}
+	}
+
+	return found, errors.Join(errs...)
+}
+
+// sweepRegion handles one region. Instances go first, then the interfaces
+// they leave detached, and NAT gateways before addresses so that dependants
+// are released before what they hold on to.
+func sweepRegion(sess *session.Session, region string, opts Options, cutoff time.Time) ([]Resource, error) {
+	ec2Client := ec2.New(sess)
+	logsClient := cloudwatchlogs.New(sess)
+
+	steps := []struct {
+		find   func() ([]Resource, error)
+		delete func(Resource) error
+	}{
+		{
+			find:   func() ([]Resource, error) { return findInstances(ec2Client, region, opts, cutoff) },
+			delete: func(r Resource) error { return terminateInstance(ec2Client, r) },
+		},
+		{
+			find:   func() ([]Resource, error) { return findNetworkInterfaces(ec2Client, region, opts) },
+			delete: func(r Resource) error { return deleteNetworkInterface(ec2Client, r) },
+		},
+		{
+			find:   func() ([]Resource, error) { return findNatGateways(ec2Client, region, opts, cutoff) },
+			delete: func(r Resource) error { return deleteNatGateway(ec2Client, r) },
+		},
+		{
+			find:   func() ([]Resource, error) { return findAddresses(ec2Client, region, opts) },
+			delete: func(r Resource) error { return releaseAddress(ec2Client, r) },
+		},
+		{
+			find:   func() ([]Resource, error) { return findVolumes(ec2Client, region, opts, cutoff) },
+			delete: func(r Resource) error { return deleteVolume(ec2Client, r) },
+		},
+		{
+			find:   func() ([]Resource, error) { return findLogGroups(logsClient, region, opts, cutoff) },
+			delete: func(r Resource) error { return deleteLogGroup(logsClient, r) },
+		},
+	}
+
+	var found []Resource
+	var errs []error
+	for _, step := range steps {
+		resources, err := step.find()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, resource := range resources {
+			found = append(found, resource)
+			if opts.DryRun {
+				opts.Logf("would delete %s", resource)
+				continue
+			}
+
+			opts.Logf("deleting %s", resource)
+			if err := step.delete(resource); err != nil {
+				errs = append(errs, fmt.Errorf("deleting %s %s: %w", resource.Type, resource.ID, err))
+			}
+		}
+	}
+
+	return found, errors.Join(errs...)
+}
+
+// Orphans finds everything in region tagged tagKey=tagValue, whatever its
+// age, without deleting anything. With a tag unique to one test it tells
+// what that test's destroy left behind.
+func Orphans(region string, tagKey string, tagValue string) ([]Resource, error) {
+	opts := Options{TagKey: tagKey, TagValue: tagValue, DryRun: true}.withDefaults()
+
+	sess, err := aws.NewAuthenticatedSession(region)
+	if err != nil {
+		return nil, err
+	}
+	return sweepRegion(sess, region, opts, time.Now())
+}
+
+// sweptTypes are the resource types Sweep has a step for, as the service
+// and resource type of their ARNs
+var sweptTypes = map[string]bool{
+	"ec2:instance":          true,
+	"ec2:network-interface": true,
+	"ec2:natgateway":        true,
+	"ec2:elastic-ip":        true,
+	"ec2:volume":            true,
+	"logs:log-group":        true,
+}
+
+// Unswept finds what carries the tag but is of a type Sweep cannot delete,
+// such as VPCs, subnets, security groups and IAM roles, in every requested
+// region and in IAM. Anything it returns has to be cleaned up by hand.
+// Resources are reported by ARN, with their type as service:type.
+func Unswept(opts Options) ([]Resource, error) {
+	opts = opts.withDefaults()
+	if len(opts.Regions) == 0 {
+		return nil, errors.New("sweeper: at least one region is required")
+	}
+
+	var found []Resource
+	var errs []error
+	for i, region := range opts.Regions {
+		sess, err := aws.NewAuthenticatedSession(region)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", region, err))
+			continue
+		}
+
+		var mappings []*resourcegroupstaggingapi.ResourceTagMapping
+		err = resourcegroupstaggingapi.New(sess).GetResourcesPages(&resourcegroupstaggingapi.GetResourcesInput{
+			TagFilters: []*resourcegroupstaggingapi.TagFilter{taggingFilter(opts)},
+		}, func(page *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+			mappings = append(mappings, page.ResourceTagMappingList...)
+			return true
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: listing tagged resources: %w", region, err))
+		}
+		found = append(found, selectUnswept(mappings, region)...)
+
+		// IAM is global, so it is only listed once
+		if i == 0 {
+			roles, err := findRoles(iam.New(sess), opts)
+			found = append(found, roles...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return found, errors.Join(errs...)
+}
+
+func taggingFilter(opts Options) *resourcegroupstaggingapi.TagFilter {
+	filter := &resourcegroupstaggingapi.TagFilter{Key: awssdk.String(opts.TagKey)}
+	if opts.TagValue != "" {
+		filter.Values = awssdk.StringSlice([]string{opts.TagValue})
+	}
+	return filter
+}
+
+// resourceType is the service:type of an ARN, e.g. "ec2:vpc" for
+// arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1
+func resourceType(resourceArn string) (string, error) {
+	parsed, err := arn.Parse(resourceArn)
+	if err != nil {
+		return "", err
+	}
+	kind := parsed.Resource
+	if i := strings.IndexAny(kind, "/:"); i >= 0 {
+		kind = kind[:i]
+	}
+	return parsed.Service + ":" + kind, nil
+}
+
+// selectUnswept drops the mappings of types Sweep deletes. The tagging API
+// keeps listing deleted resources for a while, so those would only be noise.
+func selectUnswept(mappings []*resourcegroupstaggingapi.ResourceTagMapping, region string) []Resource {
+	var resources []Resource
+	for _, mapping := range mappings {
+		resourceArn := awssdk.StringValue(mapping.ResourceARN)
+		kind, err := resourceType(resourceArn)
+		if err != nil {
+			kind = "unknown"
+		}
+		if sweptTypes[kind] {
+			continue
+		}
+		resources = append(resources, Resource{Type: kind, ID: resourceArn, Region: region})
+	}
+	return resources
+}
+
+// findRoles lists the IAM roles carrying the tag; the tagging API does not
+// cover IAM, and roles only report their tags one at a time
+func findRoles(client iamiface.IAMAPI, opts Options) ([]Resource, error) {
+	var roles []*iam.Role
+	err := client.ListRolesPages(&iam.ListRolesInput{}, func(page *iam.ListRolesOutput, lastPage bool) bool {
+		roles = append(roles, page.Roles...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing IAM roles: %w", err)
+	}
+
+	var resources []Resource
+	for _, role := range roles {
+		// Service-linked roles belong to AWS and cannot be tagged by a run
+		if strings.HasPrefix(awssdk.StringValue(role.Path), "/aws-service-role/") {
+			continue
+		}
+		tags, err := client.ListRoleTags(&iam.ListRoleTagsInput{RoleName: role.RoleName})
+		if err != nil {
+			return resources, fmt.Errorf("listing tags for IAM role %s: %w", awssdk.StringValue(role.RoleName), err)
+		}
+		for _, tag := range tags.Tags {
+			if awssdk.StringValue(tag.Key) == opts.TagKey && tagMatches(opts, awssdk.StringValue(tag.Value)) {
+				resources = append(resources, Resource{
+					Type:      TypeRole,
+					ID:        awssdk.StringValue(role.Arn),
+					Region:    "global",
+					CreatedAt: awssdk.TimeValue(role.CreateDate),
+				})
+				break
+			}
+		}
+	}
+	return resources, nil
+}
+
+// EnabledRegions lists every region enabled for the current account
+func EnabledRegions() ([]string, error) {
+	sess, err := aws.NewAuthenticatedSession("us-east-1")
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := ec2.New(sess).DescribeRegions(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("describing regions: %w", err)
+	}
+
+	var regions []string
+	for _, region := range output.Regions {
+		regions = append(regions, awssdk.StringValue(region.RegionName))
+	}
+	return regions, nil
+}
+
+func tagFilter(opts Options) *ec2.Filter {
+	if opts.TagValue == "" {
+		return &ec2.Filter{
+			Name:   awssdk.String("tag-key"),
+			Values: awssdk.StringSlice([]string{opts.TagKey}),
+		}
+	}
+	return &ec2.Filter{
+		Name:   awssdk.String("tag:" + opts.TagKey),
+		Values: awssdk.StringSlice([]string{opts.TagValue}),
+	}
+}
+
+// tagMatches reports whether a value of TagKey is one the sweep is after
+func tagMatches(opts Options, value string) bool {
+	return opts.TagValue == "" || value == opts.TagValue
+}
+
+// expired reports whether a resource created at created is past the cutoff.
+// Resources without a creation time are never considered expired.
+func expired(created *time.Time, cutoff time.Time) bool {
+	return created != nil && !created.IsZero() && created.Before(cutoff)
+}
+
+func findInstances(client ec2iface.EC2API, region string, opts Options, cutoff time.Time) ([]Resource, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			tagFilter(opts),
+			{
+				Name:   awssdk.String("instance-state-name"),
+				Values: awssdk.StringSlice([]string{"pending", "running", "stopping", "stopped"}),
+			},
+		},
+	}
+
+	var reservations []*ec2.Reservation
+	err := client.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		reservations = append(reservations, page.Reservations...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing instances: %w", err)
+	}
+
+	return selectInstances(reservations, region, cutoff), nil
+}
+
+func selectInstances(reservations []*ec2.Reservation, region string, cutoff time.Time) []Resource {
+	var resources []Resource
+	for _, reservation := range reservations {
+		for _, instance := range reservation.Instances {
+			if !expired(instance.LaunchTime, cutoff) {
+				continue
+			}
+			resources = append(resources, Resource{
+				Type:      TypeInstance,
+				ID:        awssdk.StringValue(instance.InstanceId),
+				Region:    region,
+				CreatedAt: awssdk.TimeValue(instance.LaunchTime),
+			})
+		}
+	}
+	return resources
+}
+
+func terminateInstance(client ec2iface.EC2API, r Resource) error {
+	_, err := client.TerminateInstances(&ec2.TerminateInstancesInput{
+		InstanceIds: awssdk.StringSlice([]string{r.ID}),
+	})
+	return err
+}
+
+// findNetworkInterfaces returns tagged network interfaces that are not
+// attached. Like addresses they have no creation time, and an attached one
+// goes away with its instance.
+func findNetworkInterfaces(client ec2iface.EC2API, region string, opts Options) ([]Resource, error) {
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []*ec2.Filter{
+			tagFilter(opts),
+			{
+				Name:   awssdk.String("status"),
+				Values: awssdk.StringSlice([]string{"available"}),
+			},
+		},
+	}
+
+	var interfaces []*ec2.NetworkInterface
+	err := client.DescribeNetworkInterfacesPages(input, func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+		interfaces = append(interfaces, page.NetworkInterfaces...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing network interfaces: %w", err)
+	}
+
+	var resources []Resource
+	for _, networkInterface := range interfaces {
+		resources = append(resources, Resource{
+			Type:   TypeNetworkInterface,
+			ID:     awssdk.StringValue(networkInterface.NetworkInterfaceId),
+			Region: region,
+		})
+	}
+	return resources, nil
+}
+
+func deleteNetworkInterface(client ec2iface.EC2API, r Resource) error {
+	_, err := client.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
+		NetworkInterfaceId: awssdk.String(r.ID),
+	})
+	return err
+}
+
+func findNatGateways(client ec2iface.EC2API, region string, opts Options, cutoff time.Time) ([]Resource, error) {
+	input := &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{
+			tagFilter(opts),
+			{
+				Name:   awssdk.String("state"),
+				Values: awssdk.StringSlice([]string{"pending", "available"}),
+			},
+		},
+	}
+
+	var gateways []*ec2.NatGateway
+	err := client.DescribeNatGatewaysPages(input, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		gateways = append(gateways, page.NatGateways...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing NAT gateways: %w", err)
+	}
+
+	return selectNatGateways(gateways, region, cutoff), nil
+}
+
+func selectNatGateways(gateways []*ec2.NatGateway, region string, cutoff time.Time) []Resource {
+	var resources []Resource
+	for _, gateway := range gateways {
+		if !expired(gateway.CreateTime, cutoff) {
+			continue
+		}
+		resources = append(resources, Resource{
+			Type:      TypeNatGateway,
+			ID:        awssdk.StringValue(gateway.NatGatewayId),
+			Region:    region,
+			CreatedAt: awssdk.TimeValue(gateway.CreateTime),
+		})
+	}
+	return resources
+}
+
+func deleteNatGateway(client ec2iface.EC2API, r Resource) error {
+	_, err := client.DeleteNatGateway(&ec2.DeleteNatGatewayInput{
+		NatGatewayId: awssdk.String(r.ID),
+	})
+	return err
+}
+
+// findAddresses returns tagged Elastic IPs that are no longer associated.
+// EIPs carry no creation time, so association is the only safe signal that a
+// run has let go of them; ones freed by NAT gateway deletion in this sweep
+// are picked up by the next one.
+func findAddresses(client ec2iface.EC2API, region string, opts Options) ([]Resource, error) {
+	output, err := client.DescribeAddresses(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{tagFilter(opts)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing addresses: %w", err)
+	}
+
+	return selectAddresses(output.Addresses, region), nil
+}
+
+func selectAddresses(addresses []*ec2.Address, region string) []Resource {
+	var resources []Resource
+	for _, address := range addresses {
+		if address.AssociationId != nil {
+			continue
+		}
+		resources = append(resources, Resource{
+			Type:   TypeAddress,
+			ID:     awssdk.StringValue(address.AllocationId),
+			Region: region,
+		})
+	}
+	return resources
+}
+
+func releaseAddress(client ec2iface.EC2API, r Resource) error {
+	_, err := client.ReleaseAddress(&ec2.ReleaseAddressInput{
+		AllocationId: awssdk.String(r.ID),
+	})
+	return err
+}
+
+func findVolumes(client ec2iface.EC2API, region string, opts Options, cutoff time.Time) ([]Resource, error) {
+	input := &ec2.DescribeVolumesInput{
+		Filters: []*ec2.Filter{
+			tagFilter(opts),
+			{
+				Name:   awssdk.String("status"),
+				Values: awssdk.StringSlice([]string{"available"}),
+			},
+		},
+	}
+
+	var volumes []*ec2.Volume
+	err := client.DescribeVolumesPages(input, func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+		volumes = append(volumes, page.Volumes...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing volumes: %w", err)
+	}
+
+	return selectVolumes(volumes, region, cutoff), nil
+}
+
+func selectVolumes(volumes []*ec2.Volume, region string, cutoff time.Time) []Resource {
+	var resources []Resource
+	for _, volume := range volumes {
+		if !expired(volume.CreateTime, cutoff) {
+			continue
+		}
+		resources = append(resources, Resource{
+			Type:      TypeVolume,
+			ID:        awssdk.StringValue(volume.VolumeId),
+			Region:    region,
+			CreatedAt: awssdk.TimeValue(volume.CreateTime),
+		})
+	}
+	return resources
+}
+
+func deleteVolume(client ec2iface.EC2API, r Resource) error {
+	_, err := client.DeleteVolume(&ec2.DeleteVolumeInput{
+		VolumeId: awssdk.String(r.ID),
+	})
+	return err
+}
+
+// findLogGroups filters on age first since log group tags need a call per group
+func findLogGroups(client cloudwatchlogsiface.CloudWatchLogsAPI, region string, opts Options, cutoff time.Time) ([]Resource, error) {
+	var groups []*cloudwatchlogs.LogGroup
+	err := client.DescribeLogGroupsPages(&cloudwatchlogs.DescribeLogGroupsInput{}, func(page *cloudwatchlogs.DescribeLogGroupsOutput, lastPage bool) bool {
+		groups = append(groups, page.LogGroups...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing log groups: %w", err)
+	}
+
+	var resources []Resource
+	for _, group := range selectLogGroups(groups, region, cutoff) {
+		tags, err := client.ListTagsLogGroup(&cloudwatchlogs.ListTagsLogGroupInput{
+			LogGroupName: awssdk.String(group.ID),
+		})
+		if err != nil {
+			return resources, fmt.Errorf("listing tags for log group %s: %w", group.ID, err)
+		}
+		if value, ok := tags.Tags[opts.TagKey]; ok && tagMatches(opts, awssdk.StringValue(value)) {
+			resources = append(resources, group)
+		}
+	}
+	return resources, nil
+}
+
+func selectLogGroups(groups []*cloudwatchlogs.LogGroup, region string, cutoff time.Time) []Resource {
+	var resources []Resource
+	for _, group := range groups {
+		if group.CreationTime == nil {
+			continue
+		}
+		created := time.UnixMilli(awssdk.Int64Value(group.CreationTime))
+		if !expired(&created, cutoff) {
+			continue
+		}
+		resources = append(resources, Resource{
+			Type:      TypeLogGroup,
+			ID:        awssdk.StringValue(group.LogGroupName),
+			Region:    region,
+			CreatedAt: created,
+		})
+	}
+	return resources
+}
+
+func deleteLogGroup(client cloudwatchlogsiface.CloudWatchLogsAPI, r Resource) error {
+	_, err := client.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{
+		LogGroupName: awssdk.String(r.ID),
+	})
+	return err
+}
@@ -0,0 +1,124 @@
+package sweeper
+
+import (
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpired(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	before := cutoff.Add(-time.Minute)
+	after := cutoff.Add(time.Minute)
+	zero := time.Time{}
+
+	cases := []struct {
+		name    string
+		created *time.Time
+		want    bool
+	}{
+		{"nil creation time", nil, false},
+		{"zero creation time", &zero, false},
+		{"created before cutoff", &before, true},
+		{"created at cutoff", &cutoff, false},
+		{"created after cutoff", &after, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, expired(tc.created, cutoff))
+		})
+	}
+}
+
+func TestSelectInstances(t *testing.T) {
+	cutoff := time.Now().Add(-DefaultOlderThan)
+	old := cutoff.Add(-time.Hour)
+	fresh := cutoff.Add(time.Hour)
+
+	reservations := []*ec2.Reservation{
+		{Instances: []*ec2.Instance{
+			{InstanceId: awssdk.String("i-old"), LaunchTime: &old},
+			{InstanceId: awssdk.String("i-fresh"), LaunchTime: &fresh},
+		}},
+		{Instances: []*ec2.Instance{
+			{InstanceId: awssdk.String("i-unknown")},
+		}},
+	}
+
+	resources := selectInstances(reservations, "us-west-2", cutoff)
+	assert.Equal(t, []Resource{{Type: TypeInstance, ID: "i-old", Region: "us-west-2", CreatedAt: old}}, resources)
+}
+
+func TestSelectAddressesSkipsAssociated(t *testing.T) {
+	addresses := []*ec2.Address{
+		{AllocationId: awssdk.String("eipalloc-free")},
+		{AllocationId: awssdk.String("eipalloc-held"), AssociationId: awssdk.String("eipassoc-1")},
+	}
+
+	resources := selectAddresses(addresses, "us-east-1")
+	assert.Equal(t, []Resource{{Type: TypeAddress, ID: "eipalloc-free", Region: "us-east-1"}}, resources)
+}
+
+func TestSelectLogGroups(t *testing.T) {
+	cutoff := time.Now().Add(-DefaultOlderThan)
+	old := cutoff.Add(-time.Hour).Truncate(time.Millisecond)
+	fresh := cutoff.Add(time.Hour)
+
+	groups := []*cloudwatchlogs.LogGroup{
+		{LogGroupName: awssdk.String("/vpc-flow-logs/old"), CreationTime: awssdk.Int64(old.UnixMilli())},
+		{LogGroupName: awssdk.String("/vpc-flow-logs/fresh"), CreationTime: awssdk.Int64(fresh.UnixMilli())},
+		{LogGroupName: awssdk.String("/no-creation-time")},
+	}
+
+	resources := selectLogGroups(groups, "us-west-2", cutoff)
+	if assert.Len(t, resources, 1) {
+		assert.Equal(t, "/vpc-flow-logs/old", resources[0].ID)
+		assert.True(t, old.Equal(resources[0].CreatedAt))
+	}
+}
+
+func TestSelectUnswept(t *testing.T) {
+	mappings := []*resourcegroupstaggingapi.ResourceTagMapping{
+		{ResourceARN: awssdk.String("arn:aws:ec2:us-east-1:123456789012:instance/i-swept")},
+		{ResourceARN: awssdk.String("arn:aws:logs:us-east-1:123456789012:log-group:/vpc-flow-logs/swept")},
+		{ResourceARN: awssdk.String("arn:aws:ec2:us-east-1:123456789012:vpc/vpc-left")},
+		{ResourceARN: awssdk.String("arn:aws:ec2:us-east-1:123456789012:security-group/sg-left")},
+		{ResourceARN: awssdk.String("not-an-arn")},
+	}
+
+	resources := selectUnswept(mappings, "us-east-1")
+	assert.Equal(t, []Resource{
+		{Type: "ec2:vpc", ID: "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-left", Region: "us-east-1"},
+		{Type: "ec2:security-group", ID: "arn:aws:ec2:us-east-1:123456789012:security-group/sg-left", Region: "us-east-1"},
+		{Type: "unknown", ID: "not-an-arn", Region: "us-east-1"},
+	}, resources)
+}
+
+func TestUnsweptRequiresRegion(t *testing.T) {
+	_, err := Unswept(Options{})
+	assert.Error(t, err)
+}
+
+func TestSweepRequiresRegion(t *testing.T) {
+	_, err := Sweep(Options{DryRun: true})
+	assert.Error(t, err)
+}
+
+func TestTagFilter(t *testing.T) {
+	anyValue := tagFilter(Options{}.withDefaults())
+	assert.Equal(t, "tag-key", awssdk.StringValue(anyValue.Name))
+	assert.Equal(t, []string{DefaultTagKey}, awssdk.StringValueSlice(anyValue.Values))
+
+	oneRun := tagFilter(Options{TagValue: "20240101T000000Z-abc123"}.withDefaults())
+	assert.Equal(t, "tag:"+DefaultTagKey, awssdk.StringValue(oneRun.Name))
+	assert.Equal(t, []string{"20240101T000000Z-abc123"}, awssdk.StringValueSlice(oneRun.Values))
+
+	assert.True(t, tagMatches(Options{}, "anything"))
+	assert.False(t, tagMatches(Options{TagValue: "one"}, "other"))
+}
@@ -0,0 +1,123 @@
+// Package tagcheck asserts that every taggable resource a module creates
+// carries the tags cost allocation and ownership lookups depend on. It reads
+// terraform's JSON state or plan rather than calling AWS per resource type,
+// so a new resource in a module is covered without touching the tests.
+package tagcheck
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/tfshow"
+)
+
+// DefaultRequired is the tag set every resource in the organisation needs
+var DefaultRequired = []string{"Environment", "Project", "Owner", "CostCenter"}
+
+// Resource is a managed resource and its effective tags. Taggable is false
+// for resource types without a tags argument.
+type Resource struct {
+	Address  string
+	Taggable bool
+	Tags     map[string]string
+}
+
+// Violation is a taggable resource missing required tags
+type Violation struct {
+	Address string
+	Missing []string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s is missing tags %s", v.Address, strings.Join(v.Missing, ", "))
+}
+
+// Parse reads the managed resources out of terraform show -json output,
+// for either state or a saved plan
+func Parse(data []byte) ([]Resource, error) {
+	shown, err := tfshow.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]Resource, 0, len(shown))
+	for _, resource := range shown {
+		tags, taggable := effectiveTags(resource.Values)
+		resources = append(resources, Resource{Address: resource.Address, Taggable: taggable, Tags: tags})
+	}
+	return resources, nil
+}
+
+// effectiveTags prefers tags_all, which includes the provider's
+// default_tags, and falls back to tags where tags_all is unknown until apply
+func effectiveTags(values map[string]interface{}) (map[string]string, bool) {
+	allTags, hasAll := values["tags_all"]
+	tags, hasTags := values["tags"]
+	if !hasAll && !hasTags {
+		return nil, false
+	}
+
+	source := allTags
+	if source == nil {
+		source = tags
+	}
+	out := map[string]string{}
+	if m, ok := source.(map[string]interface{}); ok {
+		for key, value := range m {
+			out[key] = fmt.Sprint(value)
+		}
+	}
+	return out, true
+}
+
+// Check returns the taggable resources missing any of the required tags.
+// A tag with an empty value counts as missing.
+func Check(resources []Resource, required []string) []Violation {
+	var violations []Violation
+	for _, resource := range resources {
+		if !resource.Taggable {
+			continue
+		}
+		var missing []string
+		for _, key := range required {
+			if resource.Tags[key] == "" {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			violations = append(violations, Violation{Address: resource.Address, Missing: missing})
+		}
+	}
+	return violations
+}
+
+// AssertState checks the applied state of options. With no tags given,
+// DefaultRequired is used.
+func AssertState(t *testing.T, options *terraform.Options, required ...string) {
+	assertShow(t, terraform.Show(t, options), required)
+}
+
+// AssertPlan plans options and checks what would be created, so a missing
+// tag is caught without applying
+func AssertPlan(t *testing.T, options *terraform.Options, required ...string) {
+	planOptions := *options
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "tagcheck.tfplan")
+	assertShow(t, terraform.InitAndPlanAndShow(t, &planOptions), required)
+}
+
+func assertShow(t *testing.T, show string, required []string) {
+	if len(required) == 0 {
+		required = DefaultRequired
+	}
+	resources, err := Parse([]byte(show))
+	require.NoError(t, err)
+	for _, violation := range Check(resources, required) {
+		assert.Fail(t, "Resource is missing required tags", violation.String())
+	}
+}
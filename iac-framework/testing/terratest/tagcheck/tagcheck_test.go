@@ -0,0 +1,103 @@
+package tagcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleState = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_vpc.main",
+          "mode": "managed",
+          "values": {
+            "tags": {"Name": "main"},
+            "tags_all": {"Name": "main", "Environment": "test", "Project": "terratest", "Owner": "platform", "CostCenter": "cc-1"}
+          }
+        },
+        {
+          "address": "aws_subnet.public[0]",
+          "mode": "managed",
+          "values": {"tags": {"Environment": "test", "Project": ""}, "tags_all": null}
+        },
+        {
+          "address": "aws_route_table_association.public[0]",
+          "mode": "managed",
+          "values": {"subnet_id": "subnet-1"}
+        },
+        {
+          "address": "data.aws_availability_zones.available",
+          "mode": "data",
+          "values": {"names": ["us-west-2a"]}
+        }
+      ],
+      "child_modules": [
+        {
+          "resources": [
+            {
+              "address": "module.logs.aws_cloudwatch_log_group.this",
+              "mode": "managed",
+              "values": {"tags": null, "tags_all": {}}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestParse(t *testing.T) {
+	resources, err := Parse([]byte(sampleState))
+	require.NoError(t, err)
+
+	addresses := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	assert.Equal(t, []string{
+		"aws_route_table_association.public[0]",
+		"aws_subnet.public[0]",
+		"aws_vpc.main",
+		"module.logs.aws_cloudwatch_log_group.this",
+	}, addresses, "Data sources should be skipped and child modules walked")
+
+	assert.False(t, resources[0].Taggable)
+	assert.Equal(t, map[string]string{"Environment": "test", "Project": ""}, resources[1].Tags,
+		"tags should be used when tags_all is unknown")
+	assert.Equal(t, "platform", resources[2].Tags["Owner"], "tags_all should include provider default tags")
+}
+
+func TestParsePlan(t *testing.T) {
+	resources, err := Parse([]byte(`{
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_eip.nat[0]", "mode": "managed", "values": {"tags": {"Project": "terratest"}}}
+      ]
+    }
+  }
+}`))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "terratest", resources[0].Tags["Project"])
+}
+
+func TestCheck(t *testing.T) {
+	resources, err := Parse([]byte(sampleState))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Violation{
+		{Address: "aws_subnet.public[0]", Missing: []string{"Project", "Owner", "CostCenter"}},
+		{Address: "module.logs.aws_cloudwatch_log_group.this", Missing: DefaultRequired},
+	}, Check(resources, DefaultRequired))
+
+	assert.Equal(t,
+		"aws_subnet.public[0] is missing tags Project, Owner, CostCenter",
+		Violation{Address: "aws_subnet.public[0]", Missing: []string{"Project", "Owner", "CostCenter"}}.String(),
+	)
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// TestTerragruntStack runs the vpc-ec2 fixture stack end to end: the ec2 unit
+// validates against the vpc unit's mock outputs first, then run-all apply
+// wires it to the real public subnet
+func TestTerragruntStack(t *testing.T) {
+	t.Parallel()
+	backend.SkipOnLocalStack(t, "the stack units get no LocalStack provider endpoints")
+
+	terragruntOptions := harness.TerragruntStack(t, "vpc-ec2", "dev")
+	terragruntOptions.EnvVars["AWS_DEFAULT_REGION"] = regions.PickRegion(t,
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(quotas.VPCs(1), quotas.InternetGateways(1)))
+
+	// An error here means the units are wired to outputs that do not exist
+	harness.RunAllValidate(t, terragruntOptions)
+
+	harness.Track(t, terragruntOptions)
+	defer harness.RunAllDestroy(t, terragruntOptions)
+
+	harness.RunAllApply(t, terragruntOptions)
+
+	vpcOptions := harness.TerragruntUnit(terragruntOptions, "vpc")
+	ec2Options := harness.TerragruntUnit(terragruntOptions, "ec2")
+
+	vpcId := terraform.Output(t, vpcOptions, "vpc_id")
+	assert.NotEmpty(t, vpcId, "VPC unit should output its ID")
+
+	publicSubnets := terraform.OutputList(t, vpcOptions, "public_subnets")
+	subnetIds := terraform.OutputList(t, ec2Options, "instance_subnet_ids")
+	require.NotEmpty(t, subnetIds, "EC2 unit should create an instance")
+	assert.Subset(t, publicSubnets, subnetIds, "EC2 unit should be placed in the VPC unit's public subnets")
+
+	for _, publicIp := range terraform.OutputList(t, ec2Options, "instance_public_ips") {
+		assert.NotEmpty(t, publicIp, "Instances in the public subnet should get a public IP")
+	}
+}
@@ -0,0 +1,110 @@
+// Package tfshow reads resources out of terraform show -json, for state and
+// saved plans alike, so checks can walk everything a module manages without
+// knowing its resource types in advance.
+package tfshow
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Resource is one managed resource with its attribute values. Values not
+// known until apply are absent from a plan.
+type Resource struct {
+	Address string
+	Type    string
+	Values  map[string]interface{}
+}
+
+// String returns a string attribute, or "" if it is unset or not a string
+func (r Resource) String(attribute string) string {
+	value, _ := r.Values[attribute].(string)
+	return value
+}
+
+// Block returns the attributes of a single nested block, such as an AKS
+// cluster's network_profile, or nil if the resource has none
+func (r Resource) Block(name string) map[string]interface{} {
+	blocks, _ := r.Values[name].([]interface{})
+	if len(blocks) == 0 {
+		return nil
+	}
+	values, _ := blocks[0].(map[string]interface{})
+	return values
+}
+
+type module struct {
+	Resources []struct {
+		Address string                 `json:"address"`
+		Mode    string                 `json:"mode"`
+		Type    string                 `json:"type"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []module `json:"child_modules"`
+}
+
+type showOutput struct {
+	// Values is set when showing state, PlannedValues when showing a plan
+	Values *struct {
+		RootModule module `json:"root_module"`
+	} `json:"values"`
+	PlannedValues *struct {
+		RootModule module `json:"root_module"`
+	} `json:"planned_values"`
+}
+
+// Parse returns the managed resources in show output, sorted by address.
+// Data sources are left out.
+func Parse(data []byte) ([]Resource, error) {
+	var out showOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	switch {
+	case out.PlannedValues != nil:
+		resources = walk(out.PlannedValues.RootModule, resources)
+	case out.Values != nil:
+		resources = walk(out.Values.RootModule, resources)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Address < resources[j].Address
+	})
+	return resources, nil
+}
+
+func walk(m module, resources []Resource) []Resource {
+	for _, resource := range m.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+		resources = append(resources, Resource{Address: resource.Address, Type: resource.Type, Values: resource.Values})
+	}
+	for _, child := range m.ChildModules {
+		resources = walk(child, resources)
+	}
+	return resources
+}
+
+// State returns the managed resources in the applied state of options
+func State(t testing.TestingT, options *terraform.Options) []Resource {
+	resources, err := Parse([]byte(terraform.Show(t, options)))
+	require.NoError(t, err)
+	return resources
+}
+
+// OfType returns the resources of one type, e.g. "aws_security_group"
+func OfType(resources []Resource, resourceType string) []Resource {
+	var out []Resource
+	for _, resource := range resources {
+		if resource.Type == resourceType {
+			out = append(out, resource)
+		}
+	}
+	return out
+}
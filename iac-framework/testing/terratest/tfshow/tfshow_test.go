@@ -0,0 +1,69 @@
+package tfshow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleState = `{
+  "values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "values": {"id": "vpc-1"}},
+        {"address": "data.aws_region.current", "mode": "data", "type": "aws_region", "values": {"name": "us-west-2"}}
+      ],
+      "child_modules": [
+        {
+          "resources": [
+            {"address": "module.sg.aws_security_group.this", "mode": "managed", "type": "aws_security_group", "values": {"id": "sg-1"}}
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestParse(t *testing.T) {
+	resources, err := Parse([]byte(sampleState))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Resource{
+		{Address: "aws_vpc.main", Type: "aws_vpc", Values: map[string]interface{}{"id": "vpc-1"}},
+		{Address: "module.sg.aws_security_group.this", Type: "aws_security_group", Values: map[string]interface{}{"id": "sg-1"}},
+	}, resources, "Data sources should be skipped and child modules walked")
+
+	groups := OfType(resources, "aws_security_group")
+	require.Len(t, groups, 1)
+	assert.Equal(t, "sg-1", groups[0].String("id"))
+	assert.Equal(t, "", groups[0].String("name"))
+}
+
+func TestParsePlan(t *testing.T) {
+	resources, err := Parse([]byte(`{
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_eip.nat[0]", "mode": "managed", "type": "aws_eip", "values": {"domain": "vpc"}}
+      ]
+    }
+  }
+}`))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "vpc", resources[0].String("domain"))
+}
+
+func TestBlock(t *testing.T) {
+	cluster := Resource{Values: map[string]interface{}{
+		"network_profile": []interface{}{
+			map[string]interface{}{"network_plugin": "azure"},
+		},
+		"release_channel": []interface{}{},
+	}}
+
+	assert.Equal(t, map[string]interface{}{"network_plugin": "azure"}, cluster.Block("network_profile"))
+	assert.Nil(t, cluster.Block("release_channel"))
+	assert.Nil(t, cluster.Block("missing"))
+}
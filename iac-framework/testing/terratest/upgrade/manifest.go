@@ -0,0 +1,155 @@
+package upgrade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// ManifestFileName is the breaking changes manifest kept next to a
+// module's main.tf. It declares, per release, the resources upgrading to
+// that release deletes or replaces on purpose:
+//
+//	{
+//	  "releases": [
+//	    {
+//	      "version": "v2.0.0",
+//	      "changes": [
+//	        {"address": "aws_nat_gateway.this[*]", "reason": "one NAT gateway per AZ"}
+//	      ]
+//	    },
+//	    {
+//	      "version": "unreleased",
+//	      "in_place": false,
+//	      "reason": "state layout rewritten, consumers must migrate by hand"
+//	    }
+//	  ]
+//	}
+//
+// Changes not yet tagged go under "unreleased" and move to their version
+// when it is tagged. In addresses "*" matches any run of characters and
+// everything else, brackets included, is literal.
+const ManifestFileName = "BREAKING_CHANGES.json"
+
+// Unreleased is the manifest version of changes since the last release
+const Unreleased = "unreleased"
+
+// Manifest is a module's breaking changes manifest
+type Manifest struct {
+	Releases []Release `json:"releases"`
+}
+
+// Release is the breaking changes of one version
+type Release struct {
+	Version string `json:"version"`
+
+	// InPlace false means the release cannot be upgraded to in place at
+	// all, and upgrade tests across it are skipped
+	InPlace *bool  `json:"in_place,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Change is a resource the release deletes or replaces on purpose
+type Change struct {
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+}
+
+// LoadManifest reads the manifest of the module in moduleDir. A module
+// without one has declared no breaking changes.
+func LoadManifest(moduleDir string) (*Manifest, error) {
+	manifestPath := filepath.Join(moduleDir, ManifestFileName)
+	data, err := os.ReadFile(manifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", manifestPath, err)
+	}
+	for _, release := range manifest.Releases {
+		if release.Version == Unreleased {
+			continue
+		}
+		if _, err := version.NewSemver(release.Version); err != nil {
+			return nil, fmt.Errorf("%s: release %q is not a version or %q", manifestPath, release.Version, Unreleased)
+		}
+	}
+	return &manifest, nil
+}
+
+// Since returns the releases an upgrade from ref crosses: those newer than
+// the version ref was tagged with, plus the unreleased changes. A ref that
+// is not a version tag, such as a branch pinned with TEST_UPGRADE_FROM,
+// crosses every release.
+func (m *Manifest) Since(ref string) []Release {
+	from, err := version.NewSemver(ref[strings.LastIndex(ref, "/")+1:])
+	var crossed []Release
+	for _, release := range m.Releases {
+		if release.Version == Unreleased || err != nil {
+			crossed = append(crossed, release)
+			continue
+		}
+		if v, _ := version.NewSemver(release.Version); v.GreaterThan(from) {
+			crossed = append(crossed, release)
+		}
+	}
+	return crossed
+}
+
+// NotInPlace returns the first crossed release that cannot be upgraded to
+// in place
+func NotInPlace(releases []Release) (Release, bool) {
+	for _, release := range releases {
+		if release.InPlace != nil && !*release.InPlace {
+			return release, true
+		}
+	}
+	return Release{}, false
+}
+
+// Undeclared splits the destructive changes of an upgrade plan into those
+// the crossed releases declare and those they do not, both sorted
+func Undeclared(destructive []string, releases []Release) (undeclared []string, declared []string) {
+	for _, address := range destructive {
+		if declaredBy(address, releases) {
+			declared = append(declared, address)
+		} else {
+			undeclared = append(undeclared, address)
+		}
+	}
+	sort.Strings(undeclared)
+	sort.Strings(declared)
+	return undeclared, declared
+}
+
+func declaredBy(address string, releases []Release) bool {
+	for _, release := range releases {
+		for _, change := range release.Changes {
+			if matchAddress(change.Address, address) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAddress matches a resource address against a pattern where only "*"
+// is special, since addresses are full of brackets
+func matchAddress(pattern string, address string) bool {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	return regexp.MustCompile("^" + quoted + "$").MatchString(address)
+}
@@ -0,0 +1,92 @@
+package upgrade
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFileName), []byte(content), 0o644))
+	return dir
+}
+
+func TestLoadManifest(t *testing.T) {
+	manifest, err := LoadManifest(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Releases, "A module without a manifest declares nothing")
+
+	manifest, err = LoadManifest(writeManifest(t, `{
+		"releases": [
+			{"version": "v2.0.0", "changes": [{"address": "aws_nat_gateway.this[*]", "reason": "one per AZ"}]},
+			{"version": "unreleased", "in_place": false, "reason": "state rewritten"}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, manifest.Releases, 2)
+	assert.Equal(t, "aws_nat_gateway.this[*]", manifest.Releases[0].Changes[0].Address)
+
+	_, err = LoadManifest(writeManifest(t, `{"releases": [{"version": "next"}]}`))
+	assert.Error(t, err)
+	_, err = LoadManifest(writeManifest(t, `{"releases": [`))
+	assert.Error(t, err)
+}
+
+func TestSince(t *testing.T) {
+	manifest := &Manifest{Releases: []Release{
+		{Version: "v1.1.0"},
+		{Version: "v2.0.0"},
+		{Version: Unreleased},
+	}}
+	versions := func(releases []Release) []string {
+		var names []string
+		for _, release := range releases {
+			names = append(names, release.Version)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"v2.0.0", Unreleased}, versions(manifest.Since("aws/vpc/v1.1.0")))
+	assert.Equal(t, []string{"v1.1.0", "v2.0.0", Unreleased}, versions(manifest.Since("v1.0.0")))
+	assert.Equal(t, []string{Unreleased}, versions(manifest.Since("aws/vpc/v2.0.0")))
+	assert.Equal(t, []string{"v1.1.0", "v2.0.0", Unreleased}, versions(manifest.Since("main")), "A branch crosses every release")
+}
+
+func TestNotInPlace(t *testing.T) {
+	no := false
+	yes := true
+
+	_, found := NotInPlace([]Release{{Version: "v1.1.0"}, {Version: "v1.2.0", InPlace: &yes}})
+	assert.False(t, found)
+
+	release, found := NotInPlace([]Release{{Version: "v1.1.0"}, {Version: "v2.0.0", InPlace: &no, Reason: "rewrite"}})
+	assert.True(t, found)
+	assert.Equal(t, "v2.0.0", release.Version)
+}
+
+func TestUndeclared(t *testing.T) {
+	releases := []Release{
+		{Version: "v2.0.0", Changes: []Change{{Address: "aws_nat_gateway.this[*]"}}},
+		{Version: Unreleased, Changes: []Change{{Address: "aws_eip.nat[0]"}}},
+	}
+	destructive := []string{"aws_route_table.private[1]", "aws_nat_gateway.this[1]", "aws_eip.nat[0]", "aws_eip.nat[1]", "aws_nat_gateway.this[0]"}
+
+	undeclared, declared := Undeclared(destructive, releases)
+	assert.Equal(t, []string{"aws_eip.nat[1]", "aws_route_table.private[1]"}, undeclared)
+	assert.Equal(t, []string{"aws_eip.nat[0]", "aws_nat_gateway.this[0]", "aws_nat_gateway.this[1]"}, declared)
+
+	undeclared, declared = Undeclared(destructive[:1], nil)
+	assert.Equal(t, []string{"aws_route_table.private[1]"}, undeclared)
+	assert.Empty(t, declared)
+}
+
+func TestMatchAddress(t *testing.T) {
+	assert.True(t, matchAddress(`module.nat["us-west-2a"].aws_eip.this`, `module.nat["us-west-2a"].aws_eip.this`))
+	assert.True(t, matchAddress(`module.nat[*].aws_eip.this`, `module.nat["us-west-2b"].aws_eip.this`))
+	assert.False(t, matchAddress(`aws_eip.nat[0]`, `aws_eip.nat[1]`))
+	assert.False(t, matchAddress(`aws_eip.nat`, `aws_eip.nat[0]`))
+}
@@ -0,0 +1,187 @@
+// Package upgrade finds a module's last release and checks that moving its
+// state to the working tree version does not destroy or replace anything.
+// Consumers upgrade in place, so a renamed resource or a changed ForceNew
+// argument is a breaking change even when both versions apply cleanly.
+// Deliberate ones are declared per release in the module's breaking
+// changes manifest.
+package upgrade
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/go-version"
+)
+
+// FromRefEnvVar pins the ref to upgrade from instead of the latest release
+const FromRefEnvVar = "TEST_UPGRADE_FROM"
+
+// Tags lists the git tags of the repository containing dir
+func Tags(dir string) ([]string, error) {
+	cmd := exec.Command("git", "tag", "--list")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing git tags: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// LatestRelease picks the newest release of a module such as "aws/vpc" from
+// a list of tags. Module tags like "aws/vpc/v1.2.0" win over repository
+// tags like "v1.2.0"; pre-releases are ignored.
+func LatestRelease(tags []string, module string) (string, bool) {
+	if tag, ok := newest(tags, module+"/"); ok {
+		return tag, true
+	}
+	return newest(tags, "")
+}
+
+func newest(tags []string, prefix string) (string, bool) {
+	var best string
+	var bestVersion *version.Version
+	for _, tag := range tags {
+		raw, ok := strings.CutPrefix(tag, prefix)
+		if !ok || !strings.HasPrefix(raw, "v") {
+			continue
+		}
+		v, err := version.NewSemver(raw)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if bestVersion == nil || v.GreaterThan(bestVersion) {
+			best, bestVersion = tag, v
+		}
+	}
+	return best, bestVersion != nil
+}
+
+// Export writes the tree of dir as of ref into dest, the way the module
+// tree looked when it was released
+func Export(dir string, ref string, dest string) error {
+	cmd := exec.Command("git", "archive", "--format=tar", ref)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git archive %s: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+	return extract(bytes.NewReader(out), dest)
+}
+
+func extract(r io.Reader, dest string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dest, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %s escapes %s", header.Name, dest)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, archive)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// workingState is what a working copy of a module keeps between plans
+var workingState = []string{".terraform", "terraform.tfstate", "terraform.tfstate.backup"}
+
+// Swap replaces the module tree in dest with the one in src, keeping the
+// .terraform directory and local state of module, e.g. "aws/vpc". That is
+// the in-place upgrade a consumer does when bumping the module version.
+func Swap(src string, dest string, module string) error {
+	hold, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dest)), "upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(hold)
+
+	moduleDir := filepath.Join(dest, filepath.FromSlash(module))
+	for _, name := range workingState {
+		err := os.Rename(filepath.Join(moduleDir, name), filepath.Join(hold, name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	// The same files terratest copies into a fresh working copy
+	err = files.CopyFolderContentsWithFilter(src, dest, func(path string) bool {
+		if files.PathIsTerraformVersionFile(path) || files.PathIsTerraformLockFile(path) {
+			return true
+		}
+		return !files.PathContainsHiddenFileOrFolder(path) && !files.PathContainsTerraformStateOrVars(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+		return err
+	}
+	for _, name := range workingState {
+		err := os.Rename(filepath.Join(hold, name), filepath.Join(moduleDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Destructive returns the addresses of resources the plan deletes or
+// replaces, sorted. Resources the released version created and the new one
+// no longer declares show up here too, which is intended: moved blocks are
+// how a module is expected to rename things.
+func Destructive(plan *terraform.PlanStruct) []string {
+	var addresses []string
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change == nil {
+			continue
+		}
+		if change.Change.Actions.Delete() || change.Change.Actions.Replace() {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
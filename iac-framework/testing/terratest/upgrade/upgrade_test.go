@@ -0,0 +1,136 @@
+package upgrade
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestRelease(t *testing.T) {
+	cases := []struct {
+		name     string
+		tags     []string
+		module   string
+		expected string
+		found    bool
+	}{
+		{
+			name:     "module tags",
+			tags:     []string{"aws/vpc/v1.2.0", "aws/vpc/v1.10.0", "aws/ec2/v2.0.0", "v3.0.0"},
+			module:   "aws/vpc",
+			expected: "aws/vpc/v1.10.0",
+			found:    true,
+		},
+		{
+			name:     "repository tags",
+			tags:     []string{"v0.9.0", "v1.0.0", "aws/vpc/v1.2.0"},
+			module:   "aws/ec2",
+			expected: "v1.0.0",
+			found:    true,
+		},
+		{
+			name:     "prereleases ignored",
+			tags:     []string{"aws/vpc/v1.0.0", "aws/vpc/v1.1.0-rc.1"},
+			module:   "aws/vpc",
+			expected: "aws/vpc/v1.0.0",
+			found:    true,
+		},
+		{
+			name:   "no releases",
+			tags:   []string{"nightly", "aws/vpc/latest"},
+			module: "aws/vpc",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tag, found := LatestRelease(tc.tags, tc.module)
+			assert.Equal(t, tc.found, found)
+			assert.Equal(t, tc.expected, tag)
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "modules", "aws", "vpc"), 0o755))
+	main := filepath.Join(repo, "modules", "aws", "vpc", "main.tf")
+	require.NoError(t, os.WriteFile(main, []byte("# released\n"), 0o644))
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "release")
+	git("tag", "aws/vpc/v1.0.0")
+	require.NoError(t, os.WriteFile(main, []byte("# working tree\n"), 0o644))
+
+	tags, err := Tags(repo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws/vpc/v1.0.0"}, tags)
+
+	dest := t.TempDir()
+	require.NoError(t, Export(filepath.Join(repo, "modules"), "aws/vpc/v1.0.0", dest))
+	exported, err := os.ReadFile(filepath.Join(dest, "aws", "vpc", "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "# released\n", string(exported), "Export should only contain the subdirectory as of the tag")
+}
+
+func TestSwap(t *testing.T) {
+	write := func(path string, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+
+	src := t.TempDir()
+	write(filepath.Join(src, "aws", "vpc", "main.tf"), "# working tree\n")
+	write(filepath.Join(src, "aws", "vpc", "outputs.tf"), "# added since the release\n")
+	write(filepath.Join(src, "aws", "vpc", "terraform.tfstate"), "stray state")
+
+	dest := filepath.Join(t.TempDir(), "modules")
+	write(filepath.Join(dest, "aws", "vpc", "main.tf"), "# released\n")
+	write(filepath.Join(dest, "aws", "vpc", "removed.tf"), "# dropped since the release\n")
+	write(filepath.Join(dest, "aws", "vpc", "terraform.tfstate"), "applied state")
+	write(filepath.Join(dest, "aws", "vpc", ".terraform", "modules", "modules.json"), "{}")
+
+	require.NoError(t, Swap(src, dest, "aws/vpc"))
+
+	moduleDir := filepath.Join(dest, "aws", "vpc")
+	main, err := os.ReadFile(filepath.Join(moduleDir, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "# working tree\n", string(main))
+	assert.FileExists(t, filepath.Join(moduleDir, "outputs.tf"))
+	assert.NoFileExists(t, filepath.Join(moduleDir, "removed.tf"), "Files dropped since the release should be gone")
+	assert.FileExists(t, filepath.Join(moduleDir, ".terraform", "modules", "modules.json"))
+
+	state, err := os.ReadFile(filepath.Join(moduleDir, "terraform.tfstate"))
+	require.NoError(t, err)
+	assert.Equal(t, "applied state", string(state), "The applied state should survive the swap")
+}
+
+func TestDestructive(t *testing.T) {
+	plan, err := terraform.ParsePlanJSON(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.this", "change": {"actions": ["no-op"]}},
+    {"address": "aws_subnet.public[0]", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_eip.nat[0]", "change": {"actions": ["delete"]}},
+    {"address": "aws_route_table.public", "change": {"actions": ["update"]}},
+    {"address": "aws_flow_log.this", "change": {"actions": ["create"]}}
+  ]
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_eip.nat[0]", "aws_subnet.public[0]"}, Destructive(plan))
+}
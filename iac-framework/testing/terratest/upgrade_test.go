@@ -0,0 +1,103 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/amis"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// TestModuleUpgrades applies the last released version of each module, then
+// switches the same working directory to the current tree and asserts the
+// plan neither destroys nor replaces anything. Modules without a release
+// tag are skipped; TEST_UPGRADE_FROM pins a different ref.
+func TestModuleUpgrades(t *testing.T) {
+	t.Parallel()
+
+	awsRegion := regions.PickRegion(t,
+		regions.DefaultVPC(),
+		regions.InstanceType("t3.micro"),
+		quotas.Requirement(
+			quotas.VPCs(1),
+			quotas.InternetGateways(1),
+			quotas.NATGatewaysPerAZ(1),
+			quotas.ElasticIPs(1)))
+	tags := map[string]string{
+		"Environment": "test",
+		"TestType":    "upgrade",
+	}
+
+	cases := []struct {
+		module string
+		vars   func(t *testing.T, uniqueId string) map[string]interface{}
+	}{
+		{
+			module: "aws/vpc",
+			vars: func(t *testing.T, uniqueId string) map[string]interface{} {
+				return map[string]interface{}{
+					"project_name":             fmt.Sprintf("upgrade-%s", uniqueId),
+					"environment":              "test",
+					"vpc_cidr":                 "10.3.0.0/16",
+					"availability_zones_count": 2,
+					"enable_nat_gateway":       true,
+					"single_nat_gateway":       true,
+					"tags":                     tags,
+				}
+			},
+		},
+		{
+			module: "aws/ec2",
+			vars: func(t *testing.T, uniqueId string) map[string]interface{} {
+				// The module looks its subnet up while planning, so it needs a real one
+				defaultVpc := aws.GetDefaultVpc(t, awsRegion)
+				defaultSubnets := aws.GetDefaultSubnetIDsForVpc(t, *defaultVpc)
+				require.NotEmpty(t, defaultSubnets, "The default VPC should have default subnets")
+
+				return map[string]interface{}{
+					"project_name":      fmt.Sprintf("upgrade-%s", uniqueId),
+					"environment":       "test",
+					"name":              fmt.Sprintf("upgrade-%s", uniqueId),
+					"instance_type":     "t3.micro",
+					"ami_id":            amis.LatestId(t, awsRegion, amis.AmazonLinux2),
+					"vpc_id":            defaultVpc.Id,
+					"subnet_id":         defaultSubnets[0],
+					"enable_ssh_access": false,
+					"tags":              tags,
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.module, func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: harness.ReleasedModuleDir(t, tc.module),
+				Vars:         tc.vars(t, random.UniqueId()),
+				EnvVars: map[string]string{
+					"AWS_DEFAULT_REGION": awsRegion,
+				},
+			}
+
+			harness.Track(t, terraformOptions)
+			defer harness.Destroy(t, terraformOptions)
+
+			// Deploy the released version as an existing consumer would have
+			harness.InitAndApply(t, terraformOptions)
+
+			// Bump to the working tree in place and check the migration
+			harness.UpgradeToWorkingTree(t, terraformOptions)
+			harness.AssertNoDestructiveChanges(t, terraformOptions)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
+)
+
+// TestVPCModuleVersionMatrix applies the VPC module with every Terraform and
+// OpenTofu release in the version matrix, catching core or provider
+// incompatibilities before the module's consumers do
+func TestVPCModuleVersionMatrix(t *testing.T) {
+	t.Parallel()
+
+	awsRegion := regions.PickRegion(t, quotas.Requirement(quotas.VPCs(1), quotas.InternetGateways(1)))
+
+	harness.ForEachVersion(t, func(t *testing.T, binary string) {
+		uniqueId := random.UniqueId()
+
+		terraformOptions := &terraform.Options{
+			TerraformDir:    harness.ModuleDir(t, "aws/vpc"),
+			TerraformBinary: binary,
+			Vars: map[string]interface{}{
+				"project_name":       fmt.Sprintf("matrix-%s", uniqueId),
+				"environment":        "dev",
+				"enable_nat_gateway": false,
+				"tags": map[string]string{
+					"Project":  "terratest",
+					"TestType": "version-matrix",
+				},
+			},
+			EnvVars: map[string]string{
+				"AWS_DEFAULT_REGION": awsRegion,
+			},
+		}
+
+		harness.Track(t, terraformOptions)
+		defer harness.Destroy(t, terraformOptions)
+		harness.InitAndApply(t, terraformOptions)
+
+		vpcId := terraform.Output(t, terraformOptions, "vpc_id")
+		assert.NotEmpty(t, vpcId, "VPC ID should not be empty")
+
+		exitCode := terraform.PlanExitCode(t, terraformOptions)
+		assert.Equal(t, 0, exitCode, "Plan after apply should be empty")
+	})
+}
@@ -0,0 +1,218 @@
+// Package versions installs pinned Terraform and OpenTofu releases so the
+// same module tests can be run against every core version we support.
+package versions
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// MatrixEnvVar overrides DefaultMatrix, e.g.
+// "terraform@1.5.7,terraform@1.7.5,tofu@1.6.2"
+const MatrixEnvVar = "TEST_TF_MATRIX"
+
+// CacheDirEnvVar overrides where downloaded binaries are kept between runs
+const CacheDirEnvVar = "TEST_TF_CACHE_DIR"
+
+// Tool is a Terraform-compatible CLI
+type Tool string
+
+// Tools
+const (
+	Terraform Tool = "terraform"
+	OpenTofu  Tool = "tofu"
+)
+
+// Version is one tool release
+type Version struct {
+	Tool    Tool
+	Version string
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%s@%s", v.Tool, v.Version)
+}
+
+// DefaultMatrix covers the oldest and newest Terraform releases the modules
+// support and the OpenTofu release we test against
+var DefaultMatrix = []Version{
+	{Tool: Terraform, Version: "1.5.7"},
+	{Tool: Terraform, Version: "1.7.5"},
+	{Tool: OpenTofu, Version: "1.6.2"},
+}
+
+// Matrix returns the versions to test, from TEST_TF_MATRIX if set
+func Matrix() ([]Version, error) {
+	spec := os.Getenv(MatrixEnvVar)
+	if spec == "" {
+		return DefaultMatrix, nil
+	}
+	return ParseMatrix(spec)
+}
+
+// ParseMatrix parses a comma separated list of tool@version entries
+func ParseMatrix(spec string) ([]Version, error) {
+	var matrix []Version
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tool, version, found := strings.Cut(entry, "@")
+		if !found || version == "" {
+			return nil, fmt.Errorf("matrix entry %q should be tool@version", entry)
+		}
+		switch Tool(tool) {
+		case Terraform, OpenTofu:
+		default:
+			return nil, fmt.Errorf("matrix entry %q: unknown tool %q", entry, tool)
+		}
+		matrix = append(matrix, Version{Tool: Tool(tool), Version: strings.TrimPrefix(version, "v")})
+	}
+	if len(matrix) == 0 {
+		return nil, fmt.Errorf("%s is set but lists no versions", MatrixEnvVar)
+	}
+	return matrix, nil
+}
+
+// releaseURLs returns where the release archive and its checksum file live
+func releaseURLs(v Version, goos string, goarch string) (archive string, checksums string) {
+	switch v.Tool {
+	case OpenTofu:
+		base := fmt.Sprintf("https://github.com/opentofu/opentofu/releases/download/v%s/tofu_%s", v.Version, v.Version)
+		return fmt.Sprintf("%s_%s_%s.zip", base, goos, goarch), base + "_SHA256SUMS"
+	default:
+		base := fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/terraform_%s", v.Version, v.Version)
+		return fmt.Sprintf("%s_%s_%s.zip", base, goos, goarch), base + "_SHA256SUMS"
+	}
+}
+
+// CacheDir is where binaries are installed
+func CacheDir() (string, error) {
+	if dir := os.Getenv(CacheDirEnvVar); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "terratest-binaries"), nil
+}
+
+// Install downloads the release into the cache, verifying it against the
+// published SHA256SUMS, and returns the path of the binary. Releases already
+// in the cache are not downloaded again.
+func Install(v Version) (string, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	binary := filepath.Join(cache, string(v.Tool), v.Version, string(v.Tool))
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	if _, err := os.Stat(binary); err == nil {
+		return binary, nil
+	}
+
+	archiveURL, checksumsURL := releaseURLs(v, runtime.GOOS, runtime.GOARCH)
+	archive, err := download(archiveURL)
+	if err != nil {
+		return "", err
+	}
+	checksums, err := download(checksumsURL)
+	if err != nil {
+		return "", err
+	}
+	if err := verify(archive, fileName(archiveURL), checksums); err != nil {
+		return "", fmt.Errorf("%s: %w", v, err)
+	}
+	if err := extract(archive, filepath.Base(binary), binary); err != nil {
+		return "", fmt.Errorf("%s: %w", v, err)
+	}
+	return binary, nil
+}
+
+func fileName(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// verify checks archive against its line in a SHA256SUMS file
+func verify(archive []byte, name string, checksums []byte) error {
+	sum := sha256.Sum256(archive)
+	actual := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			if fields[0] != actual {
+				return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, fields[0], actual)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no checksum published for %s", name)
+}
+
+// extract writes the named file from a zip archive to dest atomically, so
+// parallel installs never see a half written binary
+func extract(archive []byte, name string, dest string) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(dest), name+"-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := io.Copy(tmp, src); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), dest)
+	}
+	return fmt.Errorf("archive does not contain %s", name)
+}
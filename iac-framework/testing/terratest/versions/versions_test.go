@@ -0,0 +1,73 @@
+package versions
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatrix(t *testing.T) {
+	matrix, err := ParseMatrix("terraform@1.5.7, tofu@v1.6.2,")
+	require.NoError(t, err)
+	assert.Equal(t, []Version{
+		{Tool: Terraform, Version: "1.5.7"},
+		{Tool: OpenTofu, Version: "1.6.2"},
+	}, matrix)
+
+	for _, spec := range []string{"terraform", "pulumi@3.0.0", " , "} {
+		_, err := ParseMatrix(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestReleaseURLs(t *testing.T) {
+	archive, checksums := releaseURLs(Version{Tool: Terraform, Version: "1.5.7"}, "linux", "amd64")
+	assert.Equal(t, "https://releases.hashicorp.com/terraform/1.5.7/terraform_1.5.7_linux_amd64.zip", archive)
+	assert.Equal(t, "https://releases.hashicorp.com/terraform/1.5.7/terraform_1.5.7_SHA256SUMS", checksums)
+
+	archive, checksums = releaseURLs(Version{Tool: OpenTofu, Version: "1.6.2"}, "darwin", "arm64")
+	assert.Equal(t, "https://github.com/opentofu/opentofu/releases/download/v1.6.2/tofu_1.6.2_darwin_arm64.zip", archive)
+	assert.Equal(t, "https://github.com/opentofu/opentofu/releases/download/v1.6.2/tofu_1.6.2_SHA256SUMS", checksums)
+}
+
+func TestVerifyAndExtract(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	file, err := writer.Create("terraform")
+	require.NoError(t, err)
+	_, err = file.Write([]byte("#!/bin/sh\necho terraform\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	archive := buf.Bytes()
+
+	sum := sha256.Sum256(archive)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  terraform_1.5.7_linux_amd64.zip\n")
+	assert.NoError(t, verify(archive, "terraform_1.5.7_linux_amd64.zip", checksums))
+	assert.Error(t, verify(append(archive, 0), "terraform_1.5.7_linux_amd64.zip", checksums), "Tampered archive")
+	assert.Error(t, verify(archive, "terraform_1.5.7_linux_arm64.zip", checksums), "Missing checksum")
+
+	dest := filepath.Join(t.TempDir(), "terraform", "1.5.7", "terraform")
+	require.NoError(t, extract(archive, "terraform", dest))
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&0o100, "Binary should be executable")
+}
+
+func TestInstallUsesCache(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv(CacheDirEnvVar, cache)
+	binary := filepath.Join(cache, "tofu", "1.6.2", "tofu")
+	require.NoError(t, os.MkdirAll(filepath.Dir(binary), 0o755))
+	require.NoError(t, os.WriteFile(binary, nil, 0o755))
+
+	installed, err := Install(Version{Tool: OpenTofu, Version: "1.6.2"})
+	require.NoError(t, err)
+	assert.Equal(t, binary, installed)
+}
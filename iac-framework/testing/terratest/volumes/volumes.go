@@ -0,0 +1,87 @@
+// Package volumes checks the EBS volumes attached to an instance against the
+// block devices a module was asked for. The EC2 module only creates and
+// attaches data volumes; formatting and mounting them is up to its
+// consumers, so the checks stop at the EBS API and the kernel's view.
+package volumes
+
+import (
+	"fmt"
+	"sort"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+)
+
+// Volume is an EBS volume as attached at a device name
+type Volume struct {
+	DeviceName string
+	SizeGiB    int64
+	Type       string
+	Encrypted  bool
+}
+
+// Attached returns the volumes attached to the instance keyed by device name
+func Attached(t testing.TestingT, region string, instanceId string) map[string]Volume {
+	client := backend.NewEc2Client(t, region)
+	out, err := client.DescribeVolumes(&ec2.DescribeVolumesInput{
+		Filters: []*ec2.Filter{{
+			Name:   awssdk.String("attachment.instance-id"),
+			Values: awssdk.StringSlice([]string{instanceId}),
+		}},
+	})
+	require.NoError(t, err)
+
+	attached := map[string]Volume{}
+	for _, volume := range out.Volumes {
+		for _, attachment := range volume.Attachments {
+			if awssdk.StringValue(attachment.InstanceId) != instanceId {
+				continue
+			}
+			device := awssdk.StringValue(attachment.Device)
+			attached[device] = Volume{
+				DeviceName: device,
+				SizeGiB:    awssdk.Int64Value(volume.Size),
+				Type:       awssdk.StringValue(volume.VolumeType),
+				Encrypted:  awssdk.BoolValue(volume.Encrypted),
+			}
+		}
+	}
+	return attached
+}
+
+// AssertAttached asserts each expected volume is attached to the instance
+// at its device name with the requested size, type and encryption
+func AssertAttached(t testing.TestingT, region string, instanceId string, expected []Volume) {
+	for _, problem := range check(Attached(t, region, instanceId), expected) {
+		assert.Fail(t, problem, "instance %s", instanceId)
+	}
+}
+
+func check(attached map[string]Volume, expected []Volume) []string {
+	var problems []string
+	for _, want := range expected {
+		got, ok := attached[want.DeviceName]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("no volume attached at %s, attached: %v", want.DeviceName, deviceNames(attached)))
+			continue
+		}
+		if got != want {
+			problems = append(problems, fmt.Sprintf("volume at %s is %+v, expected %+v", want.DeviceName, got, want))
+		}
+	}
+	return problems
+}
+
+func deviceNames(attached map[string]Volume) []string {
+	names := make([]string, 0, len(attached))
+	for name := range attached {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,42 @@
+package volumes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	data := Volume{DeviceName: "/dev/sdf", SizeGiB: 10, Type: "gp3", Encrypted: true}
+	attached := map[string]Volume{
+		"/dev/xvda": {DeviceName: "/dev/xvda", SizeGiB: 8, Type: "gp3", Encrypted: true},
+		"/dev/sdf":  data,
+	}
+
+	cases := []struct {
+		name     string
+		expected []Volume
+		problems []string
+	}{
+		{
+			name:     "attached as requested",
+			expected: []Volume{data},
+		},
+		{
+			name:     "missing",
+			expected: []Volume{{DeviceName: "/dev/sdg", SizeGiB: 20, Type: "gp3", Encrypted: true}},
+			problems: []string{"no volume attached at /dev/sdg, attached: [/dev/sdf /dev/xvda]"},
+		},
+		{
+			name:     "wrong size",
+			expected: []Volume{{DeviceName: "/dev/sdf", SizeGiB: 20, Type: "gp3", Encrypted: true}},
+			problems: []string{"volume at /dev/sdf is {DeviceName:/dev/sdf SizeGiB:10 Type:gp3 Encrypted:true}, expected {DeviceName:/dev/sdf SizeGiB:20 Type:gp3 Encrypted:true}"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.problems, check(attached, tc.expected))
+		})
+	}
+}
@@ -5,12 +5,20 @@ import (
 	"fmt"
 	"strings"
 
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/company/iac-framework/testing/backend"
+	"github.com/company/iac-framework/testing/cis"
+	"github.com/company/iac-framework/testing/expectfail"
+	"github.com/company/iac-framework/testing/harness"
+	"github.com/company/iac-framework/testing/quotas"
+	"github.com/company/iac-framework/testing/regions"
 )
 
 // TestVPCModule validates the VPC module functionality
@@ -24,7 +32,7 @@ func TestVPCModule(t *testing.T) {
 
 	// Configure Terraform options
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/vpc",
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
 		Vars: map[string]interface{}{
 			"vpc_name":             vpcName,
 			"vpc_cidr":             "10.0.0.0/16",
@@ -45,11 +53,14 @@ func TestVPCModule(t *testing.T) {
 		},
 	}
 
+	// Record the workspace so an interrupted run can still clean it up
+	harness.Track(t, terraformOptions)
+
 	// Clean up resources after test
-	defer terraform.Destroy(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
 
 	// Deploy the VPC module
-	terraform.InitAndApply(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Validate outputs
 	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
@@ -61,17 +72,17 @@ func TestVPCModule(t *testing.T) {
 	assert.NotEmpty(t, vpcId, "VPC ID should not be empty")
 	
 	// Verify VPC exists in AWS
-	vpc := aws.GetVpcById(t, vpcId, awsRegion)
+	vpc := describeVpc(t, awsRegion, vpcId)
 	assert.Equal(t, "10.0.0.0/16", *vpc.CidrBlock, "VPC CIDR should match")
 	
 	// Verify DNS settings
-	assert.True(t, *vpc.EnableDnsSupport, "DNS support should be enabled")
-	assert.True(t, *vpc.EnableDnsHostnames, "DNS hostnames should be enabled")
+	assert.True(t, vpcAttribute(t, awsRegion, vpcId, ec2.VpcAttributeNameEnableDnsSupport), "DNS support should be enabled")
+	assert.True(t, vpcAttribute(t, awsRegion, vpcId, ec2.VpcAttributeNameEnableDnsHostnames), "DNS hostnames should be enabled")
 
 	// Verify public subnets
 	assert.Len(t, publicSubnetIds, 3, "Should have 3 public subnets")
 	for i, subnetId := range publicSubnetIds {
-		subnet := aws.GetSubnetById(t, subnetId, awsRegion)
+		subnet := describeSubnet(t, awsRegion, subnetId)
 		assert.True(t, *subnet.MapPublicIpOnLaunch, "Public subnet should auto-assign public IPs")
 		expectedCidr := fmt.Sprintf("10.0.%d.0/24", (i+1))
 		assert.Equal(t, expectedCidr, *subnet.CidrBlock, "Public subnet CIDR should match")
@@ -80,7 +91,7 @@ func TestVPCModule(t *testing.T) {
 	// Verify private subnets
 	assert.Len(t, privateSubnetIds, 3, "Should have 3 private subnets")
 	for i, subnetId := range privateSubnetIds {
-		subnet := aws.GetSubnetById(t, subnetId, awsRegion)
+		subnet := describeSubnet(t, awsRegion, subnetId)
 		assert.False(t, *subnet.MapPublicIpOnLaunch, "Private subnet should not auto-assign public IPs")
 		expectedCidr := fmt.Sprintf("10.0.%d0.0/24", (i+1))
 		assert.Equal(t, expectedCidr, *subnet.CidrBlock, "Private subnet CIDR should match")
@@ -105,7 +116,7 @@ func TestVPCWithoutNATGateway(t *testing.T) {
 	awsRegion := "us-west-2"
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/vpc",
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
 		Vars: map[string]interface{}{
 			"vpc_name":             vpcName,
 			"vpc_cidr":             "10.1.0.0/16",
@@ -125,8 +136,9 @@ func TestVPCWithoutNATGateway(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify NAT Gateway was not created
 	natGatewayIds := terraform.OutputList(t, terraformOptions, "nat_gateway_ids")
@@ -142,7 +154,7 @@ func TestVPCCustomCIDR(t *testing.T) {
 	awsRegion := "us-west-2"
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/vpc",
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
 		Vars: map[string]interface{}{
 			"vpc_name":             vpcName,
 			"vpc_cidr":             "172.16.0.0/16",
@@ -161,12 +173,13 @@ func TestVPCCustomCIDR(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify custom CIDR
 	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
-	vpc := aws.GetVpcById(t, vpcId, awsRegion)
+	vpc := describeVpc(t, awsRegion, vpcId)
 	assert.Equal(t, "172.16.0.0/16", *vpc.CidrBlock, "Custom VPC CIDR should match")
 
 	// Verify single NAT Gateway
@@ -174,36 +187,80 @@ func TestVPCCustomCIDR(t *testing.T) {
 	assert.Len(t, natGatewayIds, 1, "Should have exactly one NAT Gateway")
 }
 
-// TestVPCValidation tests input validation
+// TestVPCValidation checks each variable validation rejects bad input with
+// its own error message
 func TestVPCValidation(t *testing.T) {
 	t.Parallel()
 
-	uniqueId := random.UniqueId()
-	vpcName := fmt.Sprintf("test-vpc-validation-%s", uniqueId)
-	awsRegion := "us-west-2"
-
-	// Test with mismatched subnet count
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/vpc",
-		Vars: map[string]interface{}{
-			"vpc_name":             vpcName,
-			"vpc_cidr":             "10.0.0.0/16",
-			"availability_zones":   []string{"us-west-2a", "us-west-2b"},
-			"public_subnet_cidrs":  []string{"10.0.1.0/24"},  // Only 1 subnet
-			"private_subnet_cidrs": []string{"10.0.10.0/24"}, // Only 1 subnet
-			"enable_nat_gateway":   true,
+	awsRegion := regions.PickRegion(t)
+
+	// Each case breaks one variable of an otherwise valid configuration
+	cases := []struct {
+		name    string
+		vars    map[string]interface{}
+		pattern string
+	}{
+		{
+			name:    "project-name-too-long",
+			vars:    map[string]interface{}{"project_name": strings.Repeat("a", 33)},
+			pattern: `Project name must be between 1 and 32 characters`,
 		},
-		EnvVars: map[string]string{
-			"AWS_DEFAULT_REGION": awsRegion,
+		{
+			name:    "unknown-environment",
+			vars:    map[string]interface{}{"environment": "qa"},
+			pattern: `Environment must be one of: dev, staging, prod, test`,
+		},
+		{
+			name:    "invalid-cidr",
+			vars:    map[string]interface{}{"vpc_cidr": "10.0.0.0/33"},
+			pattern: `VPC CIDR must be a valid IPv4 CIDR block`,
+		},
+		{
+			name:    "single-az",
+			vars:    map[string]interface{}{"availability_zones_count": 1},
+			pattern: `Availability zones count must be between 2 and 6`,
+		},
+		{
+			name:    "subnet-bits-too-small",
+			vars:    map[string]interface{}{"subnet_bits": 3},
+			pattern: `Subnet bits must be between 4 and 16`,
+		},
+		{
+			name:    "host-tenancy",
+			vars:    map[string]interface{}{"instance_tenancy": "host"},
+			pattern: `Instance tenancy must be either 'default' or 'dedicated'`,
+		},
+		{
+			name:    "unknown-flow-log-traffic-type",
+			vars:    map[string]interface{}{"flow_logs_traffic_type": "SOME"},
+			pattern: `Flow logs traffic type must be one of: ACCEPT, REJECT, ALL`,
 		},
 	}
 
-	// This should fail due to validation
-	_, err := terraform.InitAndApplyE(t, terraformOptions)
-	if err == nil {
-		// Clean up if it somehow succeeded
-		terraform.Destroy(t, terraformOptions)
-		t.Error("Expected validation error for mismatched subnet counts")
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vars := map[string]interface{}{
+				"project_name": fmt.Sprintf("vpc-validation-%s", random.UniqueId()),
+				"environment":  "test",
+			}
+			for name, value := range tc.vars {
+				vars[name] = value
+			}
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: harness.ModuleDir(t, "aws/vpc"),
+				Vars:         vars,
+				EnvVars: map[string]string{
+					"AWS_DEFAULT_REGION": awsRegion,
+				},
+			}
+
+			// Validations fail the plan, so nothing is created
+			expectfail.Plan(t, terraformOptions, tc.pattern)
+		})
 	}
 }
 
@@ -216,7 +273,7 @@ func TestVPCEndpoints(t *testing.T) {
 	awsRegion := "us-west-2"
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/vpc",
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
 		Vars: map[string]interface{}{
 			"vpc_name":             vpcName,
 			"vpc_cidr":             "10.0.0.0/16",
@@ -240,8 +297,9 @@ func TestVPCEndpoints(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
 
 	// Verify VPC endpoints were created
 	vpcEndpointIds := terraform.OutputList(t, terraformOptions, "vpc_endpoint_ids")
@@ -261,44 +319,99 @@ func validateResourceTags(t *testing.T, expectedTags map[string]string, actualTa
 	}
 }
 
-// TestVPCFlowLogs tests VPC Flow Logs configuration
+// describeVpc looks a VPC up directly, since terratest's Vpc type carries
+// neither the CIDR block nor the DNS attributes
+func describeVpc(t *testing.T, region string, vpcId string) *ec2.Vpc {
+	out, err := backend.NewEc2Client(t, region).DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: awssdk.StringSlice([]string{vpcId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Vpcs, 1)
+	return out.Vpcs[0]
+}
+
+// vpcAttribute reads one of the boolean DNS attributes of a VPC
+func vpcAttribute(t *testing.T, region string, vpcId string, attribute string) bool {
+	out, err := backend.NewEc2Client(t, region).DescribeVpcAttribute(&ec2.DescribeVpcAttributeInput{
+		VpcId:     awssdk.String(vpcId),
+		Attribute: awssdk.String(attribute),
+	})
+	require.NoError(t, err)
+	switch attribute {
+	case ec2.VpcAttributeNameEnableDnsSupport:
+		return awssdk.BoolValue(out.EnableDnsSupport.Value)
+	case ec2.VpcAttributeNameEnableDnsHostnames:
+		return awssdk.BoolValue(out.EnableDnsHostnames.Value)
+	}
+	t.Fatalf("unsupported VPC attribute %q", attribute)
+	return false
+}
+
+// describeSubnet looks a subnet up by ID
+func describeSubnet(t *testing.T, region string, subnetId string) *ec2.Subnet {
+	out, err := backend.NewEc2Client(t, region).DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: awssdk.StringSlice([]string{subnetId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, out.Subnets, 1)
+	return out.Subnets[0]
+}
+
+// TestVPCFlowLogs checks the flow log the module attaches to the VPC
+// delivers to the given CloudWatch log group, and that the VPC then passes
+// the CIS controls that apply to it
 func TestVPCFlowLogs(t *testing.T) {
 	t.Parallel()
 
-	uniqueId := random.UniqueId()
-	vpcName := fmt.Sprintf("test-vpc-flow-logs-%s", uniqueId)
-	awsRegion := "us-west-2"
+	awsRegion := regions.PickRegion(t, quotas.Requirement(quotas.VPCs(1), quotas.InternetGateways(1)))
+
+	// The module only points the flow log at a destination, so the log
+	// group and its delivery role are created up front
+	destination := harness.EphemeralFlowLogsDestination(t, awsRegion)
 
 	terraformOptions := &terraform.Options{
-		TerraformDir: "../../modules/aws/vpc",
+		TerraformDir: harness.ModuleDir(t, "aws/vpc"),
 		Vars: map[string]interface{}{
-			"vpc_name":             vpcName,
-			"vpc_cidr":             "10.0.0.0/16",
-			"availability_zones":   []string{"us-west-2a"},
-			"public_subnet_cidrs":  []string{"10.0.1.0/24"},
-			"private_subnet_cidrs": []string{"10.0.10.0/24"},
-			"enable_nat_gateway":   true,
-			"enable_flow_logs":     true,
-			"flow_logs_destination": "cloudwatch",
-			"tags": map[string]string{
-				"Environment": "test",
-				"TestType":    "flow-logs",
-			},
+			"project_name":              fmt.Sprintf("flow-logs-%s", strings.ToLower(random.UniqueId())),
+			"environment":               "test",
+			"vpc_cidr":                  "10.9.0.0/16",
+			"availability_zones_count":  2,
+			"enable_nat_gateway":        false,
+			"enable_flow_logs":          true,
+			"flow_logs_iam_role_arn":    destination.RoleArn,
+			"flow_logs_destination_arn": destination.LogGroupArn,
+			"flow_logs_traffic_type":    "REJECT",
 		},
 		EnvVars: map[string]string{
 			"AWS_DEFAULT_REGION": awsRegion,
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	harness.Track(t, terraformOptions)
+	defer harness.Destroy(t, terraformOptions)
+	harness.InitAndApply(t, terraformOptions)
+
+	// Verify the flow log was created against the log group
+	flowLogId := terraform.Output(t, terraformOptions, "vpc_flow_log_id")
+	require.NotEmpty(t, flowLogId, "Flow log should be created")
+	assert.Equal(t, "cloud-watch-logs", terraform.Output(t, terraformOptions, "vpc_flow_log_destination_type"))
+	assert.Equal(t, destination.LogGroupArn, terraform.Output(t, terraformOptions, "vpc_flow_log_destination_arn"))
 
-	// Verify flow logs were created
-	flowLogId := terraform.Output(t, terraformOptions, "flow_log_id")
-	assert.NotEmpty(t, flowLogId, "Flow log should be created")
+	backend.SkipOnLocalStack(t, "flow log status and security group lookups are not emulated")
 
-	// Verify CloudWatch log group was created
-	logGroupName := terraform.Output(t, terraformOptions, "flow_log_group_name")
-	assert.NotEmpty(t, logGroupName, "Flow log group should be created")
-	assert.True(t, strings.Contains(logGroupName, "vpc-flow-logs"), "Log group name should contain vpc-flow-logs")
-}
\ No newline at end of file
+	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
+	flowLogs, err := backend.NewEc2Client(t, awsRegion).DescribeFlowLogs(&ec2.DescribeFlowLogsInput{
+		FlowLogIds: awssdk.StringSlice([]string{flowLogId}),
+	})
+	require.NoError(t, err)
+	require.Len(t, flowLogs.FlowLogs, 1)
+	flowLog := flowLogs.FlowLogs[0]
+	assert.Equal(t, vpcId, awssdk.StringValue(flowLog.ResourceId), "Flow log should watch the VPC")
+	assert.Equal(t, "REJECT", awssdk.StringValue(flowLog.TrafficType), "Flow log traffic type should match")
+	assert.Equal(t, destination.LogGroupName, awssdk.StringValue(flowLog.LogGroupName), "Flow log should deliver to the log group")
+	assert.Equal(t, destination.RoleArn, awssdk.StringValue(flowLog.DeliverLogsPermissionArn), "Flow log should use the delivery role")
+	assert.Equal(t, "SUCCESS", awssdk.StringValue(flowLog.DeliverLogsStatus), "Flow log delivery should be healthy")
+
+	// The VPC should pass the CIS controls that apply to it
+	cis.Assert(t, awsRegion, cis.VPC(vpcId)...)
+}
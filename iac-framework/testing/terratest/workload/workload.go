@@ -0,0 +1,189 @@
+// Package workload smoke tests a freshly applied Kubernetes cluster by
+// deploying a small web server to it and fetching a page through the API
+// server. A cluster whose nodes are Ready can still fail to schedule,
+// pull images or route to pods; this catches those before the cluster is
+// declared good.
+package workload
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	http_helper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Name of the deployment and service
+	Name = "smoke"
+
+	// Image is small, public and answers on port 80
+	Image = "nginx:1.25-alpine"
+
+	availableRetries = 30
+	availableSleep   = 10 * time.Second
+)
+
+// manifest is the sample workload, spread over two replicas so a node that
+// cannot run pods shows up
+const manifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: %[1]s
+  template:
+    metadata:
+      labels:
+        app: %[1]s
+    spec:
+      topologySpreadConstraints:
+        - maxSkew: 1
+          topologyKey: kubernetes.io/hostname
+          whenUnsatisfiable: ScheduleAnyway
+          labelSelector:
+            matchLabels:
+              app: %[1]s
+      containers:
+        - name: web
+          image: %[2]s
+          ports:
+            - containerPort: 80
+          readinessProbe:
+            httpGet:
+              path: /
+              port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+    - port: 80
+      targetPort: 80
+`
+
+// loadBalancer exposes the sample workload outside the cluster
+const loadBalancer = `apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+spec:
+  type: LoadBalancer
+  selector:
+    app: %[2]s
+  ports:
+    - port: 80
+      targetPort: 80
+`
+
+// Manifest returns the sample Deployment and Service running image
+func Manifest(image string) string {
+	return fmt.Sprintf(manifest, Name, image)
+}
+
+// Deploy applies the sample workload to a namespace of its own, waits for
+// it to become available and returns options scoped to the namespace. The
+// workload only has a ClusterIP service, so nothing outside the cluster
+// holds up its destroy; defer Delete when the cluster outlives the test.
+func Deploy(t *testing.T, kubectl *k8s.KubectlOptions) *k8s.KubectlOptions {
+	return DeployImage(t, kubectl, Image)
+}
+
+// DeployImage is Deploy with another nginx image, for clusters that can
+// only pull from their cloud's registries
+func DeployImage(t *testing.T, kubectl *k8s.KubectlOptions, image string) *k8s.KubectlOptions {
+	namespace := "smoke-" + strings.ToLower(random.UniqueId())
+	k8s.CreateNamespace(t, kubectl, namespace)
+
+	scoped := k8s.NewKubectlOptions(kubectl.ContextName, kubectl.ConfigPath, namespace)
+	scoped.Env = kubectl.Env
+	k8s.KubectlApplyFromString(t, scoped, Manifest(image))
+	k8s.WaitUntilDeploymentAvailable(t, scoped, Name, availableRetries, availableSleep)
+	return scoped
+}
+
+// Delete removes the workload's namespace
+func Delete(t *testing.T, scoped *k8s.KubectlOptions) {
+	k8s.DeleteNamespace(t, scoped, scoped.Namespace)
+}
+
+// AssertServes fetches the default page through a tunnel to the service
+func AssertServes(t *testing.T, scoped *k8s.KubectlOptions) {
+	tunnel := k8s.NewTunnel(scoped, k8s.ResourceTypeService, Name, 0, 80)
+	defer tunnel.Close()
+	tunnel.ForwardPort(t)
+
+	http_helper.HttpGetWithRetryWithCustomValidation(t, "http://"+tunnel.Endpoint(), nil, 10, 3*time.Second,
+		func(status int, body string) bool {
+			return status == 200 && strings.Contains(body, "Welcome to nginx")
+		})
+}
+
+// Expose puts the workload behind a LoadBalancer service, waits for the
+// cloud to provision it and returns its address. The load balancer is not
+// in terraform's state; clean it up with k8scleanup before the cluster is
+// destroyed.
+func Expose(t *testing.T, scoped *k8s.KubectlOptions) string {
+	name := Name + "-lb"
+	k8s.KubectlApplyFromString(t, scoped, fmt.Sprintf(loadBalancer, name, Name))
+	k8s.WaitUntilServiceAvailable(t, scoped, name, availableRetries, availableSleep)
+
+	ingress := k8s.GetService(t, scoped, name).Status.LoadBalancer.Ingress
+	require.NotEmpty(t, ingress, "Service %s has no load balancer address", name)
+	if ingress[0].Hostname != "" {
+		return ingress[0].Hostname
+	}
+	return ingress[0].IP
+}
+
+// AssertServesAt fetches the default page from address, allowing time for
+// a new load balancer's DNS name to resolve and its targets to turn healthy
+func AssertServesAt(t *testing.T, address string) {
+	http_helper.HttpGetWithRetryWithCustomValidation(t, "http://"+address, nil, availableRetries, availableSleep,
+		func(status int, body string) bool {
+			return status == 200 && strings.Contains(body, "Welcome to nginx")
+		})
+}
+
+// Pods returns the workload's pods
+func Pods(t *testing.T, scoped *k8s.KubectlOptions) []corev1.Pod {
+	return k8s.ListPods(t, scoped, metav1.ListOptions{LabelSelector: "app=" + Name})
+}
+
+// AssertPodIPsIn asserts every pod got an address from cidr, which is how
+// CNIs that hand out VPC or VNet addresses, like Azure CNI and GKE's VPC
+// native networking, can be told apart from overlay networks
+func AssertPodIPsIn(t *testing.T, pods []corev1.Pod, cidr string) {
+	_, network, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+	require.NotEmpty(t, pods, "No pods to check")
+	for _, pod := range pods {
+		ip := net.ParseIP(pod.Status.PodIP)
+		assert.True(t, ip != nil && network.Contains(ip), "Pod %s has IP %q outside %s", pod.Name, pod.Status.PodIP, cidr)
+	}
+}
+
+// NodesByLabel counts nodes by the value of a label, such as the node pool
+// label each cloud puts on its nodes
+func NodesByLabel(nodes []corev1.Node, label string) map[string]int {
+	counts := map[string]int{}
+	for _, node := range nodes {
+		counts[node.Labels[label]]++
+	}
+	return counts
+}
@@ -0,0 +1,58 @@
+package workload
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestManifest(t *testing.T) {
+	documents := strings.Split(Manifest(Image), "\n---\n")
+	require.Len(t, documents, 2)
+
+	kinds := []string{}
+	for _, document := range documents {
+		var object struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}
+		require.NoError(t, yaml.Unmarshal([]byte(document), &object))
+		assert.Equal(t, Name, object.Metadata.Name)
+		kinds = append(kinds, object.Kind)
+	}
+	assert.Equal(t, []string{"Deployment", "Service"}, kinds)
+	assert.Contains(t, Manifest("mirror.gcr.io/library/nginx:1.25-alpine"), "image: mirror.gcr.io/library/nginx:1.25-alpine")
+}
+
+func TestLoadBalancerManifest(t *testing.T) {
+	var service corev1.Service
+	require.NoError(t, yaml.Unmarshal([]byte(fmt.Sprintf(loadBalancer, Name+"-lb", Name)), &service))
+	assert.Equal(t, Name+"-lb", service.Name)
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, service.Spec.Type)
+	assert.Equal(t, map[string]string{"app": Name}, service.Spec.Selector)
+}
+
+func TestNodesByLabel(t *testing.T) {
+	node := func(pool string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"agentpool": pool}}}
+	}
+	nodes := []corev1.Node{node("default"), node("user"), node("user")}
+
+	assert.Equal(t, map[string]int{"default": 1, "user": 2}, NodesByLabel(nodes, "agentpool"))
+}
+
+func TestAssertPodIPsIn(t *testing.T) {
+	pods := []corev1.Pod{
+		{Status: corev1.PodStatus{PodIP: "10.20.1.17"}},
+		{Status: corev1.PodStatus{PodIP: "10.20.1.200"}},
+	}
+	AssertPodIPsIn(t, pods, "10.20.1.0/24")
+}